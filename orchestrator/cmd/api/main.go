@@ -2,306 +2,265 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"expvar"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/google/uuid"
-
 	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/agent/builtin"
 	"github.com/yeOmaNnn/orchestrator/internal/api"
-	"github.com/yeOmaNnn/orchestrator/internal/domain"
+	"github.com/yeOmaNnn/orchestrator/internal/app"
+	"github.com/yeOmaNnn/orchestrator/internal/catalog"
 	"github.com/yeOmaNnn/orchestrator/internal/engine"
-	"github.com/yeOmaNnn/orchestrator/internal/planner"
+	"github.com/yeOmaNnn/orchestrator/internal/leader"
 	"github.com/yeOmaNnn/orchestrator/internal/runner"
 	"github.com/yeOmaNnn/orchestrator/internal/scheduler"
-	// "github.com/yeOmaNnn/orchestrator/internal/storage"
 )
 
-type InMemoryTaskRepo struct {
-	tasks map[uuid.UUID]*domain.Task
-}
-
-func NewInMemoryTaskRepo() *InMemoryTaskRepo {
-	return &InMemoryTaskRepo{
-		tasks: make(map[uuid.UUID]*domain.Task),
-	}
-}
-
-func (r *InMemoryTaskRepo) Create(ctx context.Context, task *domain.Task) error {
-	r.tasks[task.ID] = task
-	return nil
-}
+const (
+	roleAll = "all"
+	roleAPI = "api"
+)
 
-func (r *InMemoryTaskRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Task, error) {
-	t, ok := r.tasks[id]
-	if !ok {
-		return nil, fmt.Errorf("task not found")
-	}
-	return t, nil
-}
+func main() {
+	var (
+		addr     = flag.String("addr", ":8080", "HTTP listen address")
+		role     = flag.String("role", roleAll, "process role: all (API and workers) or api (submit and monitor only)")
+		dsn      = flag.String("db", os.Getenv("DATABASE_URL"), "Postgres DSN; in-memory storage when empty")
+		parallel = flag.Int("parallel", 2, "number of step workers when role is all")
+		labels   = flag.String("labels", "", "comma-separated capability labels of the in-process worker")
+
+		agentLabels        = flag.String("agent-labels", "", "labels agents require of workers, e.g. gpu.agent=gpu;vault.agent=secrets")
+		retryPolicies      = flag.String("retry-policies", "", "JSON object of per-agent retry policies")
+		unschedulableAfter = flag.Duration("unschedulable-after", 5*time.Minute, "mark ready steps unschedulable when no worker can serve them for this long")
+
+		agentHosts        = flag.String("agent-hosts", "", "comma-separated agent host URLs whose agents are discovered and registered")
+		execDirs          = flag.String("exec-dirs", "", "comma-separated directories exec agents may run programs from; exec agents are refused when empty")
+		builtinHosts      = flag.String("builtin-http-hosts", os.Getenv("BUILTIN_HTTP_HOSTS"), "comma-separated hosts builtin.http may request, *.example.com for subdomains; builtin.http is off when empty")
+		agentSyncInterval = flag.Duration("agent-sync-interval", 5*time.Second, "how often the agent catalog and agent hosts are re-synced")
+		scheduleInterval  = flag.Duration("schedule-interval", time.Second, "how often schedules are checked for due runs")
+		staleLockTTL      = flag.Duration("stale-lock-ttl", 10*time.Minute, "return steps locked for longer than this to WAITING; must exceed every step timeout")
+
+		callbackURL     = flag.String("callback-url", os.Getenv("CALLBACK_URL"), "public base URL agents post async job results to; jobs are only polled when empty")
+		callbackSecret  = flag.String("callback-secret", os.Getenv("CALLBACK_SECRET"), "secret callback URLs are signed with; required with shared storage")
+		jobTimeout      = flag.Duration("job-timeout", 24*time.Hour, "fail steps whose async job has not reported back after this long, unless the agent sets its own bound")
+		jobPollInterval = flag.Duration("job-poll-interval", 10*time.Second, "how often async jobs are checked for deadlines and polled")
+
+		drainTimeout    = flag.Duration("drain-timeout", 30*time.Second, "how long to wait for in-flight steps on shutdown")
+		shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second, "how long to wait for open HTTP requests on shutdown")
+	)
+	flag.Parse()
 
-func (r *InMemoryTaskRepo) UpdateStatus(
-	ctx context.Context,
-	id uuid.UUID,
-	status domain.TaskStatus,
-) error {
-	t, ok := r.tasks[id]
-	if !ok {
-		return fmt.Errorf("task not found")
+	if *role != roleAll && *role != roleAPI {
+		log.Fatalf("unknown role %q", *role)
 	}
-	t.Status = status
-	return nil
-}
-
-
-type InMemoryStepRepo struct {
-	steps map[uuid.UUID]domain.Step
-}
 
-func NewInMemoryStepRepo() *InMemoryStepRepo {
-	return &InMemoryStepRepo{
-		steps: make(map[uuid.UUID]domain.Step),
+	store, err := app.OpenStorage(*dsn)
+	if err != nil {
+		log.Fatal(err)
 	}
-}
+	defer store.Close()
 
-func (r *InMemoryStepRepo) CreateMany(
-	ctx context.Context,
-	steps []domain.Step,
-) error {
-	for _, s := range steps {
-		r.steps[s.ID] = s
+	if *role == roleAPI && !store.Shared {
+		log.Fatal("role api needs shared storage (-db) so workers can pick up steps")
 	}
-	return nil
-}
 
-func (r *InMemoryStepRepo) CancelByTask(
-	ctx context.Context, 
-	taskID uuid.UUID,
-) error {
-	for id, step := range r.steps {
-		if step.TaskID != taskID {
-			continue
-		}
-
-		if step.Status == domain.StepDone || 
-		step.Status == domain.StepError || 
-		step.Status == domain.StepCancelled {
-			continue
-		}
-
-		step.Status = domain.StepCancelled
-		step.UpdatedAt = time.Now()
-		r.steps[id] = step 
-	}
-	return nil 
-}
-
-func (r *InMemoryStepRepo) GetByTask(
-	ctx context.Context,
-	taskID uuid.UUID,
-) ([]domain.Step, error) {
-
-	var out []domain.Step
-	for _, s := range r.steps {
-		if s.TaskID == taskID {
-			out = append(out, s)
-		}
+	requiredLabels, err := app.ParseAgentLabels(*agentLabels)
+	if err != nil {
+		log.Fatal(err)
 	}
-	return out, nil
-}
-
-func (r *InMemoryStepRepo) Update(
-	ctx context.Context,
-	step *domain.Step,
-) error {
-		if step.Output == nil {
-			step.Output = json.RawMessage(`{}`)
 
-		}
-		r.steps[step.ID] = *step
-		return nil
+	policies, err := app.ParseRetryPolicies(*retryPolicies)
+	if err != nil {
+		log.Fatal(err)
 	}
 
-func (r *InMemoryStepRepo) AcquireReadySteps(
-    ctx context.Context,
-    taskID uuid.UUID,
-    limit int,
-) ([]domain.Step, error) {
-    
-    var readySteps []domain.Step
-    stepsByID := make(map[uuid.UUID]domain.Step)
-    
-    for _, s := range r.steps {
-        if s.TaskID == taskID {
-            stepsByID[s.ID] = s
-        }
-    }
-    
-    for _, s := range stepsByID {
-        if s.Status != domain.StepWaiting {
-            continue
-        }
-        
-        ready := true
-        for _, depID := range s.DependsOn {
-            if dep, exists := stepsByID[depID]; exists && dep.Status != domain.StepDone {
-                ready = false
-                break
-            }
-        }
-        
-        if ready {
-            s.Status = domain.StepInProgress
-            r.steps[s.ID] = s
-            readySteps = append(readySteps, s)
-            
-            if len(readySteps) >= limit {
-                break
-            }
-        }
-    }
-    
-    return readySteps, nil
-}
- 
-
-type DummyPlanner struct{}
-
-func (p *DummyPlanner) Plan(
-	ctx context.Context,
-	req planner.PlanRequest,
-) (planner.PlanResponse, error) {
-
-	inputBytes, err := json.Marshal(map[string]any{
-		"text": "hello",
-	})
+	signer, err := app.CallbackSigner(*callbackURL, *callbackSecret, store.Shared)
 	if err != nil {
-		return planner.PlanResponse{}, err
+		log.Fatal(err)
 	}
-
-	step := planner.PlannedStep{
-		ID:        uuid.New(),
-		Agent:     "agent1",
-		Input:     inputBytes,
-		DependsOn: []uuid.UUID{},
+	runnerOpts := []runner.Option{
+		runner.WithDeadLetters(store.DeadLetters),
+		runner.WithEvents(store.Events),
+		runner.WithCallbacks(signer),
+		runner.WithJobTimeout(*jobTimeout),
 	}
 
-	return planner.PlanResponse{
-		Steps: []planner.PlannedStep{step},
-	}, nil
-}
-
-
-type DummyAgent struct{}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
 
-func (a *DummyAgent) Name() string {
-	return "agent1"
-}
-
-func (a *DummyAgent) Call(
-	ctx context.Context,
-	input json.RawMessage,
-) (json.RawMessage, error) {
-
-	return json.RawMessage(`{"result":"ok"}`), nil
-}
-
-func (r *InMemoryTaskRepo) ListActive (
-	ctx context.Context, 
-) ([]domain.Task, error) {
-	var out []domain.Task
-	for _, t := range r.tasks {
-		if t.Status == domain.TaskPending || 
-		t.Status == domain.TaskRunning {
-			out = append(out, *t)
-		}
+	catalogOpts := []catalog.Option{
+		catalog.WithExecDirs(app.SplitList(*execDirs)...),
+		catalog.WithWASMModules(store.Modules),
 	}
 
-	return out, nil
-}  
-
-func main() {
-	ctx := context.Background()
-
-	taskRepo := NewInMemoryTaskRepo()
-	stepRepo := NewInMemoryStepRepo()
-
-	registry := agent.NewRegistry()
-	registry.Register(&DummyAgent{})
-
-	router := agent.NewRouter(registry)
-
-	runnerService := runner.New(
-		stepRepo,
-		router,
+	var (
+		schedulerService *scheduler.Scheduler
+		healthChecker    *agent.HealthChecker
+		registry         *agent.Registry
+		agentCatalog     = catalog.New(store.AgentCatalog, catalogOpts...)
+		// jobs settles async jobs; without a local scheduler it never
+		// calls an agent itself.
+		jobs = runner.New(store.Steps, nil, runnerOpts...)
 	)
 
-	schedulerService := scheduler.New(
-		stepRepo,
-		taskRepo,
-		runnerService,
-		2,
-
-	)
-	go schedulerService.Run(ctx)
-
-	plannerClient := &DummyPlanner{}
+	if *role == roleAll {
+		registry = app.NewRegistry(builtin.WithHTTPHosts(app.SplitList(*builtinHosts)...))
+		agentCatalog = catalog.New(store.AgentCatalog, append(catalogOpts, catalog.WithRegistry(registry))...)
+		if err := agentCatalog.Sync(ctx); err != nil {
+			log.Fatalf("load agent catalog: %v", err)
+		}
+		go agentCatalog.Run(ctx, *agentSyncInterval)
+		app.StartAgentHosts(ctx, registry, app.SplitList(*agentHosts), *agentSyncInterval)
+
+		healthChecker = agent.NewHealthChecker(registry, 30*time.Second)
+		app.PublishHealth(healthChecker, store.Events)
+		router := agent.NewRouter(registry, agent.WithHealthChecker(healthChecker))
+
+		runnerService := runner.New(store.Steps, router, runnerOpts...)
+		jobs = runnerService
+
+		schedulerService = scheduler.New(
+			store.Steps,
+			store.Tasks,
+			runnerService,
+			*parallel,
+			scheduler.WithWorkerRepo(store.Workers),
+			scheduler.WithAgentPauses(store.AgentPauses),
+			scheduler.WithAgentHealth(healthChecker),
+			scheduler.WithCapabilitySource(registry.List),
+			scheduler.WithLabels(app.SplitList(*labels)...),
+		)
+		go schedulerService.Run(ctx)
+		expvar.Publish("scheduler", expvar.Func(func() any {
+			return schedulerService.Metrics()
+		}))
+		expvar.Publish("agent_pools", expvar.Func(func() any {
+			return registry.Pools()
+		}))
+		expvar.Publish("agent_versions", expvar.Func(func() any {
+			return registry.Versions()
+		}))
+
+		healthChecker.Start()
+
+		log.Printf("worker %s started", schedulerService.WorkerID())
+	}
 
+	engineOpts := []engine.Option{
+		engine.WithAgentLabels(requiredLabels),
+		engine.WithRetryPolicies(policies),
+		engine.WithUnschedulableDetection(store.Workers, *unschedulableAfter),
+		engine.WithDeadLetters(store.DeadLetters),
+		engine.WithAgentPauses(store.AgentPauses),
+		engine.WithSchedules(store.Schedules),
+		engine.WithAgentCatalog(store.AgentCatalog),
+	}
+	if registry != nil {
+		engineOpts = append(engineOpts, engine.WithAgentDirectory(registry))
+	}
 	eng := engine.New(
-		plannerClient,
-		schedulerService,
-		taskRepo,
-		stepRepo,		
+		&app.DummyPlanner{},
+		store.Tasks,
+		store.Steps,
+		engineOpts...,
 	)
 
 	handler := api.NewHandler(
-		eng, 
-		taskRepo, 
-		stepRepo,
+		eng,
+		store.Tasks,
+		store.Steps,
+		store.Workers,
+		store.DeadLetters,
+		store.Schedules,
+		store.Events,
+	)
+	go store.ListenEvents(ctx)
+	// Loops that must run once across all replicas go through the
+	// elector. Health checking stays per process: every router reads its
+	// own cached statuses.
+	hostname, _ := os.Hostname()
+	instanceID := fmt.Sprintf("%s-%d", hostname, os.Getpid())
+	elector := leader.New(
+		store.LeaderBackend("singletons", instanceID),
+		instanceID,
 	)
+	elector.Register("schedules", func(ctx context.Context) {
+		eng.RunSchedules(ctx, *scheduleInterval)
+	})
+	elector.Register("stale-locks", func(ctx context.Context) {
+		eng.RunStaleLockSweeper(ctx, *staleLockTTL, *staleLockTTL/4)
+	})
+	elector.Register("async-jobs", func(ctx context.Context) {
+		jobs.RunJobs(ctx, *jobPollInterval)
+	})
+	electorDone := make(chan struct{})
+	go func() {
+		elector.Run(ctx)
+		close(electorDone)
+	}()
+
+	probes := api.NewProbes()
 
 	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
 	handler.Register(mux)
+	probes.Register(mux)
+	api.NewLeaderStatus(elector).Register(mux)
+	api.NewAgents(agentCatalog, registry).Register(mux)
+	api.NewWASMModules(store.Modules, agentCatalog).Register(mux)
+	if signer != nil {
+		api.NewCallbacks(signer, jobs).Register(mux)
+	}
 
-	
-
-	task := domain.Task{
-		ID:     uuid.New(),
-		Goal:   "Say Hello",
-		Status: domain.TaskPending,
+	srv := &http.Server{
+		Addr:    *addr,
+		Handler: mux,
 	}
 
-	_ = taskRepo.Create(ctx, &task)
-	
+	go func() {
+		log.Printf("api listening %s (role %s)", *addr, *role)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+	probes.SetReady(true)
 
-	finalTask, _ := taskRepo.GetByID(ctx, task.ID)
-	fmt.Println("Task finished with status:", finalTask.Status)
+	<-ctx.Done()
+	stop()
+	log.Println("shutting down")
 
-	if err := taskRepo.Create(ctx, &task); err != nil {
-		log.Fatal(err)
+	// Report unready first so load balancers stop sending traffic while
+	// the workers drain; the HTTP server keeps answering until the end.
+	probes.SetReady(false)
+
+	if schedulerService != nil {
+		drainCtx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+		if err := schedulerService.Shutdown(drainCtx); err != nil {
+			log.Printf("drain incomplete, released remaining steps: %v", err)
+		}
+		cancel()
 	}
 
-	if err := eng.InitTaskExecution(ctx, task); err != nil {
-		log.Fatal(err)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("http shutdown: %v", err)
 	}
 
-	steps, _ := stepRepo.GetByTask(ctx, task.ID)
-	for _, s := range steps {
-		fmt.Println(
-			"Step:",
-			s.ID,
-			"Status:",
-			s.Status,
-			"Output:",
-			string(s.Output),
-		)
+	if healthChecker != nil {
+		healthChecker.Stop()
 	}
-	
-	log.Println("api listening :8080")
-	log.Fatal(http.ListenAndServe(":8080", mux))
-	
-}
 
+	// Hand leadership over before the database connection goes away.
+	<-electorDone
+
+	log.Println("stopped")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/agent/builtin"
+	"github.com/yeOmaNnn/orchestrator/internal/api"
+	"github.com/yeOmaNnn/orchestrator/internal/app"
+	"github.com/yeOmaNnn/orchestrator/internal/catalog"
+	"github.com/yeOmaNnn/orchestrator/internal/runner"
+	"github.com/yeOmaNnn/orchestrator/internal/scheduler"
+)
+
+// The worker runs only scheduler workers. It shares storage with the API
+// and other workers, which coordinate through AcquireReadySteps.
+func main() {
+	var (
+		addr         = flag.String("addr", ":8081", "listen address for health and readiness probes")
+		dsn          = flag.String("db", os.Getenv("DATABASE_URL"), "Postgres DSN")
+		id           = flag.String("id", "", "worker ID; generated when empty")
+		parallel     = flag.Int("parallel", 4, "number of concurrent steps")
+		capabilities = flag.String("capabilities", "", "comma-separated agents to serve; defaults to every registered agent")
+		labels       = flag.String("labels", "", "comma-separated capability labels, e.g. gpu,vpc-internal")
+		drainTimeout = flag.Duration("drain-timeout", 30*time.Second, "how long to wait for in-flight steps on shutdown")
+
+		agentHosts        = flag.String("agent-hosts", "", "comma-separated agent host URLs whose agents are discovered and registered")
+		execDirs          = flag.String("exec-dirs", "", "comma-separated directories exec agents may run programs from; exec agents are refused when empty")
+		builtinHosts      = flag.String("builtin-http-hosts", os.Getenv("BUILTIN_HTTP_HOSTS"), "comma-separated hosts builtin.http may request, *.example.com for subdomains; builtin.http is off when empty")
+		agentSyncInterval = flag.Duration("agent-sync-interval", 5*time.Second, "how often the agent catalog and agent hosts are re-synced")
+
+		callbackURL    = flag.String("callback-url", os.Getenv("CALLBACK_URL"), "public base URL of the API that agents post async job results to")
+		callbackSecret = flag.String("callback-secret", os.Getenv("CALLBACK_SECRET"), "secret callback URLs are signed with; must match the API's")
+		jobTimeout     = flag.Duration("job-timeout", 24*time.Hour, "fail steps whose async job has not reported back after this long, unless the agent sets its own bound")
+	)
+	flag.Parse()
+
+	if *dsn == "" {
+		log.Fatal("worker needs shared storage: set -db or DATABASE_URL")
+	}
+
+	store, err := app.OpenStorage(*dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	signer, err := app.CallbackSigner(*callbackURL, *callbackSecret, store.Shared)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	registry := app.NewRegistry(builtin.WithHTTPHosts(app.SplitList(*builtinHosts)...))
+	catalogOpts := []catalog.Option{
+		catalog.WithExecDirs(app.SplitList(*execDirs)...),
+		catalog.WithWASMModules(store.Modules),
+	}
+	agentCatalog := catalog.New(store.AgentCatalog, append(catalogOpts, catalog.WithRegistry(registry))...)
+	if err := agentCatalog.Sync(ctx); err != nil {
+		log.Fatalf("load agent catalog: %v", err)
+	}
+	go agentCatalog.Run(ctx, *agentSyncInterval)
+	app.StartAgentHosts(ctx, registry, app.SplitList(*agentHosts), *agentSyncInterval)
+
+	healthChecker := agent.NewHealthChecker(registry, 30*time.Second)
+	app.PublishHealth(healthChecker, store.Events)
+	router := agent.NewRouter(registry, agent.WithHealthChecker(healthChecker))
+
+	runnerService := runner.New(
+		store.Steps,
+		router,
+		runner.WithDeadLetters(store.DeadLetters),
+		runner.WithEvents(store.Events),
+		runner.WithCallbacks(signer),
+		runner.WithJobTimeout(*jobTimeout),
+	)
+
+	agents := registry.List()
+	capabilityOpt := scheduler.WithCapabilitySource(registry.List)
+	if *capabilities != "" {
+		agents = app.SplitList(*capabilities)
+		capabilityOpt = scheduler.WithCapabilities(agents...)
+	}
+
+	opts := []scheduler.Option{
+		scheduler.WithWorkerRepo(store.Workers),
+		scheduler.WithAgentPauses(store.AgentPauses),
+		scheduler.WithAgentHealth(healthChecker),
+		capabilityOpt,
+		scheduler.WithLabels(app.SplitList(*labels)...),
+	}
+	if *id != "" {
+		opts = append(opts, scheduler.WithWorkerID(*id))
+	}
+
+	schedulerService := scheduler.New(
+		store.Steps,
+		store.Tasks,
+		runnerService,
+		*parallel,
+		opts...,
+	)
+
+	expvar.Publish("scheduler", expvar.Func(func() any {
+		return schedulerService.Metrics()
+	}))
+	expvar.Publish("agent_pools", expvar.Func(func() any {
+		return registry.Pools()
+	}))
+	expvar.Publish("agent_versions", expvar.Func(func() any {
+		return registry.Versions()
+	}))
+
+	healthChecker.Start()
+
+	probes := api.NewProbes()
+	mux := http.NewServeMux()
+	mux.Handle("/debug/vars", expvar.Handler())
+	probes.Register(mux)
+	srv := &http.Server{
+		Addr:    *addr,
+		Handler: mux,
+	}
+
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	log.Printf("worker %s serving %v with labels %v", schedulerService.WorkerID(), agents, app.SplitList(*labels))
+	probes.SetReady(true)
+
+	schedulerService.Run(ctx)
+	stop()
+	log.Println("shutting down")
+
+	probes.SetReady(false)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	if err := schedulerService.Shutdown(drainCtx); err != nil {
+		log.Printf("drain incomplete, released remaining steps: %v", err)
+	}
+	cancel()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("probe server shutdown: %v", err)
+	}
+
+	healthChecker.Stop()
+
+	log.Println("stopped")
+}
@@ -0,0 +1,5 @@
+// Package agentpb holds the gRPC contract of agents served over gRPC,
+// generated from agent.proto.
+package agentpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative agent.proto
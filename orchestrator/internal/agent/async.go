@@ -0,0 +1,162 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AsyncJob is returned by Call when the agent accepted the input as a
+// long-running job instead of answering with an output: an HTTP agent
+// answers 202 Accepted with the job's ID. The job reports back later, to
+// the URL set by WithCallbackURL or through its StatusURL.
+//
+// It is returned as an error so that it passes through pools, routers and
+// versioned agents unchanged; circuit breakers count it as a success.
+type AsyncJob struct {
+	Agent string
+	ID    string
+	// StatusURL is where the status of the job can be polled, if the agent
+	// offers it.
+	StatusURL string
+	// PollInterval is how often the agent asked to be polled; zero means
+	// no preference.
+	PollInterval time.Duration
+	// Timeout is how long the job may take before the step fails; zero
+	// leaves it to the caller.
+	Timeout time.Duration
+}
+
+func (j *AsyncJob) Error() string {
+	return fmt.Sprintf("agent %s: accepted as job %s", j.Agent, j.ID)
+}
+
+// AsAsyncJob returns the job err carries, if the call was accepted as one.
+func AsAsyncJob(err error) (*AsyncJob, bool) {
+	var job *AsyncJob
+	ok := errors.As(err, &job)
+	return job, ok
+}
+
+type callbackURLKey struct{}
+
+// WithCallbackURL tags ctx with the URL an agent that runs the call as an
+// asynchronous job reports the result to.
+func WithCallbackURL(ctx context.Context, url string) context.Context {
+	return context.WithValue(ctx, callbackURLKey{}, url)
+}
+
+// CallbackURL returns the URL set by WithCallbackURL, or "".
+func CallbackURL(ctx context.Context) string {
+	u, _ := ctx.Value(callbackURLKey{}).(string)
+	return u
+}
+
+// jobAccepted is the body of a 202 answer.
+type jobAccepted struct {
+	JobID        string `json:"job_id"`
+	StatusURL    string `json:"status_url,omitempty"`
+	PollInterval int    `json:"poll_interval_seconds,omitempty"`
+}
+
+// readJob reads the job an agent at baseURL accepted a call as.
+func readJob(agent, baseURL string, timeout time.Duration, resp *http.Response) error {
+	var accepted jobAccepted
+	if err := json.NewDecoder(resp.Body).Decode(&accepted); err != nil {
+		return newError(KindTransient, agent, fmt.Errorf("decode job: %w", err))
+	}
+	if accepted.JobID == "" {
+		return newError(KindPermanent, agent, errors.New("job accepted without a job_id"))
+	}
+
+	job := &AsyncJob{
+		Agent:        agent,
+		ID:           accepted.JobID,
+		PollInterval: time.Duration(accepted.PollInterval) * time.Second,
+		Timeout:      timeout,
+	}
+	if accepted.StatusURL != "" {
+		// The status URL may be relative to the agent.
+		base, err := url.Parse(baseURL + "/")
+		if err != nil {
+			return newError(KindPermanent, agent, err)
+		}
+		ref, err := url.Parse(accepted.StatusURL)
+		if err != nil {
+			return newError(KindPermanent, agent, fmt.Errorf("job status_url: %w", err))
+		}
+		job.StatusURL = base.ResolveReference(ref).String()
+	}
+	return job
+}
+
+// JobResult is how an asynchronous job ended, as reported to its callback
+// or by its status URL.
+type JobResult struct {
+	Output json.RawMessage `json:"output"`
+	Error  string          `json:"error,omitempty"`
+	Retry  bool            `json:"should_retry,omitempty"`
+}
+
+// Err returns the failure the result reports, classified like the error of
+// a synchronous call, or nil if the job succeeded.
+func (r JobResult) Err(agent string) error {
+	if r.Error == "" {
+		return nil
+	}
+	kind := KindPermanent
+	if r.Retry {
+		kind = KindTransient
+	}
+	return newError(kind, agent, fmt.Errorf("agent error: %s", r.Error))
+}
+
+// jobStatus is the body of a status URL.
+type jobStatus struct {
+	JobResult
+	// Status is done or failed once the job has ended; anything else
+	// means it is still running.
+	Status string `json:"status"`
+}
+
+// PollJob asks the status URL of an asynchronous job how it is doing. It
+// returns nil while the job is still running.
+func PollJob(ctx context.Context, client *http.Client, agent, statusURL string) (*JobResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, statusURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create status request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Agent-Name", agent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("poll job: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("job status returned status %d", resp.StatusCode)
+	}
+
+	var status jobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("decode job status: %w", err)
+	}
+
+	switch status.Status {
+	case "done":
+		return &status.JobResult, nil
+	case "failed":
+		if status.Error == "" {
+			status.Error = "job failed"
+		}
+		return &status.JobResult, nil
+	default:
+		return nil, nil
+	}
+}
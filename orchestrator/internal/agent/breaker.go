@@ -18,16 +18,17 @@ type CircuitBreaker struct {
 	successThreshold  int
 	consecutiveErrors int
 	resetTimeout      time.Duration
+
+	// calls and failures count every call the breaker let through, for
+	// comparing error rates; they are never reset.
+	calls    int
+	failures int
 }
 
 func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
 	if config == nil {
-		config = &CircuitBreakerConfig{
-			FailureThreshold: 5,
-			SuccessThreshold: 3,
-			HalfOpenTimeout:  10 * time.Second,
-			ResetTimeout:     60 * time.Second,
-		}
+		defaults := DefaultCircuitBreakerConfig()
+		config = &defaults
 	}
 
 	return &CircuitBreaker{
@@ -39,6 +40,16 @@ func NewCircuitBreaker(config *CircuitBreakerConfig) *CircuitBreaker {
 	}
 }
 
+// DefaultCircuitBreakerConfig is used when no config is given.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: 5,
+		SuccessThreshold: 3,
+		HalfOpenTimeout:  10 * time.Second,
+		ResetTimeout:     60 * time.Second,
+	}
+}
+
 type CircuitState int
 
 const (
@@ -71,19 +82,30 @@ func (cb *CircuitBreaker) Execute(fn func() error) error {
 
 	err := fn()
 
+	if IsCanceled(err) {
+		// The caller gave up; the call tells nothing about the agent.
+		return err
+	}
+
 	cb.mu.Lock()
 	defer cb.mu.Unlock()
 
-	if err != nil {
+	cb.calls++
+
+	// A permanent error means the agent answered and rejected the input;
+	// it says nothing about the agent's health. An accepted job is an
+	// answer too.
+	if _, async := AsAsyncJob(err); err != nil && !IsPermanent(err) && !async {
 		cb.handleFailure()
 		return err
 	}
 
 	cb.handleSuccess()
-	return nil
+	return err
 }
 
 func (cb *CircuitBreaker) handleFailure() {
+	cb.failures++
 	cb.failureCount++
 	cb.consecutiveErrors++
 	cb.lastFailure = time.Now()
@@ -112,6 +134,38 @@ func (cb *CircuitBreaker) handleSuccess() {
 	}
 }
 
+// RetryAfter returns how long an open circuit stays open before letting a
+// trial call through.
+func (cb *CircuitBreaker) RetryAfter() time.Duration {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	if cb.state != StateOpen {
+		return 0
+	}
+	if d := cb.resetTimeout - time.Since(cb.lastFailure); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// Ready reports whether Execute would let a call through now.
+func (cb *CircuitBreaker) Ready() bool {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return cb.state != StateOpen || time.Since(cb.lastFailure) > cb.resetTimeout
+}
+
+// Stats returns how many calls the breaker let through since it was
+// created, and how many of them failed.
+func (cb *CircuitBreaker) Stats() (calls, failures int) {
+	cb.mu.RLock()
+	defer cb.mu.RUnlock()
+
+	return cb.calls, cb.failures
+}
+
 func (cb *CircuitBreaker) State() CircuitState {
 	cb.mu.RLock()
 	defer cb.mu.RUnlock()
@@ -130,5 +184,7 @@ func (cb *CircuitBreaker) Metrics() map[string]interface{} {
 		"last_failure":       cb.lastFailure,
 		"last_success":       cb.lastSuccess,
 		"is_open":            cb.state == StateOpen,
+		"calls":              cb.calls,
+		"failures":           cb.failures,
 	}
 }
\ No newline at end of file
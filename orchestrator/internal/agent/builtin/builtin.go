@@ -0,0 +1,144 @@
+// Package builtin provides agents built into the orchestrator for the
+// plumbing steps of plans: merging objects, picking values out of JSON,
+// rendering text, waiting, and making plain HTTP requests. They run in the
+// process that runs the step and are named under agent.BuiltinPrefix,
+// which no other agent may use.
+//
+// Every agent documents its input in its InputSchema, which the router
+// checks calls against. Inputs that do not fit fail permanently.
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+const (
+	// defaultMaxSleep bounds builtin.sleep.
+	defaultMaxSleep = time.Hour
+	// defaultMaxOutput bounds what builtin.template renders and what
+	// builtin.http reads of a response.
+	defaultMaxOutput = 10 << 20
+)
+
+type options struct {
+	httpClient *http.Client
+	httpHosts  []string
+	maxSleep   time.Duration
+	maxOutput  int64
+	// allowPrivate lets builtin.http reach private addresses; tests serve
+	// on loopback.
+	allowPrivate bool
+}
+
+type Option func(*options)
+
+// WithHTTPClient sets the client builtin.http makes its requests with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(o *options) {
+		o.httpClient = client
+	}
+}
+
+// WithHTTPHosts allows builtin.http to request the given hosts, redirects
+// included. A host of the form "*.example.com" allows every subdomain of
+// example.com. Without it builtin.http is not provided. Whatever the
+// hosts, it never connects to loopback, link-local or private addresses.
+func WithHTTPHosts(hosts ...string) Option {
+	return func(o *options) {
+		o.httpHosts = append(o.httpHosts, hosts...)
+	}
+}
+
+// WithMaxSleep bounds how long builtin.sleep may be asked to wait; the
+// default is an hour. A wait is also bounded by the timeout of its step,
+// which a plan sets with timeout_seconds.
+func WithMaxSleep(d time.Duration) Option {
+	return func(o *options) {
+		o.maxSleep = d
+	}
+}
+
+// WithMaxOutput bounds the text builtin.template renders and the response
+// body builtin.http reads; the default is 10 MiB.
+func WithMaxOutput(n int64) Option {
+	return func(o *options) {
+		o.maxOutput = n
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		httpClient: http.DefaultClient,
+		maxSleep:   defaultMaxSleep,
+		maxOutput:  defaultMaxOutput,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Agents returns every built-in agent; builtin.http only once hosts are
+// allowed with WithHTTPHosts.
+func Agents(opts ...Option) []agent.Client {
+	o := newOptions(opts)
+	agents := []agent.Client{
+		&mergeAgent{},
+		&jqAgent{},
+		&templateAgent{maxOutput: o.maxOutput},
+		&sleepAgent{max: o.maxSleep},
+	}
+	if len(o.httpHosts) > 0 {
+		agents = append(agents, newHTTPAgent(o))
+	}
+	return agents
+}
+
+// Register registers every built-in agent with registry.
+func Register(registry *agent.Registry, opts ...Option) {
+	for _, a := range Agents(opts...) {
+		registry.Replace(a)
+	}
+}
+
+// decodeInput decodes the input of a call to the agent called name into v,
+// keeping numbers as they were written.
+func decodeInput(name string, input json.RawMessage, v any) error {
+	if len(input) == 0 {
+		input = json.RawMessage(`{}`)
+	}
+	dec := json.NewDecoder(bytes.NewReader(input))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return permanent(name, fmt.Errorf("decode input: %w", err))
+	}
+	return nil
+}
+
+func encodeOutput(name string, v any) (json.RawMessage, error) {
+	out, err := json.Marshal(v)
+	if err != nil {
+		return nil, permanent(name, fmt.Errorf("encode output: %w", err))
+	}
+	return out, nil
+}
+
+func permanent(name string, err error) error {
+	return &agent.Error{Kind: agent.KindPermanent, Agent: name, Err: err}
+}
+
+// contextError classifies a call that ended because ctx did.
+func contextError(name string, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return &agent.Error{Kind: agent.KindTimeout, Agent: name, Err: err}
+	}
+	return &agent.Error{Kind: agent.KindCanceled, Agent: name, Err: err}
+}
@@ -0,0 +1,97 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// call calls a through a router, so that input and output are checked
+// against the agent's schemas as they are in the runner.
+func call(t *testing.T, a agent.Client, input string) (json.RawMessage, error) {
+	t.Helper()
+	return callContext(t, context.Background(), a, input)
+}
+
+func callContext(t *testing.T, ctx context.Context, a agent.Client, input string) (json.RawMessage, error) {
+	t.Helper()
+
+	registry := agent.NewRegistry()
+	registry.Register(a)
+	return agent.NewRouter(registry).Call(ctx, a.Name(), json.RawMessage(input))
+}
+
+// wantKind fails the test unless err is an agent error of kind.
+func wantKind(t *testing.T, err error, kind agent.ErrorKind) {
+	t.Helper()
+
+	var agentErr *agent.Error
+	if !errors.As(err, &agentErr) {
+		t.Fatalf("err = %v, want an *agent.Error", err)
+	}
+	if agentErr.Kind != kind {
+		t.Errorf("kind = %s, want %s (%v)", agentErr.Kind, kind, err)
+	}
+}
+
+// wantJSON fails the test unless got and want are the same JSON value.
+func wantJSON(t *testing.T, got json.RawMessage, want string) {
+	t.Helper()
+
+	var g, w any
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("output %s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("want %s: %v", want, err)
+	}
+	gb, _ := json.Marshal(g)
+	wb, _ := json.Marshal(w)
+	if string(gb) != string(wb) {
+		t.Errorf("output = %s, want %s", got, want)
+	}
+}
+
+func TestAgentsAreBuiltin(t *testing.T) {
+	for _, a := range Agents(WithHTTPHosts("example.com")) {
+		if !strings.HasPrefix(a.Name(), agent.BuiltinPrefix) {
+			t.Errorf("agent %s is outside the builtin namespace", a.Name())
+		}
+
+		metadata := agent.Describe(a).Metadata
+		if len(metadata.InputSchema) == 0 || len(metadata.OutputSchema) == 0 {
+			t.Errorf("agent %s does not document its schemas", a.Name())
+		}
+		if err := metadata.Validate(); err != nil {
+			t.Errorf("agent %s: %v", a.Name(), err)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	registry := agent.NewRegistry()
+	Register(registry, WithHTTPHosts("example.com"))
+
+	for _, name := range []string{"builtin.merge", "builtin.jq", "builtin.template", "builtin.sleep", "builtin.http"} {
+		if _, err := registry.Get(name); err != nil {
+			t.Errorf("Get(%s): %v", name, err)
+		}
+	}
+
+	registry = agent.NewRegistry()
+	Register(registry)
+	if _, err := registry.Get("builtin.http"); err == nil {
+		t.Error("builtin.http was registered without allowed hosts")
+	}
+}
+
+func TestReservedNames(t *testing.T) {
+	cfg := agent.Config{Name: "builtin.merge", BaseURL: "http://localhost:8080"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("a config took a builtin name")
+	}
+}
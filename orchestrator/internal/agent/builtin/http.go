@@ -0,0 +1,275 @@
+package builtin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// httpAgent makes a plain HTTP request:
+//
+//	{"method": "POST", "url": "https://example.com/hooks", "headers": {"Authorization": "Bearer ..."}, "body": {"event": "done"}}
+//	→ {"status": 200, "headers": {"Content-Type": "application/json"}, "body": {"ok": true}}
+//
+// A body is sent as JSON. A JSON response body is returned as JSON, any
+// other as a string. Responses with status 400 and above fail the call and
+// are retried as responses of HTTP agents are: 408, 429 and 5xx
+// transiently, the others never.
+//
+// Only the allowed hosts may be requested, and only at public addresses:
+// the address a request connects to is checked when it is dialed, so
+// neither a redirect nor a name that resolves to an internal address
+// reaches the metadata service, localhost or the orchestrator itself.
+type httpAgent struct {
+	client    *http.Client
+	hosts     []string
+	maxOutput int64
+}
+
+const httpName = agent.BuiltinPrefix + "http"
+
+func newHTTPAgent(o options) *httpAgent {
+	a := &httpAgent{
+		hosts:     o.httpHosts,
+		maxOutput: o.maxOutput,
+	}
+
+	client := *o.httpClient
+	checkRedirect := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := a.checkHost(req.URL); err != nil {
+			return err
+		}
+		if checkRedirect != nil {
+			return checkRedirect(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	if !o.allowPrivate {
+		client.Transport = publicTransport(client.Transport)
+	}
+	a.client = &client
+	return a
+}
+
+// publicTransport returns a copy of rt that only connects to public
+// addresses. Proxies are not used: a proxy would connect on its behalf,
+// past the check. A RoundTripper other than an *http.Transport is
+// returned as it is.
+func publicTransport(rt http.RoundTripper) http.RoundTripper {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return rt
+	}
+
+	t = t.Clone()
+	t.Proxy = nil
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   checkAddress,
+	}
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+// cgnat is the shared address space of carrier-grade NAT, which cloud
+// providers also use for internal services.
+var cgnat = netip.MustParsePrefix("100.64.0.0/10")
+
+// checkAddress refuses to connect to an address that is not public. It
+// runs for every address a name resolves to, right before connecting.
+func checkAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return &hostError{host: address}
+	}
+
+	ip := addrPort.Addr().Unmap()
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || cgnat.Contains(ip) {
+		return &hostError{host: ip.String(), private: true}
+	}
+	return nil
+}
+
+func (h *httpAgent) Name() string {
+	return httpName
+}
+
+func (h *httpAgent) Metadata() agent.Metadata {
+	return agent.Metadata{
+		Description: "Makes an HTTP request and returns the response.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"method": {
+					"description": "The request method; GET by default.",
+					"type": "string",
+					"enum": ["GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"]
+				},
+				"url": {
+					"description": "An http or https URL.",
+					"type": "string",
+					"pattern": "^https?://"
+				},
+				"headers": {
+					"description": "Request headers.",
+					"type": "object",
+					"additionalProperties": {"type": "string"}
+				},
+				"body": {
+					"description": "A value sent as the JSON request body."
+				}
+			},
+			"required": ["url"],
+			"additionalProperties": false
+		}`),
+		OutputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"status": {"type": "integer"},
+				"headers": {
+					"type": "object",
+					"additionalProperties": {"type": "string"}
+				},
+				"body": {}
+			},
+			"required": ["status", "headers", "body"]
+		}`),
+		Tags: []string{"builtin"},
+	}
+}
+
+func (h *httpAgent) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	var in struct {
+		Method  string            `json:"method"`
+		URL     string            `json:"url"`
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+	}
+	if err := decodeInput(httpName, input, &in); err != nil {
+		return nil, err
+	}
+	if in.Method == "" {
+		in.Method = http.MethodGet
+	}
+
+	u, err := url.Parse(in.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, permanent(httpName, fmt.Errorf("invalid url %q", in.URL))
+	}
+	if err := h.checkHost(u); err != nil {
+		return nil, permanent(httpName, err)
+	}
+
+	var body io.Reader
+	if len(in.Body) > 0 && string(in.Body) != "null" {
+		body = bytes.NewReader(in.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, in.Method, u.String(), body)
+	if err != nil {
+		return nil, permanent(httpName, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range in.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		var hostErr *hostError
+		if errors.As(err, &hostErr) {
+			return nil, permanent(httpName, hostErr)
+		}
+		return nil, agent.TransportError(httpName, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		agentErr := agent.StatusError(httpName, resp)
+		agentErr.Err = fmt.Errorf("%s %s returned status %d", in.Method, u.Redacted(), resp.StatusCode)
+		return nil, agentErr
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, h.maxOutput+1))
+	if err != nil {
+		return nil, agent.TransportError(httpName, fmt.Errorf("read response: %w", err))
+	}
+	if int64(len(data)) > h.maxOutput {
+		return nil, permanent(httpName, fmt.Errorf("response exceeds %d bytes", h.maxOutput))
+	}
+
+	headers := make(map[string]string, len(resp.Header))
+	for k := range resp.Header {
+		headers[k] = resp.Header.Get(k)
+	}
+	out := struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    any               `json:"body"`
+	}{
+		Status:  resp.StatusCode,
+		Headers: headers,
+		Body:    string(data),
+	}
+	if isJSON(resp.Header.Get("Content-Type")) && json.Valid(data) {
+		out.Body = json.RawMessage(data)
+	}
+	return encodeOutput(httpName, out)
+}
+
+// hostError refuses a request to a host outside the allowed ones, or to
+// an address that is not public.
+type hostError struct {
+	host    string
+	private bool
+}
+
+func (e *hostError) Error() string {
+	if e.private {
+		return fmt.Sprintf("address %s is not public", e.host)
+	}
+	return fmt.Sprintf("host %s is not allowed", e.host)
+}
+
+func (h *httpAgent) checkHost(u *url.URL) error {
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range h.hosts {
+		allowed = strings.ToLower(allowed)
+		if domain, ok := strings.CutPrefix(allowed, "*."); ok && strings.HasSuffix(host, "."+domain) {
+			return nil
+		}
+		if host == allowed {
+			return nil
+		}
+	}
+	return &hostError{host: host}
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
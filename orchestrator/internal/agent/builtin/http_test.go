@@ -0,0 +1,222 @@
+package builtin
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+func TestHTTP(t *testing.T) {
+	var got struct {
+		method string
+		header http.Header
+		body   string
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got.method, got.header, got.body = r.Method, r.Header, string(body)
+
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.Write([]byte(`{"ok": true}`))
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("pong"))
+		}
+	}))
+	defer srv.Close()
+
+	a := testHTTPAgent()
+
+	out, err := call(t, a, `{"method": "POST", "url": "`+srv.URL+`/json", "headers": {"X-Token": "secret"}, "body": {"event": "done"}}`)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	var resp struct {
+		Status  int               `json:"status"`
+		Headers map[string]string `json:"headers"`
+		Body    json.RawMessage   `json:"body"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Status != http.StatusOK || resp.Headers["Content-Type"] != "application/json; charset=utf-8" {
+		t.Errorf("status, headers = %d, %v", resp.Status, resp.Headers)
+	}
+	wantJSON(t, resp.Body, `{"ok": true}`)
+	if got.method != http.MethodPost || got.header.Get("X-Token") != "secret" ||
+		got.header.Get("Content-Type") != "application/json" {
+		t.Errorf("request = %s %v", got.method, got.header)
+	}
+	wantJSON(t, json.RawMessage(got.body), `{"event": "done"}`)
+
+	out, err = call(t, a, `{"url": "`+srv.URL+`/text"}`)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if got.method != http.MethodGet || got.body != "" {
+		t.Errorf("request = %s with body %q, want a GET without one", got.method, got.body)
+	}
+	if !strings.Contains(string(out), `"body":"pong"`) {
+		t.Errorf("output = %s, want the text body as a string", out)
+	}
+}
+
+func TestHTTPStatusErrors(t *testing.T) {
+	tests := []struct {
+		status int
+		kind   agent.ErrorKind
+	}{
+		{http.StatusBadRequest, agent.KindPermanent},
+		{http.StatusNotFound, agent.KindPermanent},
+		{http.StatusRequestTimeout, agent.KindTimeout},
+		{http.StatusTooManyRequests, agent.KindRateLimited},
+		{http.StatusInternalServerError, agent.KindTransient},
+		{http.StatusServiceUnavailable, agent.KindTransient},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			_, err := call(t, testHTTPAgent(), `{"url": "`+srv.URL+`"}`)
+			wantKind(t, err, tt.kind)
+		})
+	}
+}
+
+func TestHTTPUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	addr := srv.URL
+	srv.Close()
+
+	_, err := call(t, testHTTPAgent(), `{"url": "`+addr+`"}`)
+	wantKind(t, err, agent.KindTransient)
+}
+
+func TestHTTPResponseLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 101)))
+	}))
+	defer srv.Close()
+
+	_, err := call(t, testHTTPAgent(WithMaxOutput(100)), `{"url": "`+srv.URL+`"}`)
+	wantKind(t, err, agent.KindPermanent)
+}
+
+func TestHTTPHosts(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "http://example.com/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	a := newHTTPAgent(newOptions([]Option{WithHTTPHosts("127.0.0.1", "*.internal.example"), allowPrivate()}))
+
+	if _, err := call(t, a, `{"url": "`+srv.URL+`"}`); err != nil {
+		t.Errorf("allowed host: %v", err)
+	}
+
+	_, err := call(t, a, `{"url": "`+srv.URL+`/redirect"}`)
+	wantKind(t, err, agent.KindPermanent)
+
+	for _, u := range []string{
+		"http://example.com/",
+		"http://internal.example/",
+		"http://evilinternal.example/",
+	} {
+		_, err := call(t, a, `{"url": "`+u+`"}`)
+		wantKind(t, err, agent.KindPermanent)
+	}
+
+	for _, host := range []string{"api.internal.example", "a.b.internal.example"} {
+		if err := a.checkHost(mustParseURL(t, "https://"+host+"/")); err != nil {
+			t.Errorf("checkHost(%s): %v", host, err)
+		}
+	}
+}
+
+func TestHTTPPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	port := mustParseURL(t, srv.URL).Port()
+	a := newHTTPAgent(newOptions([]Option{WithHTTPHosts("127.0.0.1", "localhost")}))
+
+	for _, u := range []string{srv.URL, "http://localhost:" + port} {
+		_, err := call(t, a, `{"url": "`+u+`"}`)
+		wantKind(t, err, agent.KindPermanent)
+		if err == nil || !strings.Contains(err.Error(), "is not public") {
+			t.Errorf("%s: err = %v, want the address refused", u, err)
+		}
+	}
+
+	for addr, public := range map[string]bool{
+		"127.0.0.1:80":          false,
+		"10.1.2.3:80":           false,
+		"172.16.0.1:80":         false,
+		"192.168.1.1:80":        false,
+		"169.254.169.254:80":    false,
+		"100.100.100.200:80":    false,
+		"0.0.0.0:80":            false,
+		"[::1]:80":              false,
+		"[fd00::1]:80":          false,
+		"[fe80::1]:80":          false,
+		"[::ffff:127.0.0.1]:80": false,
+		"93.184.216.34:443":     true,
+		"[2606:4700::1]:443":    true,
+	} {
+		if err := checkAddress("tcp", addr, nil); (err == nil) != public {
+			t.Errorf("checkAddress(%s) = %v, want public %t", addr, err, public)
+		}
+	}
+}
+
+func TestHTTPInvalidInput(t *testing.T) {
+	for _, input := range []string{
+		`{}`,
+		`{"url": "ftp://example.com/"}`,
+		`{"url": "http://example.com/", "method": "CONNECT"}`,
+		`{"url": "http://example.com/", "headers": {"X-N": 1}}`,
+	} {
+		_, err := call(t, testHTTPAgent(), input)
+		wantKind(t, err, agent.KindPermanent)
+	}
+}
+
+// testHTTPAgent returns builtin.http allowed to request the test servers.
+func testHTTPAgent(opts ...Option) *httpAgent {
+	opts = append([]Option{WithHTTPHosts("127.0.0.1"), allowPrivate()}, opts...)
+	return newHTTPAgent(newOptions(opts))
+}
+
+func allowPrivate() Option {
+	return func(o *options) {
+		o.allowPrivate = true
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
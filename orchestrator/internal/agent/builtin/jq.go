@@ -0,0 +1,135 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/itchyny/gojq"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// maxJQResults bounds how many values a query may produce.
+const maxJQResults = 10000
+
+// jqAgent runs a jq query against a JSON value:
+//
+//	{"query": ".items[] | select(.n > $min) | .name", "input": {"items": [...]}, "vars": {"min": 3}}
+//	→ {"result": "first", "results": ["first", "second"]}
+//
+// Results holds every value the query produced and result the first, or
+// null when there is none. Vars are bound to $name. The query sees neither
+// the environment nor any file.
+type jqAgent struct{}
+
+const jqName = agent.BuiltinPrefix + "jq"
+
+func (j *jqAgent) Name() string {
+	return jqName
+}
+
+func (j *jqAgent) Metadata() agent.Metadata {
+	return agent.Metadata{
+		Description: "Runs a jq query against a JSON value.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"query": {
+					"description": "The jq query, e.g. .items[0].name.",
+					"type": "string",
+					"minLength": 1
+				},
+				"input": {
+					"description": "The value the query runs against; null when missing."
+				},
+				"vars": {
+					"description": "Values bound to $name in the query.",
+					"type": "object",
+					"propertyNames": {"pattern": "^[A-Za-z_][A-Za-z0-9_]*$"}
+				}
+			},
+			"required": ["query"],
+			"additionalProperties": false
+		}`),
+		OutputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"result": {},
+				"results": {"type": "array"}
+			},
+			"required": ["result", "results"]
+		}`),
+		Tags: []string{"builtin"},
+	}
+}
+
+func (j *jqAgent) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	var in struct {
+		Query string         `json:"query"`
+		Input any            `json:"input"`
+		Vars  map[string]any `json:"vars"`
+	}
+	if err := decodeInput(jqName, input, &in); err != nil {
+		return nil, err
+	}
+
+	query, err := gojq.Parse(in.Query)
+	if err != nil {
+		return nil, permanent(jqName, fmt.Errorf("parse query: %w", err))
+	}
+
+	names := make([]string, 0, len(in.Vars))
+	for name := range in.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	vars := make([]string, len(names))
+	values := make([]any, len(names))
+	for i, name := range names {
+		vars[i] = "$" + name
+		values[i] = in.Vars[name]
+	}
+
+	code, err := gojq.Compile(query,
+		gojq.WithVariables(vars),
+		// $ENV and env would hand the orchestrator's secrets to the plan.
+		gojq.WithEnvironLoader(func() []string { return nil }),
+	)
+	if err != nil {
+		return nil, permanent(jqName, fmt.Errorf("compile query: %w", err))
+	}
+
+	results := []any{}
+	iter := code.RunWithContext(ctx, in.Input, values...)
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			if ctx.Err() != nil {
+				return nil, contextError(jqName, ctx.Err())
+			}
+			if halt, ok := err.(*gojq.HaltError); ok && halt.Value() == nil {
+				// halt ends the query without an error.
+				break
+			}
+			return nil, permanent(jqName, fmt.Errorf("run query: %w", err))
+		}
+		if len(results) == maxJQResults {
+			return nil, permanent(jqName, fmt.Errorf("query produced more than %d results", maxJQResults))
+		}
+		results = append(results, v)
+	}
+
+	out := struct {
+		Result  any   `json:"result"`
+		Results []any `json:"results"`
+	}{Results: results}
+	if len(results) > 0 {
+		out.Result = results[0]
+	}
+	return encodeOutput(jqName, out)
+}
@@ -0,0 +1,97 @@
+package builtin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+func TestJQ(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "field",
+			input: `{"query": ".user.name", "input": {"user": {"name": "ada"}}}`,
+			want:  `{"result": "ada", "results": ["ada"]}`,
+		},
+		{
+			name:  "several results",
+			input: `{"query": ".[] | .n * 2", "input": [{"n": 1}, {"n": 2}]}`,
+			want:  `{"result": 2, "results": [2, 4]}`,
+		},
+		{
+			name:  "no result",
+			input: `{"query": "empty", "input": 1}`,
+			want:  `{"result": null, "results": []}`,
+		},
+		{
+			name:  "missing input is null",
+			input: `{"query": "."}`,
+			want:  `{"result": null, "results": [null]}`,
+		},
+		{
+			name:  "vars",
+			input: `{"query": "[.[] | select(. > $min)]", "input": [1, 5, 10], "vars": {"min": 4}}`,
+			want:  `{"result": [5, 10], "results": [[5, 10]]}`,
+		},
+		{
+			name:  "no environment",
+			input: `{"query": "$ENV | length"}`,
+			want:  `{"result": 0, "results": [0]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := call(t, &jqAgent{}, tt.input)
+			if err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			wantJSON(t, out, tt.want)
+		})
+	}
+}
+
+func TestJQNumbersKeepPrecision(t *testing.T) {
+	out, err := call(t, &jqAgent{}, `{"query": ".id", "input": {"id": 12345678901234567890}}`)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if string(out) != `{"result":12345678901234567890,"results":[12345678901234567890]}` {
+		t.Errorf("output = %s, want the id unchanged", out)
+	}
+}
+
+func TestJQErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"syntax error", `{"query": ".["}`},
+		{"runtime error", `{"query": ".a.b", "input": [1]}`},
+		{"undefined variable", `{"query": "$missing"}`},
+		{"invalid variable name", `{"query": ".", "vars": {"not a name": 1}}`},
+		{"no files", `{"query": "input"}`},
+		{"too many results", `{"query": "range(20000)"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := call(t, &jqAgent{}, tt.input)
+			wantKind(t, err, agent.KindPermanent)
+		})
+	}
+}
+
+func TestJQTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := callContext(t, ctx, &jqAgent{}, `{"query": "last(repeat(1))"}`)
+	wantKind(t, err, agent.KindTimeout)
+}
@@ -0,0 +1,78 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// mergeAgent combines JSON objects into one:
+//
+//	{"objects": [{"a": 1, "b": {"x": 1}}, {"b": {"y": 2}}], "deep": true}
+//	→ {"a": 1, "b": {"x": 1, "y": 2}}
+//
+// Keys of later objects win. With deep, objects under the same key are
+// merged the same way; anything else, arrays included, is replaced.
+type mergeAgent struct{}
+
+const mergeName = agent.BuiltinPrefix + "merge"
+
+func (m *mergeAgent) Name() string {
+	return mergeName
+}
+
+func (m *mergeAgent) Metadata() agent.Metadata {
+	return agent.Metadata{
+		Description: "Merges JSON objects into one; keys of later objects win.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"objects": {
+					"description": "The objects to merge, in order.",
+					"type": "array",
+					"items": {"type": "object"},
+					"minItems": 1
+				},
+				"deep": {
+					"description": "Merge nested objects instead of replacing them.",
+					"type": "boolean"
+				}
+			},
+			"required": ["objects"],
+			"additionalProperties": false
+		}`),
+		OutputSchema: json.RawMessage(`{"type": "object"}`),
+		Tags:         []string{"builtin"},
+	}
+}
+
+func (m *mergeAgent) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	var in struct {
+		Objects []map[string]any `json:"objects"`
+		Deep    bool             `json:"deep"`
+	}
+	if err := decodeInput(mergeName, input, &in); err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]any)
+	for _, obj := range in.Objects {
+		mergeInto(out, obj, in.Deep)
+	}
+	return encodeOutput(mergeName, out)
+}
+
+func mergeInto(dst, src map[string]any, deep bool) {
+	for k, v := range src {
+		if deep {
+			from, ok := v.(map[string]any)
+			into, isObject := dst[k].(map[string]any)
+			if ok && isObject {
+				mergeInto(into, from, deep)
+				continue
+			}
+		}
+		dst[k] = v
+	}
+}
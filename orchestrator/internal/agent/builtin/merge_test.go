@@ -0,0 +1,73 @@
+package builtin
+
+import (
+	"testing"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "later keys win",
+			input: `{"objects": [{"a": 1, "b": 2}, {"b": 3, "c": 4}]}`,
+			want:  `{"a": 1, "b": 3, "c": 4}`,
+		},
+		{
+			name:  "shallow replaces nested objects",
+			input: `{"objects": [{"a": {"x": 1}}, {"a": {"y": 2}}]}`,
+			want:  `{"a": {"y": 2}}`,
+		},
+		{
+			name:  "deep merges nested objects",
+			input: `{"objects": [{"a": {"x": 1, "z": {"p": 1}}}, {"a": {"y": 2, "z": {"q": 2}}}], "deep": true}`,
+			want:  `{"a": {"x": 1, "y": 2, "z": {"p": 1, "q": 2}}}`,
+		},
+		{
+			name:  "deep replaces arrays",
+			input: `{"objects": [{"a": [1, 2]}, {"a": [3]}], "deep": true}`,
+			want:  `{"a": [3]}`,
+		},
+		{
+			name:  "deep replaces an object with a scalar",
+			input: `{"objects": [{"a": {"x": 1}}, {"a": null}], "deep": true}`,
+			want:  `{"a": null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := call(t, &mergeAgent{}, tt.input)
+			if err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			wantJSON(t, out, tt.want)
+		})
+	}
+}
+
+func TestMergeNumbersKeepPrecision(t *testing.T) {
+	out, err := call(t, &mergeAgent{}, `{"objects": [{"id": 12345678901234567890}]}`)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if string(out) != `{"id":12345678901234567890}` {
+		t.Errorf("output = %s, want the id unchanged", out)
+	}
+}
+
+func TestMergeInvalidInput(t *testing.T) {
+	for _, input := range []string{
+		`{}`,
+		`{"objects": []}`,
+		`{"objects": [1]}`,
+		`{"objects": [{}], "extra": true}`,
+	} {
+		_, err := call(t, &mergeAgent{}, input)
+		wantKind(t, err, agent.KindPermanent)
+	}
+}
@@ -0,0 +1,80 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// sleepAgent waits before answering:
+//
+//	{"seconds": 1.5}
+//	→ {"slept_seconds": 1.5}
+//
+// The wait counts against the step's timeout like any call. A wait longer
+// than the step has left fails at once and is not retried: the step needs
+// a longer timeout_seconds in its plan.
+type sleepAgent struct {
+	max time.Duration
+}
+
+const sleepName = agent.BuiltinPrefix + "sleep"
+
+func (s *sleepAgent) Name() string {
+	return sleepName
+}
+
+func (s *sleepAgent) Metadata() agent.Metadata {
+	return agent.Metadata{
+		Description: "Waits for a number of seconds, no longer than the step's timeout.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"seconds": {
+					"description": "How long to wait; fractions are allowed.",
+					"type": "number",
+					"minimum": 0
+				}
+			},
+			"required": ["seconds"],
+			"additionalProperties": false
+		}`),
+		OutputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {"slept_seconds": {"type": "number"}},
+			"required": ["slept_seconds"]
+		}`),
+		Tags: []string{"builtin"},
+	}
+}
+
+func (s *sleepAgent) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	var in struct {
+		Seconds float64 `json:"seconds"`
+	}
+	if err := decodeInput(sleepName, input, &in); err != nil {
+		return nil, err
+	}
+
+	if in.Seconds < 0 || in.Seconds > s.max.Seconds() {
+		return nil, permanent(sleepName, fmt.Errorf("seconds must be between 0 and %g", s.max.Seconds()))
+	}
+
+	wait := time.Duration(in.Seconds * float64(time.Second))
+	if deadline, ok := ctx.Deadline(); ok && wait > time.Until(deadline) {
+		return nil, permanent(sleepName, fmt.Errorf("seconds %g exceed the %s left before the step times out",
+			in.Seconds, time.Until(deadline).Round(time.Second)))
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return nil, contextError(sleepName, ctx.Err())
+	case <-timer.C:
+	}
+	return encodeOutput(sleepName, map[string]float64{"slept_seconds": in.Seconds})
+}
@@ -0,0 +1,58 @@
+package builtin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+func TestSleep(t *testing.T) {
+	start := time.Now()
+	out, err := call(t, &sleepAgent{max: time.Minute}, `{"seconds": 0.05}`)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("slept %v, want 50ms", elapsed)
+	}
+	wantJSON(t, out, `{"slept_seconds": 0.05}`)
+}
+
+func TestSleepPastDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	_, err := callContext(t, ctx, &sleepAgent{max: time.Minute}, `{"seconds": 10}`)
+	wantKind(t, err, agent.KindPermanent)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("a sleep past the deadline waited %v before failing", elapsed)
+	}
+}
+
+func TestSleepCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := callContext(t, ctx, &sleepAgent{max: time.Minute}, `{"seconds": 10}`)
+	wantKind(t, err, agent.KindCanceled)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("sleep outlived its context by %v", elapsed)
+	}
+}
+
+func TestSleepInvalidInput(t *testing.T) {
+	for _, input := range []string{
+		`{}`,
+		`{"seconds": -1}`,
+		`{"seconds": "1"}`,
+		`{"seconds": 61}`,
+		`{"seconds": 1e300}`,
+	} {
+		_, err := call(t, &sleepAgent{max: time.Minute}, input)
+		wantKind(t, err, agent.KindPermanent)
+	}
+}
@@ -0,0 +1,121 @@
+package builtin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// templateAgent renders a Go text/template with JSON data:
+//
+//	{"template": "Hello {{.name}}, you have {{len .items}} items", "data": {"name": "Ada", "items": [1, 2]}}
+//	→ {"text": "Hello Ada, you have 2 items"}
+//
+// A key missing from the data fails the call instead of rendering as
+// "<no value>". Besides the text/template builtins, templates can call
+// json, upper, lower, trim, join and replace.
+type templateAgent struct {
+	maxOutput int64
+}
+
+const templateName = agent.BuiltinPrefix + "template"
+
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"trim":  strings.TrimSpace,
+	"join": func(sep string, items []any) string {
+		parts := make([]string, len(items))
+		for i, item := range items {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, sep)
+	},
+	"replace": func(old, new, s string) string {
+		return strings.ReplaceAll(s, old, new)
+	},
+}
+
+func (t *templateAgent) Name() string {
+	return templateName
+}
+
+func (t *templateAgent) Metadata() agent.Metadata {
+	return agent.Metadata{
+		Description: "Renders a Go text/template with JSON data.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {
+				"template": {
+					"description": "The text/template source, e.g. Hello {{.name}}.",
+					"type": "string"
+				},
+				"data": {
+					"description": "The value the template renders as dot."
+				}
+			},
+			"required": ["template"],
+			"additionalProperties": false
+		}`),
+		OutputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {"text": {"type": "string"}},
+			"required": ["text"]
+		}`),
+		Tags: []string{"builtin"},
+	}
+}
+
+func (t *templateAgent) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	var in struct {
+		Template string `json:"template"`
+		Data     any    `json:"data"`
+	}
+	if err := decodeInput(templateName, input, &in); err != nil {
+		return nil, err
+	}
+
+	tmpl, err := template.New("template").
+		Option("missingkey=error").
+		Funcs(templateFuncs).
+		Parse(in.Template)
+	if err != nil {
+		return nil, permanent(templateName, fmt.Errorf("parse template: %w", err))
+	}
+
+	var text strings.Builder
+	w := &limitedWriter{w: &text, n: t.maxOutput}
+	if err := tmpl.Execute(w, in.Data); err != nil {
+		if errors.Is(err, errOutputLimit) {
+			err = fmt.Errorf("text exceeds %d bytes", t.maxOutput)
+		}
+		return nil, permanent(templateName, err)
+	}
+	return encodeOutput(templateName, map[string]string{"text": text.String()})
+}
+
+var errOutputLimit = errors.New("output limit exceeded")
+
+// limitedWriter fails writes once more than n bytes were written, which
+// stops a template that renders without end.
+type limitedWriter struct {
+	w *strings.Builder
+	n int64
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > l.n {
+		return 0, errOutputLimit
+	}
+	l.n -= int64(len(p))
+	return l.w.Write(p)
+}
@@ -0,0 +1,93 @@
+package builtin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+func TestTemplate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "fields",
+			input: `{"template": "Hello {{.name}}, you have {{len .items}} items", "data": {"name": "Ada", "items": [1, 2]}}`,
+			want:  "Hello Ada, you have 2 items",
+		},
+		{
+			name:  "range",
+			input: `{"template": "{{range .}}[{{.}}]{{end}}", "data": ["a", "b"]}`,
+			want:  "[a][b]",
+		},
+		{
+			name:  "numbers as written",
+			input: `{"template": "{{.n}} {{.f}}", "data": {"n": 12345678901234567890, "f": 1.50}}`,
+			want:  "12345678901234567890 1.50",
+		},
+		{
+			name:  "funcs",
+			input: `{"template": "{{upper .a}} {{lower .b}} {{trim .c}} {{join \", \" .d}} {{replace \"-\" \"_\" .e}}", "data": {"a": "x", "b": "Y", "c": " z ", "d": [1, "two"], "e": "a-b"}}`,
+			want:  "X y z 1, two a_b",
+		},
+		{
+			name:  "json",
+			input: `{"template": "{{json .}}", "data": {"a": [1, true]}}`,
+			want:  `{"a":[1,true]}`,
+		},
+		{
+			name:  "no data",
+			input: `{"template": "static"}`,
+			want:  "static",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := call(t, &templateAgent{maxOutput: defaultMaxOutput}, tt.input)
+			if err != nil {
+				t.Fatalf("Call: %v", err)
+			}
+			wantJSON(t, out, jsonString(t, tt.want))
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"syntax error", `{"template": "{{.name"}`},
+		{"missing key", `{"template": "{{.missing}}", "data": {"name": "Ada"}}`},
+		{"unknown func", `{"template": "{{shout .}}"}`},
+		{"no template", `{"data": {}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := call(t, &templateAgent{maxOutput: 100}, tt.input)
+			wantKind(t, err, agent.KindPermanent)
+		})
+	}
+}
+
+func TestTemplateOutputLimit(t *testing.T) {
+	_, err := call(t, &templateAgent{maxOutput: 100}, `{"template": "{{range 100}}0123456789{{end}}"}`)
+	if err == nil || !strings.Contains(err.Error(), "exceeds 100 bytes") {
+		t.Errorf("err = %v, want the output limit", err)
+	}
+}
+
+func jsonString(t *testing.T, text string) string {
+	t.Helper()
+
+	out, err := encodeOutput(templateName, map[string]string{"text": text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
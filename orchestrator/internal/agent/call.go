@@ -0,0 +1,96 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+)
+
+type callIDKey struct{}
+
+// WithCallID tags ctx with an ID for one agent call. Agents receive it with
+// the request and it identifies the call in a later Cancel.
+func WithCallID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, callIDKey{}, id)
+}
+
+// CallID returns the call ID set by WithCallID, or "".
+func CallID(ctx context.Context) string {
+	id, _ := ctx.Value(callIDKey{}).(string)
+	return id
+}
+
+type routingKey struct{}
+
+// WithRoutingKey tags ctx with the key a ConsistentHash pool uses to pick
+// an endpoint, so that calls with the same key reach the same replica.
+func WithRoutingKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, routingKey{}, key)
+}
+
+// RoutingKey returns the key set by WithRoutingKey, or "".
+func RoutingKey(ctx context.Context) string {
+	key, _ := ctx.Value(routingKey{}).(string)
+	return key
+}
+
+type taskLabelsKey struct{}
+
+// WithTaskLabels tags ctx with the labels of the task a call works for. A
+// Versioned agent pins calls to a version by them.
+func WithTaskLabels(ctx context.Context, labels []string) context.Context {
+	return context.WithValue(ctx, taskLabelsKey{}, labels)
+}
+
+// TaskLabels returns the labels set by WithTaskLabels, or nil.
+func TaskLabels(ctx context.Context) []string {
+	labels, _ := ctx.Value(taskLabelsKey{}).([]string)
+	return labels
+}
+
+// CallInfo is filled in while a call is served, for the caller to record.
+type CallInfo struct {
+	// Version is the version of the agent that served the call.
+	Version string
+	// Stderr is the end of what an exec agent wrote to stderr.
+	Stderr string
+}
+
+type callInfoKey struct{}
+
+// WithCallInfo returns a ctx whose calls report to the returned CallInfo.
+func WithCallInfo(ctx context.Context) (context.Context, *CallInfo) {
+	info := &CallInfo{}
+	return context.WithValue(ctx, callInfoKey{}, info), info
+}
+
+// callInfo returns the CallInfo of ctx, or nil when nobody asked for it.
+func callInfo(ctx context.Context) *CallInfo {
+	info, _ := ctx.Value(callInfoKey{}).(*CallInfo)
+	return info
+}
+
+// Progress is reported by streaming agents while a call runs.
+type Progress struct {
+	// Percent is how much of the work is done, from 0 to 100; zero when
+	// the agent did not say.
+	Percent float64
+	Message string
+	// Partial is a partial output, when the event carried one.
+	Partial json.RawMessage
+}
+
+type progressKey struct{}
+
+// WithProgress has streaming agents report the progress of calls made
+// with ctx to fn. fn is called on the calling goroutine, before the call
+// returns.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// reportProgress passes p to the function set by WithProgress, if any.
+func reportProgress(ctx context.Context, p Progress) {
+	if fn, ok := ctx.Value(progressKey{}).(func(Progress)); ok {
+		fn(p)
+	}
+}
@@ -0,0 +1,459 @@
+package agent
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Config describes an HTTP agent, or a gRPC agent when its URLs are
+// grpc:// or grpcs:// URLs, or a local command when Exec is set, or a
+// WebAssembly module when WASM is set. The agent catalog stores one per
+// name and builds its client with NewFromConfig.
+type Config struct {
+	Name    string `json:"name"`
+	BaseURL string `json:"base_url"`
+	// Replicas are further base URLs serving the same agent. With any
+	// set, the agent is a Pool that picks an endpoint by Strategy.
+	Replicas []string `json:"replicas,omitempty"`
+	Strategy Strategy `json:"strategy,omitempty"`
+
+	// TimeoutSeconds bounds each call; the default is 30. Steps planned
+	// for the agent take it as their timeout unless the plan sets one.
+	TimeoutSeconds int `json:"timeout_seconds"`
+	// AsyncTimeoutSeconds bounds how long a job the agent accepts with 202
+	// may take before its step fails. Zero keeps the runner's default.
+	AsyncTimeoutSeconds int `json:"async_timeout_seconds,omitempty"`
+	// MaxRetries is how often a failed step of this agent is retried when
+	// no retry policy is configured for it. Zero keeps the default policy.
+	MaxRetries int `json:"max_retries"`
+
+	CircuitBreaker bool `json:"circuit_breaker"`
+	// Breaker tunes the circuit breaker; nil keeps the defaults.
+	Breaker *BreakerConfig `json:"breaker,omitempty"`
+
+	HealthCheck bool `json:"health_check"`
+	// HealthCheckURL is an absolute URL or a path under each base URL.
+	// The default is /health.
+	HealthCheckURL string `json:"health_check_url,omitempty"`
+
+	Metadata
+
+	// Versions are further versions of the agent, each deployed at its own
+	// URLs; BaseURL and Replicas serve Metadata.Version. With any set, the
+	// agent is Versioned and Rollout routes calls between the versions.
+	Versions []VersionConfig `json:"versions,omitempty"`
+	// Rollout defaults to sending every call to Metadata.Version.
+	Rollout *Rollout `json:"rollout,omitempty"`
+
+	// Exec runs the agent as a local command instead of calling it at
+	// BaseURL, which must then be empty.
+	Exec *ExecConfig `json:"exec,omitempty"`
+	// WASM runs the agent as a WebAssembly module in process instead of
+	// calling it at BaseURL, which must then be empty.
+	WASM *WASMConfig `json:"wasm,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// BreakerConfig tunes the circuit breaker of a configured agent.
+type BreakerConfig struct {
+	FailureThreshold       int `json:"failure_threshold"`
+	SuccessThreshold       int `json:"success_threshold"`
+	HalfOpenTimeoutSeconds int `json:"half_open_timeout_seconds,omitempty"`
+	ResetTimeoutSeconds    int `json:"reset_timeout_seconds"`
+}
+
+func (b *BreakerConfig) validate() error {
+	if b.FailureThreshold < 1 || b.SuccessThreshold < 1 {
+		return errors.New("breaker thresholds must be at least 1")
+	}
+	if b.ResetTimeoutSeconds < 1 {
+		return errors.New("breaker reset_timeout_seconds must be at least 1")
+	}
+	if b.HalfOpenTimeoutSeconds < 0 {
+		return errors.New("breaker half_open_timeout_seconds must not be negative")
+	}
+	return nil
+}
+
+func (b *BreakerConfig) circuitBreaker() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		FailureThreshold: b.FailureThreshold,
+		SuccessThreshold: b.SuccessThreshold,
+		HalfOpenTimeout:  time.Duration(b.HalfOpenTimeoutSeconds) * time.Second,
+		ResetTimeout:     time.Duration(b.ResetTimeoutSeconds) * time.Second,
+	}
+}
+
+// VersionConfig is one further version of a configured agent.
+type VersionConfig struct {
+	Version  string   `json:"version"`
+	BaseURL  string   `json:"base_url"`
+	Replicas []string `json:"replicas,omitempty"`
+}
+
+// ExecConfig describes an agent that is a local command; see ExecAgent.
+type ExecConfig struct {
+	// Command is the absolute path of the program and its arguments.
+	Command []string `json:"command"`
+	// Env names the variables of the orchestrator's environment the
+	// command gets; it gets no others.
+	Env []string `json:"env,omitempty"`
+	// WorkDir is where the working directory of each call is created.
+	// The default is the system's temporary directory.
+	WorkDir string `json:"work_dir,omitempty"`
+	// MaxOutputBytes bounds stdout; the default is 16 MiB.
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty"`
+}
+
+func (e *ExecConfig) validate() error {
+	if len(e.Command) == 0 || !filepath.IsAbs(e.Command[0]) {
+		return errors.New("exec command must start with an absolute path")
+	}
+	if e.WorkDir != "" && !filepath.IsAbs(e.WorkDir) {
+		return errors.New("exec work_dir must be an absolute path")
+	}
+	if e.MaxOutputBytes < 0 {
+		return errors.New("exec max_output_bytes must not be negative")
+	}
+	return nil
+}
+
+// WASMConfig describes an agent that is a WebAssembly module; see
+// WASMAgent.
+type WASMConfig struct {
+	// Path is the absolute path of a module file present on every worker.
+	Path string `json:"path,omitempty"`
+	// Module is the digest of a module uploaded through the API. Exactly
+	// one of Path and Module is set.
+	Module string `json:"module,omitempty"`
+	// MaxMemoryBytes bounds the module's memory; the default is 64 MiB.
+	MaxMemoryBytes int64 `json:"max_memory_bytes,omitempty"`
+	// MaxOutputBytes bounds stdout; the default is 16 MiB.
+	MaxOutputBytes int64 `json:"max_output_bytes,omitempty"`
+}
+
+func (w *WASMConfig) validate() error {
+	if (w.Path == "") == (w.Module == "") {
+		return errors.New("wasm needs exactly one of path and module")
+	}
+	if w.Path != "" && !filepath.IsAbs(w.Path) {
+		return errors.New("wasm path must be an absolute path")
+	}
+	if w.Module != "" && !validDigest(w.Module) {
+		return fmt.Errorf("wasm module %q is not a sha256: digest", w.Module)
+	}
+	if w.MaxMemoryBytes < 0 || w.MaxOutputBytes < 0 {
+		return errors.New("wasm limits must not be negative")
+	}
+	return nil
+}
+
+func validDigest(d string) bool {
+	hexSum, ok := strings.CutPrefix(d, "sha256:")
+	if !ok || len(hexSum) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(hexSum)
+	return err == nil
+}
+
+const defaultTimeoutSeconds = 30
+
+// Validate checks the config and fills in defaults.
+func (c *Config) Validate() error {
+	if c.Name == "" {
+		return errors.New("name is required")
+	}
+	if strings.ContainsAny(c.Name, "/ ") {
+		return fmt.Errorf("name %q must not contain slashes or spaces", c.Name)
+	}
+	if strings.HasPrefix(c.Name, BuiltinPrefix) {
+		return fmt.Errorf("name %q is reserved for built-in agents", c.Name)
+	}
+	switch {
+	case c.Exec != nil && c.WASM != nil:
+		return errors.New("an agent is either exec or wasm")
+	case c.Exec != nil || c.WASM != nil:
+		if c.BaseURL != "" || len(c.Replicas) > 0 || len(c.Versions) > 0 {
+			return errors.New("exec and wasm agents take no base url, replicas or versions")
+		}
+		if c.Exec != nil {
+			if err := c.Exec.validate(); err != nil {
+				return err
+			}
+		} else if err := c.WASM.validate(); err != nil {
+			return err
+		}
+	default:
+		for _, u := range append([]string{c.BaseURL}, c.Replicas...) {
+			if err := validateAgentURL(u); err != nil {
+				return fmt.Errorf("base url: %w", err)
+			}
+		}
+	}
+	if _, err := NewSelector(c.Strategy); err != nil {
+		return err
+	}
+	if c.TimeoutSeconds < 0 {
+		return errors.New("timeout_seconds must not be negative")
+	}
+	if c.TimeoutSeconds == 0 {
+		c.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	if c.AsyncTimeoutSeconds < 0 {
+		return errors.New("async_timeout_seconds must not be negative")
+	}
+	if c.MaxRetries < 0 {
+		return errors.New("max_retries must not be negative")
+	}
+	if c.Breaker != nil {
+		if err := c.Breaker.validate(); err != nil {
+			return err
+		}
+	}
+	if c.HealthCheckURL != "" && !strings.HasPrefix(c.HealthCheckURL, "/") {
+		if err := validateURL(c.HealthCheckURL); err != nil {
+			return fmt.Errorf("health check url: %w", err)
+		}
+	}
+	if err := c.Metadata.Validate(); err != nil {
+		return err
+	}
+	return c.validateVersions()
+}
+
+func (c *Config) validateVersions() error {
+	if len(c.Versions) == 0 {
+		if c.Rollout != nil {
+			return errors.New("rollout needs versions")
+		}
+		return nil
+	}
+	if c.Version == "" {
+		return errors.New("version is required with versions")
+	}
+
+	names := []string{c.Version}
+	for _, v := range c.Versions {
+		if v.Version == "" {
+			return errors.New("versions: version is required")
+		}
+		if slices.Contains(names, v.Version) {
+			return fmt.Errorf("versions: duplicate version %q", v.Version)
+		}
+		names = append(names, v.Version)
+		for _, u := range append([]string{v.BaseURL}, v.Replicas...) {
+			if err := validateAgentURL(u); err != nil {
+				return fmt.Errorf("version %s: base url: %w", v.Version, err)
+			}
+		}
+	}
+
+	if c.Rollout == nil {
+		c.Rollout = &Rollout{Stable: c.Version}
+	}
+	if c.Rollout.Stable == "" {
+		c.Rollout.Stable = c.Version
+	}
+	return c.Rollout.Validate(names)
+}
+
+// validateAgentURL accepts the http(s) and grpc(s) URLs agents are
+// served at.
+func validateAgentURL(raw string) error {
+	if isGRPCURL(raw) {
+		_, _, err := grpcTarget(raw)
+		return err
+	}
+	return validateURL(raw)
+}
+
+func validateURL(raw string) error {
+	u, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q is not an http(s) URL", raw)
+	}
+	return nil
+}
+
+// noBreaker never opens, for agents configured without a circuit breaker.
+var noBreaker = CircuitBreakerConfig{
+	FailureThreshold: math.MaxInt,
+	SuccessThreshold: 1,
+	ResetTimeout:     time.Second,
+}
+
+func (c *Config) breaker() CircuitBreakerConfig {
+	switch {
+	case !c.CircuitBreaker:
+		return noBreaker
+	case c.Breaker != nil:
+		return c.Breaker.circuitBreaker()
+	default:
+		return DefaultCircuitBreakerConfig()
+	}
+}
+
+func (c *Config) timeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+func (c *Config) healthURL(baseURL string) string {
+	switch {
+	case c.HealthCheckURL == "":
+		return baseURL + "/health"
+	case strings.HasPrefix(c.HealthCheckURL, "/"):
+		return baseURL + c.HealthCheckURL
+	default:
+		return c.HealthCheckURL
+	}
+}
+
+// NewFromConfig builds the client the config describes: an HTTP agent, or
+// a Pool of them when the config has replicas, or a Versioned agent of
+// those when it has versions, or an ExecAgent, or a WASMAgent of a module
+// file. Agents of uploaded modules are built with WASMAgent instead.
+func NewFromConfig(cfg Config, opts ...VersionedOption) (Client, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Versions) == 0 {
+		return cfg.client(cfg.breaker())
+	}
+
+	opts = append([]VersionedOption{
+		WithVersionBreaker(cfg.breaker()),
+		WithRollout(*cfg.Rollout),
+	}, opts...)
+	versioned := NewVersioned(cfg.Name, opts...)
+
+	base := cfg
+	base.Versions, base.Rollout = nil, nil
+	configs := []Config{base}
+	for _, v := range cfg.Versions {
+		vc := base
+		vc.BaseURL, vc.Replicas = v.BaseURL, v.Replicas
+		vc.Metadata.Version = v.Version
+		configs = append(configs, vc)
+	}
+	for _, vc := range configs {
+		// The versioned agent breaks the circuit of each version itself.
+		client, err := vc.client(noBreaker)
+		if err != nil {
+			return nil, err
+		}
+		versioned.Add(vc.Version, client.(Callable))
+	}
+	return versioned, nil
+}
+
+// client builds an HTTP agent breaking its circuit by single, or a Pool
+// of them when the config has replicas.
+func (c *Config) client(single CircuitBreakerConfig) (Client, error) {
+	if c.Exec != nil {
+		return c.execAgent()
+	}
+	if c.WASM != nil {
+		if c.WASM.Path == "" {
+			return nil, fmt.Errorf("wasm module %s must be loaded by the catalog", c.WASM.Module)
+		}
+		module, err := os.ReadFile(c.WASM.Path)
+		if err != nil {
+			return nil, fmt.Errorf("read wasm module: %w", err)
+		}
+		return c.WASMAgent(module)
+	}
+	if len(c.Replicas) == 0 {
+		return c.agentAt(c.BaseURL, NewCircuitBreaker(&single))
+	}
+
+	selector, err := NewSelector(c.Strategy)
+	if err != nil {
+		return nil, err
+	}
+	pool := NewPool(
+		c.Name,
+		WithSelector(selector),
+		WithEndpointBreaker(c.breaker()),
+	)
+	for _, u := range append([]string{c.BaseURL}, c.Replicas...) {
+		// The pool breaks the circuit of each endpoint itself.
+		breaker := noBreaker
+		client, err := c.agentAt(u, NewCircuitBreaker(&breaker))
+		if err != nil {
+			return nil, err
+		}
+		pool.Add(client.(Callable))
+	}
+	return pool, nil
+}
+
+// agentAt builds the agent at baseURL, over gRPC or HTTP by its scheme.
+func (c *Config) agentAt(baseURL string, breaker *CircuitBreaker) (Client, error) {
+	if isGRPCURL(baseURL) {
+		return NewGRPCAgent(
+			c.Name,
+			baseURL,
+			WithGRPCTimeout(c.timeout()),
+			WithGRPCCircuitBreaker(breaker),
+			WithGRPCHealthCheck(c.HealthCheck),
+			WithGRPCMetadata(c.Metadata),
+		)
+	}
+	return c.httpAgent(baseURL, breaker), nil
+}
+
+func (c *Config) httpAgent(baseURL string, breaker *CircuitBreaker) *HTTPAgentClient {
+	opts := []HTTPAgentOption{
+		WithTimeout(c.timeout()),
+		WithCircuitBreaker(breaker),
+		WithHealthCheckURL(c.healthURL(baseURL)),
+		WithAgentMetadata(c.Metadata),
+		WithAsyncTimeout(time.Duration(c.AsyncTimeoutSeconds) * time.Second),
+	}
+	if !c.HealthCheck {
+		opts = append(opts, WithHealthCheckURL(""))
+	}
+	return NewHTTPAgent(c.Name, baseURL, opts...)
+}
+
+func (c *Config) execAgent() (*ExecAgent, error) {
+	opts := []ExecAgentOption{
+		WithExecTimeout(c.timeout()),
+		WithExecEnv(c.Exec.Env...),
+		WithExecWorkDir(c.Exec.WorkDir),
+		WithExecMetadata(c.Metadata),
+	}
+	if c.Exec.MaxOutputBytes > 0 {
+		opts = append(opts, WithExecMaxOutput(c.Exec.MaxOutputBytes))
+	}
+	return NewExecAgent(c.Name, c.Exec.Command, opts...)
+}
+
+// WASMAgent builds the agent of a wasm config that runs module.
+func (c *Config) WASMAgent(module []byte) (*WASMAgent, error) {
+	opts := []WASMAgentOption{
+		WithWASMTimeout(c.timeout()),
+		WithWASMMetadata(c.Metadata),
+	}
+	if c.WASM.MaxMemoryBytes > 0 {
+		opts = append(opts, WithWASMMemoryLimit(c.WASM.MaxMemoryBytes))
+	}
+	if c.WASM.MaxOutputBytes > 0 {
+		opts = append(opts, WithWASMMaxOutput(c.WASM.MaxOutputBytes))
+	}
+	return NewWASMAgent(c.Name, module, opts...)
+}
@@ -0,0 +1,71 @@
+package agent
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// errClosed fails a call made on a client after Close. The call raced the
+// replacement of the client and can be retried on its successor.
+var errClosed = errors.New("agent client is closed")
+
+// drain counts the calls in progress on a client, so that Close can wait
+// for them before releasing what they use.
+type drain struct {
+	mu     sync.Mutex
+	calls  int
+	closed bool
+	idle   chan struct{}
+}
+
+// enter registers a call; it reports false once the client is closed.
+func (d *drain) enter() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return false
+	}
+	d.calls++
+	return true
+}
+
+func (d *drain) leave() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.calls--
+	if d.closed && d.calls == 0 && d.idle != nil {
+		close(d.idle)
+		d.idle = nil
+	}
+}
+
+// close refuses new calls and waits for those in progress to finish.
+func (d *drain) close() {
+	d.mu.Lock()
+	d.closed = true
+	if d.calls == 0 {
+		d.mu.Unlock()
+		return
+	}
+	if d.idle == nil {
+		d.idle = make(chan struct{})
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	<-idle
+}
+
+// closeAll closes every client that is an io.Closer.
+func closeAll(clients []Callable) error {
+	var errs []error
+	for _, c := range clients {
+		if closer, ok := c.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,161 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/retry"
+)
+
+type ErrorKind string
+
+const (
+	// KindPermanent failures will fail again with the same input, e.g. a
+	// validation error.
+	KindPermanent ErrorKind = "permanent"
+	// KindTransient failures may succeed on a later attempt.
+	KindTransient ErrorKind = "transient"
+	// KindRateLimited failures carry the time the agent asked us to wait.
+	KindRateLimited ErrorKind = "rate_limited"
+	KindTimeout     ErrorKind = "timeout"
+	// KindCircuitOpen means the call never reached the agent.
+	KindCircuitOpen ErrorKind = "circuit_open"
+	// KindUnhealthy means the call was not made because the agent's last
+	// health check failed.
+	KindUnhealthy ErrorKind = "unhealthy"
+	// KindCanceled means the caller gave up on the call, e.g. because its
+	// step was cancelled or its worker is draining. It says nothing about
+	// the agent.
+	KindCanceled ErrorKind = "canceled"
+)
+
+// Error is a failed agent call, classified so the runner can decide how to
+// retry it.
+type Error struct {
+	Kind       ErrorKind
+	Agent      string
+	StatusCode int
+	// RetryAfter is how long the agent or the circuit breaker asked us to
+	// wait before calling again. Zero means no hint.
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("agent %s: %s", e.Agent, e.Kind)
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf(" (status %d)", e.StatusCode)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) RetryClass() retry.Class {
+	switch e.Kind {
+	case KindPermanent:
+		return retry.ClassPermanent
+	case KindTimeout:
+		return retry.ClassTimeout
+	case KindCircuitOpen, KindUnhealthy:
+		return retry.ClassDeferred
+	default:
+		return retry.ClassTransient
+	}
+}
+
+func (e *Error) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+func newError(kind ErrorKind, agent string, err error) *Error {
+	return &Error{Kind: kind, Agent: agent, Err: err}
+}
+
+// IsCanceled reports whether err is an agent call the caller cancelled.
+func IsCanceled(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == KindCanceled
+}
+
+// IsPermanent reports whether err is an agent failure that retrying with
+// the same input cannot fix.
+func IsPermanent(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Kind == KindPermanent
+}
+
+// classifyTransportError classifies a request that got no HTTP response.
+func classifyTransportError(agent string, err error) *Error {
+	if errors.Is(err, context.Canceled) {
+		return newError(KindCanceled, agent, err)
+	}
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout()) {
+		return newError(KindTimeout, agent, err)
+	}
+	return newError(KindTransient, agent, err)
+}
+
+// classifyStatus classifies a non-200 response.
+func classifyStatus(agent string, resp *http.Response) *Error {
+	err := &Error{
+		Agent:      agent,
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Err:        fmt.Errorf("agent returned status %d", resp.StatusCode),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		err.Kind = KindRateLimited
+	case resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusGatewayTimeout:
+		err.Kind = KindTimeout
+	case resp.StatusCode >= 500:
+		err.Kind = KindTransient
+	default:
+		err.Kind = KindPermanent
+	}
+	return err
+}
+
+// StatusError classifies a non-200 response to a request made for agent
+// the way responses of HTTP agents are classified.
+func StatusError(agent string, resp *http.Response) *Error {
+	return classifyStatus(agent, resp)
+}
+
+// TransportError classifies a request made for agent that got no HTTP
+// response.
+func TransportError(agent string, err error) *Error {
+	return classifyTransportError(agent, err)
+}
+
+// parseRetryAfter reads a Retry-After header in either delay-seconds or
+// HTTP-date form.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
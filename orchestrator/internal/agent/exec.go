@@ -0,0 +1,310 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultMaxExecOutput bounds what an exec agent may write to stdout.
+	defaultMaxExecOutput = 16 << 20
+	// maxExecStderr is how much of the end of stderr is kept.
+	maxExecStderr = 8 << 10
+	// execTempFail is the exit code (EX_TEMPFAIL) a command exits with to
+	// ask for a retry.
+	execTempFail = 75
+)
+
+// ExecAgent runs a local command for every call. The input is written to
+// the command's stdin and its stdout, which must be one JSON value, is the
+// output. The command fails the call by exiting non-zero: with exit code
+// 75 (EX_TEMPFAIL) the failure is transient, with any other code it is
+// permanent. A command killed by a signal failed transiently as well.
+//
+// Each call runs in a fresh working directory that is removed afterwards,
+// with only the allowed environment variables set. When the call ends, any
+// process the command left behind in its process group is killed.
+type ExecAgent struct {
+	name      string
+	command   []string
+	env       []string
+	workDir   string
+	timeout   time.Duration
+	maxOutput int64
+	metadata  Metadata
+}
+
+type ExecAgentOption func(*ExecAgent)
+
+// WithExecTimeout bounds each call; the command's process group is killed
+// when it runs out.
+func WithExecTimeout(timeout time.Duration) ExecAgentOption {
+	return func(a *ExecAgent) {
+		a.timeout = timeout
+	}
+}
+
+// WithExecEnv passes the named variables of the orchestrator's environment
+// on to the command. No other variables are.
+func WithExecEnv(names ...string) ExecAgentOption {
+	return func(a *ExecAgent) {
+		a.env = append(a.env, names...)
+	}
+}
+
+// WithExecWorkDir sets the directory the working directory of each call is
+// created in; the default is the system's temporary directory.
+func WithExecWorkDir(dir string) ExecAgentOption {
+	return func(a *ExecAgent) {
+		a.workDir = dir
+	}
+}
+
+// WithExecMaxOutput bounds how many bytes the command may write to stdout.
+// A command that writes more is killed and the call fails permanently.
+func WithExecMaxOutput(n int64) ExecAgentOption {
+	return func(a *ExecAgent) {
+		a.maxOutput = n
+	}
+}
+
+// WithExecMetadata sets what the agent reports through Metadata.
+func WithExecMetadata(m Metadata) ExecAgentOption {
+	return func(a *ExecAgent) {
+		a.metadata = m
+	}
+}
+
+// NewExecAgent creates an agent that runs command, whose first element is
+// the absolute path of the program.
+func NewExecAgent(name string, command []string, opts ...ExecAgentOption) (*ExecAgent, error) {
+	if len(command) == 0 {
+		return nil, errors.New("exec agent needs a command")
+	}
+	if !filepath.IsAbs(command[0]) {
+		return nil, fmt.Errorf("exec command %q must be an absolute path", command[0])
+	}
+
+	a := &ExecAgent{
+		name:      name,
+		command:   command,
+		timeout:   30 * time.Second,
+		maxOutput: defaultMaxExecOutput,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	return a, nil
+}
+
+func (a *ExecAgent) Name() string {
+	return a.name
+}
+
+func (a *ExecAgent) Metadata() Metadata {
+	return a.metadata
+}
+
+// withCallTimeout bounds ctx by timeout. It also returns the bound that
+// applies, which is shorter when ctx has a sooner deadline, such as the
+// timeout of the step.
+func withCallTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc, time.Duration) {
+	bound := timeout
+	if deadline, ok := ctx.Deadline(); ok {
+		bound = min(bound, time.Until(deadline))
+	}
+	if bound > time.Second {
+		bound = bound.Round(time.Second)
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, cancel, bound
+}
+
+func (a *ExecAgent) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	ctx, cancel, timeout := withCallTimeout(ctx, a.timeout)
+	defer cancel()
+	// runCtx also ends when stdout overflows.
+	runCtx, kill := context.WithCancel(ctx)
+	defer kill()
+
+	dir, err := os.MkdirTemp(a.workDir, "agent-"+a.name+"-")
+	if err != nil {
+		return nil, newError(KindTransient, a.name, fmt.Errorf("create work dir: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	if len(input) == 0 {
+		input = json.RawMessage(`{}`)
+	}
+
+	stdout := &limitedBuffer{max: a.maxOutput, exceeded: kill}
+	stderr := &tailBuffer{max: maxExecStderr}
+
+	cmd := exec.CommandContext(runCtx, a.command[0], a.command[1:]...)
+	cmd.Dir = dir
+	cmd.Env = a.environ(ctx, dir)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessGroup(cmd)
+	}
+	// Children that outlive the command may hold its pipes open.
+	cmd.WaitDelay = time.Second
+
+	err = cmd.Run()
+	if cmd.Process != nil {
+		// Leave nothing of the call running.
+		killProcessGroup(cmd)
+	}
+	if info := callInfo(ctx); info != nil {
+		info.Stderr = stderr.String()
+	}
+
+	switch {
+	case stdout.overflow():
+		return nil, newError(KindPermanent, a.name, fmt.Errorf("output exceeds %d bytes", a.maxOutput))
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, newError(KindTimeout, a.name, fmt.Errorf("command timed out after %s", timeout))
+	case ctx.Err() != nil:
+		return nil, newError(KindCanceled, a.name, ctx.Err())
+	case err != nil:
+		return nil, a.classifyExit(err, stderr.String())
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(output) {
+		return nil, newError(KindPermanent, a.name, errors.New("stdout is not a JSON value"))
+	}
+	return output, nil
+}
+
+// classifyExit classifies a command that ran and failed, or did not start.
+func (a *ExecAgent) classifyExit(err error, stderr string) *Error {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		// A program missing on this worker may be present on another.
+		return newError(KindTransient, a.name, fmt.Errorf("run command: %w", err))
+	}
+
+	if line := lastLine(stderr); line != "" {
+		err = fmt.Errorf("%w: %s", err, line)
+	}
+	switch code := exitErr.ExitCode(); {
+	case code == -1, code == execTempFail:
+		// Killed by a signal, or asked to be retried.
+		return newError(KindTransient, a.name, err)
+	default:
+		return newError(KindPermanent, a.name, err)
+	}
+}
+
+// environ returns the environment of a call running in dir.
+func (a *ExecAgent) environ(ctx context.Context, dir string) []string {
+	var env []string
+	for _, name := range a.env {
+		if v, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+v)
+		}
+	}
+	return append(env,
+		"HOME="+dir,
+		"TMPDIR="+dir,
+		"ORCHESTRATOR_AGENT="+a.name,
+		"ORCHESTRATOR_CALL_ID="+CallID(ctx),
+	)
+}
+
+// HealthCheck checks that the program exists and is executable.
+func (a *ExecAgent) HealthCheck(ctx context.Context) error {
+	info, err := os.Stat(a.command[0])
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	if info.IsDir() || info.Mode().Perm()&0o111 == 0 {
+		return fmt.Errorf("health check failed: %s is not executable", a.command[0])
+	}
+	return nil
+}
+
+func lastLine(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		s = s[i+1:]
+	}
+	return s
+}
+
+// limitedBuffer keeps up to max bytes and calls exceeded, once, when more
+// are written. Writes past the limit are discarded rather than failed, so
+// that the command is killed instead of blocking on a full pipe.
+type limitedBuffer struct {
+	mu       sync.Mutex
+	buf      bytes.Buffer
+	max      int64
+	over     bool
+	exceeded func()
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.over {
+		return len(p), nil
+	}
+	if int64(b.buf.Len()+len(p)) > b.max {
+		b.over = true
+		b.exceeded()
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) overflow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.over
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mu  sync.Mutex
+	buf []byte
+	max int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.buf = append(b.buf, p...)
+	if len(b.buf) > b.max {
+		b.buf = append(b.buf[:0], b.buf[len(b.buf)-b.max:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package agent
+
+import "os/exec"
+
+// setProcessGroup does nothing here: only the command itself is killed,
+// not the processes it spawns.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
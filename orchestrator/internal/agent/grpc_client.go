@@ -0,0 +1,284 @@
+package agent
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent/agentpb"
+)
+
+// GRPCAgentClient calls an agent served over gRPC by an
+// agentpb.AgentService. It is identified by a grpc:// (plaintext) or
+// grpcs:// (TLS) URL.
+type GRPCAgentClient struct {
+	name        string
+	baseURL     string
+	conn        *grpc.ClientConn
+	client      agentpb.AgentServiceClient
+	circuit     *CircuitBreaker
+	timeout     time.Duration
+	healthCheck bool
+	metadata    Metadata
+	dialOptions []grpc.DialOption
+	calls       drain
+}
+
+type GRPCAgentOption func(*GRPCAgentClient)
+
+// WithGRPCTimeout bounds each call.
+func WithGRPCTimeout(timeout time.Duration) GRPCAgentOption {
+	return func(c *GRPCAgentClient) {
+		c.timeout = timeout
+	}
+}
+
+func WithGRPCCircuitBreaker(cb *CircuitBreaker) GRPCAgentOption {
+	return func(c *GRPCAgentClient) {
+		c.circuit = cb
+	}
+}
+
+// WithGRPCHealthCheck turns the Health RPC on or off; it is on by default.
+func WithGRPCHealthCheck(enabled bool) GRPCAgentOption {
+	return func(c *GRPCAgentClient) {
+		c.healthCheck = enabled
+	}
+}
+
+// WithGRPCMetadata sets what the agent reports through Metadata.
+func WithGRPCMetadata(m Metadata) GRPCAgentOption {
+	return func(c *GRPCAgentClient) {
+		c.metadata = m
+	}
+}
+
+// WithGRPCDialOptions adds options to the connection, e.g. a custom dialer
+// or client certificates. They override the transport credentials picked
+// from the URL scheme.
+func WithGRPCDialOptions(opts ...grpc.DialOption) GRPCAgentOption {
+	return func(c *GRPCAgentClient) {
+		c.dialOptions = append(c.dialOptions, opts...)
+	}
+}
+
+// NewGRPCAgent creates a client for the agent at baseURL. The connection is
+// made on the first call and re-established as needed.
+func NewGRPCAgent(name string, baseURL string, opts ...GRPCAgentOption) (*GRPCAgentClient, error) {
+	target, creds, err := grpcTarget(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &GRPCAgentClient{
+		name:        name,
+		baseURL:     baseURL,
+		timeout:     30 * time.Second,
+		healthCheck: true,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.circuit == nil {
+		c.circuit = NewCircuitBreaker(nil)
+	}
+
+	dialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(creds)}, c.dialOptions...)
+	c.conn, err = grpc.NewClient(target, dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("agent %s: %w", name, err)
+	}
+	c.client = agentpb.NewAgentServiceClient(c.conn)
+
+	return c, nil
+}
+
+// grpcTarget splits a grpc:// or grpcs:// URL into the dial target and the
+// transport credentials its scheme asks for.
+func grpcTarget(baseURL string) (string, credentials.TransportCredentials, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return "", nil, err
+	}
+	if u.Host == "" {
+		return "", nil, fmt.Errorf("%q has no host", baseURL)
+	}
+
+	switch u.Scheme {
+	case "grpc":
+		return u.Host, insecure.NewCredentials(), nil
+	case "grpcs":
+		return u.Host, credentials.NewTLS(&tls.Config{}), nil
+	default:
+		return "", nil, fmt.Errorf("%q is not a grpc(s) URL", baseURL)
+	}
+}
+
+// isGRPCURL reports whether baseURL names an agent served over gRPC.
+func isGRPCURL(baseURL string) bool {
+	return strings.HasPrefix(baseURL, "grpc://") || strings.HasPrefix(baseURL, "grpcs://")
+}
+
+func (c *GRPCAgentClient) Name() string {
+	return c.name
+}
+
+func (c *GRPCAgentClient) Metadata() Metadata {
+	return c.metadata
+}
+
+// Endpoint returns the base URL, which identifies this replica in a Pool.
+func (c *GRPCAgentClient) Endpoint() string {
+	return c.baseURL
+}
+
+// Close closes the connection once the calls in progress finish. Calls
+// made afterwards fail transiently.
+func (c *GRPCAgentClient) Close() error {
+	c.calls.close()
+	return c.conn.Close()
+}
+
+func (c *GRPCAgentClient) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	if !c.calls.enter() {
+		return nil, newError(KindTransient, c.name, errClosed)
+	}
+	defer c.calls.leave()
+
+	var result json.RawMessage
+
+	err := c.circuit.Execute(func() error {
+		callCtx, cancel := context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+
+		if len(input) == 0 {
+			input = json.RawMessage(`{}`)
+		}
+		req := &agentpb.RunRequest{
+			Agent:    c.name,
+			Input:    input,
+			CallId:   CallID(ctx),
+			Metadata: callMetadata(ctx),
+		}
+		if deadline, ok := callCtx.Deadline(); ok {
+			req.Deadline = timestamppb.New(deadline)
+		}
+		if req.CallId != "" {
+			callCtx = metadata.AppendToOutgoingContext(callCtx, "x-call-id", req.CallId)
+		}
+
+		resp, err := c.client.Run(callCtx, req)
+		if err != nil {
+			return classifyGRPCError(c.name, err)
+		}
+
+		result = resp.GetOutput()
+		return nil
+	})
+
+	if errors.Is(err, ErrCircuitOpen) {
+		return nil, &Error{
+			Kind:       KindCircuitOpen,
+			Agent:      c.name,
+			RetryAfter: c.circuit.RetryAfter(),
+			Err:        err,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// callMetadata collects the context of a call that agents may route or log
+// by.
+func callMetadata(ctx context.Context) map[string]string {
+	md := make(map[string]string)
+	if key := RoutingKey(ctx); key != "" {
+		md["routing_key"] = key
+	}
+	if labels := TaskLabels(ctx); len(labels) > 0 {
+		md["task_labels"] = strings.Join(labels, ",")
+	}
+	return md
+}
+
+// Cancel asks the agent to abort the call with callID. Agents that do not
+// implement Cancel have nothing to cancel.
+func (c *GRPCAgentClient) Cancel(ctx context.Context, callID string) error {
+	_, err := c.client.Cancel(ctx, &agentpb.CancelRequest{
+		Agent:  c.name,
+		CallId: callID,
+	})
+	switch status.Code(err) {
+	case codes.OK, codes.Unimplemented, codes.NotFound:
+		return nil
+	default:
+		return fmt.Errorf("cancel failed: %w", err)
+	}
+}
+
+func (c *GRPCAgentClient) HealthCheck(ctx context.Context) error {
+	if !c.healthCheck {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	resp, err := c.client.Health(ctx, &agentpb.HealthRequest{Agent: c.name})
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	if !resp.GetServing() {
+		return fmt.Errorf("agent is not serving: %s", resp.GetMessage())
+	}
+	return nil
+}
+
+// classifyGRPCError classifies a failed Run by its status code.
+func classifyGRPCError(agent string, err error) *Error {
+	st, ok := status.FromError(err)
+	if !ok {
+		return classifyTransportError(agent, err)
+	}
+
+	agentErr := &Error{Agent: agent, Err: err}
+	switch st.Code() {
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.NotFound,
+		codes.PermissionDenied, codes.Unauthenticated, codes.Unimplemented,
+		codes.OutOfRange:
+		agentErr.Kind = KindPermanent
+	case codes.ResourceExhausted:
+		agentErr.Kind = KindRateLimited
+		for _, d := range st.Details() {
+			if info, ok := d.(*errdetails.RetryInfo); ok {
+				agentErr.RetryAfter = info.GetRetryDelay().AsDuration()
+			}
+		}
+	case codes.DeadlineExceeded:
+		agentErr.Kind = KindTimeout
+	case codes.Canceled:
+		agentErr.Kind = KindCanceled
+	default:
+		agentErr.Kind = KindTransient
+	}
+	return agentErr
+}
@@ -0,0 +1,367 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent/agentpb"
+)
+
+// testAgentServer implements the AgentService contract in process. run
+// decides the outcome of each Run; every request is recorded.
+type testAgentServer struct {
+	agentpb.UnimplementedAgentServiceServer
+
+	run     func(ctx context.Context, req *agentpb.RunRequest) (*agentpb.RunResponse, error)
+	serving atomic.Bool
+
+	mu        sync.Mutex
+	requests  []*agentpb.RunRequest
+	callIDs   []string
+	cancelled []string
+}
+
+func (s *testAgentServer) Run(ctx context.Context, req *agentpb.RunRequest) (*agentpb.RunResponse, error) {
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		s.callIDs = append(s.callIDs, md.Get("x-call-id")...)
+	}
+	s.mu.Unlock()
+
+	if s.run != nil {
+		return s.run(ctx, req)
+	}
+	return &agentpb.RunResponse{Output: req.GetInput()}, nil
+}
+
+func (s *testAgentServer) Cancel(ctx context.Context, req *agentpb.CancelRequest) (*agentpb.CancelResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelled = append(s.cancelled, req.GetCallId())
+	return &agentpb.CancelResponse{}, nil
+}
+
+func (s *testAgentServer) Health(ctx context.Context, req *agentpb.HealthRequest) (*agentpb.HealthResponse, error) {
+	if !s.serving.Load() {
+		return &agentpb.HealthResponse{Message: "warming up"}, nil
+	}
+	return &agentpb.HealthResponse{Serving: true}, nil
+}
+
+// startAgentServer serves srv on a local port and returns its grpc:// URL.
+func startAgentServer(t *testing.T, srv agentpb.AgentServiceServer) string {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := grpc.NewServer()
+	agentpb.RegisterAgentServiceServer(s, srv)
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	return "grpc://" + lis.Addr().String()
+}
+
+func newTestGRPCAgent(t *testing.T, baseURL string, opts ...GRPCAgentOption) *GRPCAgentClient {
+	t.Helper()
+
+	c, err := NewGRPCAgent("grpc-agent", baseURL, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestGRPCAgentCall(t *testing.T) {
+	srv := &testAgentServer{}
+	c := newTestGRPCAgent(t, startAgentServer(t, srv))
+
+	ctx := WithCallID(context.Background(), "step-1")
+	ctx = WithRoutingKey(ctx, "task-1")
+	ctx = WithTaskLabels(ctx, []string{"beta", "eu"})
+
+	out, err := c.Call(ctx, json.RawMessage(`{"text":"hi"}`))
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if string(out) != `{"text":"hi"}` {
+		t.Errorf("output = %s, want the echoed input", out)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	req := srv.requests[0]
+	if req.GetAgent() != "grpc-agent" || req.GetCallId() != "step-1" {
+		t.Errorf("agent, call id = %q, %q", req.GetAgent(), req.GetCallId())
+	}
+	if md := req.GetMetadata(); md["routing_key"] != "task-1" || md["task_labels"] != "beta,eu" {
+		t.Errorf("metadata = %v", md)
+	}
+	if req.GetDeadline() == nil || !req.GetDeadline().AsTime().After(time.Now()) {
+		t.Errorf("deadline = %v, want one in the future", req.GetDeadline())
+	}
+	if len(srv.callIDs) != 1 || srv.callIDs[0] != "step-1" {
+		t.Errorf("x-call-id = %v", srv.callIDs)
+	}
+}
+
+func TestGRPCAgentEmptyInput(t *testing.T) {
+	srv := &testAgentServer{}
+	c := newTestGRPCAgent(t, startAgentServer(t, srv))
+
+	out, err := c.Call(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if string(out) != `{}` {
+		t.Errorf("output = %s, want {}", out)
+	}
+}
+
+func TestGRPCAgentErrorKinds(t *testing.T) {
+	rateLimited, err := status.New(codes.ResourceExhausted, "slow down").
+		WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(7 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		err        error
+		kind       ErrorKind
+		retryAfter time.Duration
+	}{
+		{"invalid argument", status.Error(codes.InvalidArgument, "bad input"), KindPermanent, 0},
+		{"unimplemented", status.Error(codes.Unimplemented, "no"), KindPermanent, 0},
+		{"unavailable", status.Error(codes.Unavailable, "down"), KindTransient, 0},
+		{"internal", status.Error(codes.Internal, "boom"), KindTransient, 0},
+		{"deadline", status.Error(codes.DeadlineExceeded, "slow"), KindTimeout, 0},
+		{"rate limited", rateLimited.Err(), KindRateLimited, 7 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &testAgentServer{
+				run: func(context.Context, *agentpb.RunRequest) (*agentpb.RunResponse, error) {
+					return nil, tt.err
+				},
+			}
+			c := newTestGRPCAgent(t, startAgentServer(t, srv))
+
+			_, err := c.Call(context.Background(), json.RawMessage(`{}`))
+			var agentErr *Error
+			if !errors.As(err, &agentErr) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if agentErr.Kind != tt.kind {
+				t.Errorf("kind = %s, want %s", agentErr.Kind, tt.kind)
+			}
+			if agentErr.RetryAfter != tt.retryAfter {
+				t.Errorf("retry after = %v, want %v", agentErr.RetryAfter, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestGRPCAgentTimeout(t *testing.T) {
+	srv := &testAgentServer{
+		run: func(ctx context.Context, _ *agentpb.RunRequest) (*agentpb.RunResponse, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	c := newTestGRPCAgent(t, startAgentServer(t, srv), WithGRPCTimeout(50*time.Millisecond))
+
+	_, err := c.Call(context.Background(), json.RawMessage(`{}`))
+	var agentErr *Error
+	if !errors.As(err, &agentErr) || agentErr.Kind != KindTimeout {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+}
+
+func TestGRPCAgentCircuitBreaker(t *testing.T) {
+	srv := &testAgentServer{
+		run: func(context.Context, *agentpb.RunRequest) (*agentpb.RunResponse, error) {
+			return nil, status.Error(codes.Unavailable, "down")
+		},
+	}
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 2,
+		SuccessThreshold: 1,
+		ResetTimeout:     time.Minute,
+	})
+	c := newTestGRPCAgent(t, startAgentServer(t, srv), WithGRPCCircuitBreaker(breaker))
+
+	for range 2 {
+		c.Call(context.Background(), json.RawMessage(`{}`))
+	}
+	_, err := c.Call(context.Background(), json.RawMessage(`{}`))
+
+	var agentErr *Error
+	if !errors.As(err, &agentErr) || agentErr.Kind != KindCircuitOpen {
+		t.Fatalf("err = %v, want an open circuit", err)
+	}
+	if agentErr.RetryAfter <= 0 {
+		t.Errorf("retry after = %v, want the reset timeout", agentErr.RetryAfter)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.requests) != 2 {
+		t.Errorf("server got %d calls, want 2", len(srv.requests))
+	}
+}
+
+func TestGRPCAgentPermanentErrorKeepsCircuitClosed(t *testing.T) {
+	srv := &testAgentServer{
+		run: func(context.Context, *agentpb.RunRequest) (*agentpb.RunResponse, error) {
+			return nil, status.Error(codes.InvalidArgument, "bad input")
+		},
+	}
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		ResetTimeout:     time.Minute,
+	})
+	c := newTestGRPCAgent(t, startAgentServer(t, srv), WithGRPCCircuitBreaker(breaker))
+
+	for range 3 {
+		c.Call(context.Background(), json.RawMessage(`{}`))
+	}
+	if breaker.State() != StateClosed {
+		t.Errorf("circuit state = %v, want closed", breaker.State())
+	}
+}
+
+func TestGRPCAgentCancelKeepsCircuitClosed(t *testing.T) {
+	srv := &testAgentServer{
+		run: func(ctx context.Context, _ *agentpb.RunRequest) (*agentpb.RunResponse, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+	breaker := NewCircuitBreaker(&CircuitBreakerConfig{
+		FailureThreshold: 1,
+		SuccessThreshold: 1,
+		ResetTimeout:     time.Minute,
+	})
+	c := newTestGRPCAgent(t, startAgentServer(t, srv), WithGRPCCircuitBreaker(breaker))
+
+	for range 3 {
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(20*time.Millisecond, cancel)
+		_, err := c.Call(ctx, json.RawMessage(`{}`))
+		if !IsCanceled(err) {
+			t.Errorf("err = %v, want a cancelled call", err)
+		}
+	}
+	if breaker.State() != StateClosed {
+		t.Errorf("circuit state = %v, want closed", breaker.State())
+	}
+}
+
+func TestGRPCAgentHealthCheck(t *testing.T) {
+	srv := &testAgentServer{}
+	baseURL := startAgentServer(t, srv)
+	c := newTestGRPCAgent(t, baseURL)
+
+	if err := c.HealthCheck(context.Background()); err == nil {
+		t.Error("health check passed while the agent is not serving")
+	}
+
+	srv.serving.Store(true)
+	if err := c.HealthCheck(context.Background()); err != nil {
+		t.Errorf("health check: %v", err)
+	}
+
+	unchecked := newTestGRPCAgent(t, "grpc://127.0.0.1:1", WithGRPCHealthCheck(false))
+	if err := unchecked.HealthCheck(context.Background()); err != nil {
+		t.Errorf("disabled health check: %v", err)
+	}
+}
+
+func TestGRPCAgentCancel(t *testing.T) {
+	srv := &testAgentServer{}
+	c := newTestGRPCAgent(t, startAgentServer(t, srv))
+
+	if err := c.Cancel(context.Background(), "step-1"); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	srv.mu.Lock()
+	if len(srv.cancelled) != 1 || srv.cancelled[0] != "step-1" {
+		t.Errorf("cancelled = %v", srv.cancelled)
+	}
+	srv.mu.Unlock()
+
+	// Agents that cannot cancel have nothing to cancel.
+	bare := newTestGRPCAgent(t, startAgentServer(t, &agentpb.UnimplementedAgentServiceServer{}))
+	if err := bare.Cancel(context.Background(), "step-1"); err != nil {
+		t.Errorf("Cancel on an agent without it: %v", err)
+	}
+}
+
+func TestGRPCAgentFromConfig(t *testing.T) {
+	srv := &testAgentServer{}
+	srv.serving.Store(true)
+	baseURL := startAgentServer(t, srv)
+
+	client, err := NewFromConfig(Config{
+		Name:        "grpc-agent",
+		BaseURL:     baseURL,
+		HealthCheck: true,
+		Metadata:    Metadata{Version: "2"},
+	})
+	if err != nil {
+		t.Fatalf("NewFromConfig: %v", err)
+	}
+	if _, ok := client.(*GRPCAgentClient); !ok {
+		t.Fatalf("client is %T, want *GRPCAgentClient", client)
+	}
+
+	registry := NewRegistry()
+	registry.Register(client)
+	router := NewRouter(registry)
+
+	ctx, info := WithCallInfo(context.Background())
+	out, err := router.Call(ctx, "grpc-agent", json.RawMessage(`{"n":1}`))
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if string(out) != `{"n":1}` {
+		t.Errorf("output = %s", out)
+	}
+	if info.Version != "2" {
+		t.Errorf("version = %q, want 2", info.Version)
+	}
+}
+
+func TestGRPCAgentConfigValidation(t *testing.T) {
+	for _, u := range []string{"grpc://", "grpcx://host:1", "ftp://host"} {
+		cfg := Config{Name: "a", BaseURL: u}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("Validate accepted base url %q", u)
+		}
+	}
+
+	cfg := Config{Name: "a", BaseURL: "grpcs://agents.internal:443"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate rejected a grpcs url: %v", err)
+	}
+}
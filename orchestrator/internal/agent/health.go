@@ -2,17 +2,21 @@ package agent
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"log"
+	"slices"
 	"sync"
 	"time"
 )
 
 type HealthChecker struct {
-	registry  *Registry
-	statuses  map[string]*HealthStatus
-	mu        sync.RWMutex
-	interval  time.Duration
-	stopCh    chan struct{}
+	registry *Registry
+	statuses map[string]*HealthStatus
+	mu       sync.RWMutex
+	interval time.Duration
+	stopCh   chan struct{}
+
+	subscribers []func(HealthEvent)
 }
 
 type HealthStatus struct {
@@ -23,10 +27,20 @@ type HealthStatus struct {
 	Error     string        `json:"error,omitempty"`
 }
 
-type healthCheckable interface {
-	HealthCheck(ctx context.Context) error
+// HealthEvent reports an agent turning unhealthy or recovering. Agents
+// start out presumed healthy, so the first event of an agent that is up
+// is its first failure.
+type HealthEvent struct {
+	Agent   string    `json:"agent"`
+	Healthy bool      `json:"healthy"`
+	At      time.Time `json:"at"`
+	Error   string    `json:"error,omitempty"`
 }
 
+// staleAfter is how many intervals a status stays trusted without a new
+// check, e.g. after Stop.
+const staleAfter = 3
+
 func NewHealthChecker(registry *Registry, interval time.Duration) *HealthChecker {
 	return &HealthChecker{
 		registry: registry,
@@ -40,7 +54,18 @@ func (hc *HealthChecker) Start() {
 	go hc.run()
 }
 
+// Subscribe registers fn to receive every health transition. fn is called
+// from the checking goroutine and must not block.
+func (hc *HealthChecker) Subscribe(fn func(HealthEvent)) {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+
+	hc.subscribers = append(hc.subscribers, fn)
+}
+
 func (hc *HealthChecker) run() {
+	hc.checkAll(context.Background())
+
 	ticker := time.NewTicker(hc.interval)
 	defer ticker.Stop()
 
@@ -70,6 +95,15 @@ func (hc *HealthChecker) checkAll(ctx context.Context) {
 		}(agentName)
 	}
 	wg.Wait()
+
+	// Forget agents removed from the registry since the last round.
+	hc.mu.Lock()
+	for name := range hc.statuses {
+		if !slices.Contains(agents, name) {
+			delete(hc.statuses, name)
+		}
+	}
+	hc.mu.Unlock()
 }
 
 func (hc *HealthChecker) checkAgent(ctx context.Context, name string) {
@@ -79,7 +113,7 @@ func (hc *HealthChecker) checkAgent(ctx context.Context, name string) {
 		return
 	}
 
-	healthCheckable, ok := client.(healthCheckable)
+	healthCheckable, ok := client.(HealthCheckable)
 	if !ok {
 		hc.updateStatus(name, true, 0, "")
 		return
@@ -97,16 +131,69 @@ func (hc *HealthChecker) checkAgent(ctx context.Context, name string) {
 }
 
 func (hc *HealthChecker) updateStatus(name string, healthy bool, latency time.Duration, error string) {
-	hc.mu.Lock()
-	defer hc.mu.Unlock()
+	now := time.Now()
 
+	hc.mu.Lock()
+	prev, seen := hc.statuses[name]
 	hc.statuses[name] = &HealthStatus{
 		Name:      name,
 		Healthy:   healthy,
-		LastCheck: time.Now(),
+		LastCheck: now,
 		Latency:   latency,
 		Error:     error,
 	}
+	subscribers := hc.subscribers
+	hc.mu.Unlock()
+
+	if (seen && prev.Healthy == healthy) || (!seen && healthy) {
+		return
+	}
+
+	event := HealthEvent{Agent: name, Healthy: healthy, At: now, Error: error}
+	if healthy {
+		log.Printf("agent %s is healthy again", name)
+	} else {
+		log.Printf("agent %s is unhealthy: %s", name, error)
+	}
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
+
+// Check returns the cached health of the agent: whether it is healthy and,
+// if not, how long until it is checked again. Agents that have not been
+// checked yet, or whose status went stale, count as healthy.
+func (hc *HealthChecker) Check(name string) (bool, time.Duration) {
+	hc.mu.RLock()
+	status, ok := hc.statuses[name]
+	hc.mu.RUnlock()
+
+	if !ok || status.Healthy {
+		return true, 0
+	}
+	age := time.Since(status.LastCheck)
+	if age > staleAfter*hc.interval {
+		return true, 0
+	}
+	return false, max(hc.interval-age, 0)
+}
+
+// Unhealthy returns the agents whose cached status is unhealthy.
+func (hc *HealthChecker) Unhealthy() []string {
+	hc.mu.RLock()
+	names := make([]string, 0, len(hc.statuses))
+	for name := range hc.statuses {
+		names = append(names, name)
+	}
+	hc.mu.RUnlock()
+
+	var out []string
+	for _, name := range names {
+		if ok, _ := hc.Check(name); !ok {
+			out = append(out, name)
+		}
+	}
+	return out
 }
 
 func (hc *HealthChecker) GetStatus(name string) (*HealthStatus, bool) {
@@ -135,8 +222,8 @@ func (hc *HealthChecker) GetAllStatusesErrors() map[string]error {
 	result := make(map[string]error)
 	for k, v := range hc.statuses {
 		if !v.Healthy {
-			result[k] = fmt.Errorf(v.Error)
+			result[k] = errors.New(v.Error)
 		}
 	}
 	return result
-}
\ No newline at end of file
+}
@@ -0,0 +1,274 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AgentHost serves the agents of one agent host process: a server that
+// lists its agents at GET / and runs them at POST /agents/{name}. Sync
+// discovers the listed agents and keeps them registered in the registry.
+type AgentHost struct {
+	baseURL  string
+	registry *Registry
+	client   *http.Client
+	circuit  *CircuitBreaker
+	timeout  time.Duration
+
+	mu sync.Mutex
+	// agents are the names this host registered in the registry.
+	agents []string
+}
+
+type AgentHostOption func(*AgentHost)
+
+// WithHostTimeout bounds each agent call made through the host.
+func WithHostTimeout(timeout time.Duration) AgentHostOption {
+	return func(h *AgentHost) {
+		h.timeout = timeout
+	}
+}
+
+// WithHostCircuitBreaker replaces the circuit breaker shared by the agents
+// of the host.
+func WithHostCircuitBreaker(cb *CircuitBreaker) AgentHostOption {
+	return func(h *AgentHost) {
+		h.circuit = cb
+	}
+}
+
+func NewAgentHost(baseURL string, registry *Registry, opts ...AgentHostOption) *AgentHost {
+	h := &AgentHost{
+		baseURL:  strings.TrimSuffix(baseURL, "/"),
+		registry: registry,
+		client:   &http.Client{},
+		timeout:  30 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	if h.circuit == nil {
+		h.circuit = NewCircuitBreaker(nil)
+	}
+
+	return h
+}
+
+// hostListing is the response to GET / on an agent host.
+type hostListing struct {
+	Status string   `json:"status"`
+	Agents []string `json:"agents"`
+}
+
+func (h *AgentHost) list(ctx context.Context) (hostListing, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.baseURL+"/", nil)
+	if err != nil {
+		return hostListing{}, fmt.Errorf("create listing request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return hostListing{}, fmt.Errorf("list agents: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return hostListing{}, fmt.Errorf("list agents: status %d", resp.StatusCode)
+	}
+
+	var listing hostListing
+	if err := json.NewDecoder(resp.Body).Decode(&listing); err != nil {
+		return hostListing{}, fmt.Errorf("decode agent listing: %w", err)
+	}
+	return listing, nil
+}
+
+// Sync registers agents the host lists and drops agents it stopped
+// listing. Agents served by several hosts are pooled by the registry.
+func (h *AgentHost) Sync(ctx context.Context) error {
+	listing, err := h.list(ctx)
+	if err != nil {
+		return fmt.Errorf("agent host %s: %w", h.baseURL, err)
+	}
+
+	// A host cannot stand in for the built-in agents.
+	listing.Agents = slices.DeleteFunc(listing.Agents, func(name string) bool {
+		return strings.HasPrefix(name, BuiltinPrefix)
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, name := range listing.Agents {
+		if slices.Contains(h.agents, name) {
+			continue
+		}
+		h.registry.Register(&HostAgentClient{name: name, host: h})
+		log.Printf("agent host %s: registered agent %s", h.baseURL, name)
+	}
+
+	for _, name := range h.agents {
+		if slices.Contains(listing.Agents, name) {
+			continue
+		}
+		h.registry.RemoveEndpoint(name, h.endpoint(name))
+		log.Printf("agent host %s: agent %s is gone", h.baseURL, name)
+	}
+
+	h.agents = slices.Clone(listing.Agents)
+	return nil
+}
+
+// Run syncs the host every interval until ctx is done.
+func (h *AgentHost) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.Sync(ctx); err != nil {
+				log.Printf("%v", err)
+			}
+		}
+	}
+}
+
+func (h *AgentHost) endpoint(name string) string {
+	return h.baseURL + "/agents/" + url.PathEscape(name)
+}
+
+// HostAgentClient calls one agent of an AgentHost.
+type HostAgentClient struct {
+	name string
+	host *AgentHost
+}
+
+func (c *HostAgentClient) Name() string {
+	return c.name
+}
+
+// Endpoint returns the URL the agent is called at, which identifies it in
+// a Pool.
+func (c *HostAgentClient) Endpoint() string {
+	return c.host.endpoint(c.name)
+}
+
+func (c *HostAgentClient) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	var result json.RawMessage
+
+	err := c.host.circuit.Execute(func() error {
+		callCtx, cancel := context.WithTimeout(ctx, c.host.timeout)
+		defer cancel()
+
+		if len(input) == 0 {
+			input = json.RawMessage(`{}`)
+		}
+		body, err := json.Marshal(map[string]any{
+			"input": input,
+		})
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+
+		req, err := http.NewRequestWithContext(
+			callCtx,
+			http.MethodPost,
+			c.Endpoint(),
+			bytes.NewReader(body),
+		)
+		if err != nil {
+			return fmt.Errorf("create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if id := CallID(ctx); id != "" {
+			req.Header.Set("X-Call-ID", id)
+		}
+
+		resp, err := c.host.client.Do(req)
+		if err != nil {
+			return classifyTransportError(c.name, fmt.Errorf("execute request: %w", err))
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			agentErr := classifyStatus(c.name, resp)
+			if detail := hostErrorDetail(resp.Body); detail != "" {
+				agentErr.Err = fmt.Errorf("%w: %s", agentErr.Err, detail)
+			}
+			return agentErr
+		}
+
+		var out struct {
+			Output json.RawMessage `json:"output"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			return newError(KindTransient, c.name, fmt.Errorf("decode response: %w", err))
+		}
+
+		result = out.Output
+		return nil
+	})
+
+	if errors.Is(err, ErrCircuitOpen) {
+		return nil, &Error{
+			Kind:       KindCircuitOpen,
+			Agent:      c.name,
+			RetryAfter: c.host.circuit.RetryAfter(),
+			Err:        err,
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// HealthCheck passes when the host is up and still lists the agent.
+func (c *HostAgentClient) HealthCheck(ctx context.Context) error {
+	listing, err := c.host.list(ctx)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(listing.Agents, c.name) {
+		return fmt.Errorf("agent host %s no longer lists %s", c.host.baseURL, c.name)
+	}
+	return nil
+}
+
+// hostErrorDetail reads the {"detail": ...} body an agent host sends with
+// an error status. The detail of a validation error is a list, which is
+// returned as JSON.
+func hostErrorDetail(body io.Reader) string {
+	var out struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	if err := json.NewDecoder(io.LimitReader(body, 64<<10)).Decode(&out); err != nil {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(out.Detail, &s); err == nil {
+		return s
+	}
+	return string(out.Detail)
+}
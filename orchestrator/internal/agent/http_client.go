@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	//"errors"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -18,6 +18,8 @@ type HTTPAgentClient struct {
 	healthURL    string
 	timeout      time.Duration
 	lastHealthOK time.Time
+	metadata     Metadata
+	asyncTimeout time.Duration
 }
 
 func NewHTTPAgent(name string, baseURL string, opts ...HTTPAgentOption) *HTTPAgentClient {
@@ -62,16 +64,42 @@ func WithCircuitBreaker(cb *CircuitBreaker) HTTPAgentOption {
 	}
 }
 
+// WithHealthCheckURL sets the URL probed by HealthCheck. An empty URL
+// turns health checks off.
 func WithHealthCheckURL(url string) HTTPAgentOption {
 	return func(h *HTTPAgentClient) {
 		h.healthURL = url
 	}
 }
 
+// WithAgentMetadata sets what the agent reports through Metadata.
+func WithAgentMetadata(m Metadata) HTTPAgentOption {
+	return func(h *HTTPAgentClient) {
+		h.metadata = m
+	}
+}
+
+// WithAsyncTimeout bounds how long a job the agent accepts may take before
+// the step fails. Zero leaves it to the runner.
+func WithAsyncTimeout(timeout time.Duration) HTTPAgentOption {
+	return func(h *HTTPAgentClient) {
+		h.asyncTimeout = timeout
+	}
+}
+
 func (c *HTTPAgentClient) Name() string {
 	return c.name
 }
 
+func (c *HTTPAgentClient) Metadata() Metadata {
+	return c.metadata
+}
+
+// Endpoint returns the base URL, which identifies this replica in a Pool.
+func (c *HTTPAgentClient) Endpoint() string {
+	return c.baseURL
+}
+
 func (c *HTTPAgentClient) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
 	var result json.RawMessage
 	//var callErr error
@@ -80,12 +108,18 @@ func (c *HTTPAgentClient) Call(ctx context.Context, input json.RawMessage) (json
 		callCtx, cancel := context.WithTimeout(ctx, c.timeout)
 		defer cancel()
 
+		meta := map[string]any{
+			"agent":   c.name,
+			"call_id": CallID(ctx),
+			"time":    time.Now().UTC(),
+		}
+		callbackURL := CallbackURL(ctx)
+		if callbackURL != "" {
+			meta["callback_url"] = callbackURL
+		}
 		body, err := json.Marshal(map[string]any{
-			"input": input,
-			"metadata": map[string]any{
-				"agent": c.name,
-				"time":  time.Now().UTC(),
-			},
+			"input":    input,
+			"metadata": meta,
 		})
 		if err != nil {
 			return fmt.Errorf("marshal request: %w", err)
@@ -101,16 +135,36 @@ func (c *HTTPAgentClient) Call(ctx context.Context, input json.RawMessage) (json
 			return fmt.Errorf("create request: %w", err)
 		}
 		req.Header.Set("Content-Type", "application/json")
+		// Agents may stream progress instead of answering with one body.
+		req.Header.Set("Accept", "application/json, "+contentTypeNDJSON+", "+contentTypeSSE)
 		req.Header.Set("X-Agent-Name", c.name)
+		if id := CallID(ctx); id != "" {
+			req.Header.Set("X-Call-ID", id)
+		}
+		if callbackURL != "" {
+			req.Header.Set("X-Callback-URL", callbackURL)
+		}
 
 		resp, err := c.client.Do(req)
 		if err != nil {
-			return fmt.Errorf("execute request: %w", err)
+			return classifyTransportError(c.name, fmt.Errorf("execute request: %w", err))
 		}
 		defer resp.Body.Close()
 
+		if resp.StatusCode == http.StatusAccepted {
+			return readJob(c.name, c.baseURL, c.asyncTimeout, resp)
+		}
 		if resp.StatusCode != http.StatusOK {
-			return fmt.Errorf("agent returned status %d", resp.StatusCode)
+			return classifyStatus(c.name, resp)
+		}
+
+		if isStream(resp) {
+			output, err := readStream(ctx, c.name, resp)
+			if err != nil {
+				return err
+			}
+			result = output
+			return nil
 		}
 
 		var out struct {
@@ -120,17 +174,29 @@ func (c *HTTPAgentClient) Call(ctx context.Context, input json.RawMessage) (json
 		}
 
 		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-			return fmt.Errorf("decode response: %w", err)
+			return newError(KindTransient, c.name, fmt.Errorf("decode response: %w", err))
 		}
 
 		if out.Error != "" {
-			return fmt.Errorf("agent error: %s", out.Error)
+			kind := KindPermanent
+			if out.Retry {
+				kind = KindTransient
+			}
+			return newError(kind, c.name, fmt.Errorf("agent error: %s", out.Error))
 		}
 
 		result = out.Output
 		return nil
 	})
 
+	if errors.Is(err, ErrCircuitOpen) {
+		return nil, &Error{
+			Kind:       KindCircuitOpen,
+			Agent:      c.name,
+			RetryAfter: c.circuit.RetryAfter(),
+			Err:        err,
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -138,7 +204,51 @@ func (c *HTTPAgentClient) Call(ctx context.Context, input json.RawMessage) (json
 	return result, nil
 }
 
+// Cancel asks the agent to abort the call with callID by posting it to
+// /cancel. Agents without that endpoint answer 404 or 405, which is not an
+// error: there is simply nothing to cancel.
+func (c *HTTPAgentClient) Cancel(ctx context.Context, callID string) error {
+	body, err := json.Marshal(map[string]string{
+		"call_id": callID,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		c.baseURL+"/cancel",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("create cancel request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Agent-Name", c.name)
+	req.Header.Set("X-Call-ID", callID)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cancel request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300,
+		resp.StatusCode == http.StatusNotFound,
+		resp.StatusCode == http.StatusMethodNotAllowed:
+		return nil
+	default:
+		return fmt.Errorf("cancel returned status %d", resp.StatusCode)
+	}
+}
+
 func (c *HTTPAgentClient) HealthCheck(ctx context.Context) error {
+	if c.healthURL == "" {
+		return nil
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
@@ -4,41 +4,38 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
-	"time"
 )
 
 var (
-	ErrNotCallable = errors.New("agent not call")
-	ErrCircuitOpen = errors.New("breaker not found")
-	ErrAgentNotFound = errors.New("agent is not health")
+	ErrNotCallable   = errors.New("agent is not callable")
+	ErrCircuitOpen   = errors.New("circuit breaker is open")
+	ErrAgentNotFound = errors.New("agent not found")
 )
 
 type Client interface {
-	Name() string 
+	Name() string
 }
 type Callable interface {
 	Call(
-		ctx context.Context, 
-		input json.RawMessage, 
+		ctx context.Context,
+		input json.RawMessage,
 	) (json.RawMessage, error)
 }
 
+// Cancelable is implemented by agents that can abort a call in progress.
+// Cancel is best effort: the call may already have finished.
+type Cancelable interface {
+	Cancel(ctx context.Context, callID string) error
+}
+
+// HealthCheckable is implemented by agents that can report their health.
+// HealthChecker probes them in the background.
 type HealthCheckable interface {
-	HealthChecker(ctx context.Context) error 
+	HealthCheck(ctx context.Context) error
 }
 
 type Agent interface {
-	Client 
+	Client
 	Callable
 	HealthCheckable
 }
-
-type Config struct {
-	Name string 
-	BaseURL string 
-	Timeout time.Duration 
-	MaxRetries int 
-	CircuitBreaker bool 
-	HealthCheck bool 
-	HealthCheckURL string 
-}
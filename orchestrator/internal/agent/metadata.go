@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// ErrSchemaViolation is wrapped by the permanent errors of calls whose
+// input or output does not match the agent's schema.
+var ErrSchemaViolation = errors.New("schema violation")
+
+// Metadata describes what an agent does, for planners and for validating
+// the data it exchanges.
+type Metadata struct {
+	Description string `json:"description,omitempty"`
+	// InputSchema and OutputSchema are JSON Schemas. Calls are only made
+	// with matching input, and only succeed with matching output.
+	InputSchema  json.RawMessage `json:"input_schema,omitempty"`
+	OutputSchema json.RawMessage `json:"output_schema,omitempty"`
+	Tags         []string        `json:"tags,omitempty"`
+	Cost         *Cost           `json:"cost,omitempty"`
+	Version      string          `json:"version,omitempty"`
+}
+
+// Cost hints at how expensive a call is, so that planners can prefer the
+// cheaper of two agents that would do.
+type Cost struct {
+	// PerCall is the price of one call in whatever unit the deployment
+	// bills in.
+	PerCall   float64 `json:"per_call,omitempty"`
+	LatencyMS int     `json:"latency_ms,omitempty"`
+}
+
+// Validate checks that the schemas compile.
+func (m *Metadata) Validate() error {
+	for _, s := range []struct {
+		name   string
+		schema json.RawMessage
+	}{
+		{"input_schema", m.InputSchema},
+		{"output_schema", m.OutputSchema},
+	} {
+		if len(s.schema) == 0 {
+			continue
+		}
+		if _, err := compileSchema(s.schema); err != nil {
+			return fmt.Errorf("%s: %w", s.name, err)
+		}
+	}
+	if m.Cost != nil && (m.Cost.PerCall < 0 || m.Cost.LatencyMS < 0) {
+		return errors.New("cost must not be negative")
+	}
+	return nil
+}
+
+// Describer is implemented by agents that carry Metadata.
+type Describer interface {
+	Metadata() Metadata
+}
+
+// Description is an agent's name with its metadata.
+type Description struct {
+	Name string `json:"name"`
+	Metadata
+}
+
+// Describe returns the description of client, with empty metadata when it
+// has none.
+func Describe(client Client) Description {
+	d := Description{Name: client.Name()}
+	if describer, ok := client.(Describer); ok {
+		d.Metadata = describer.Metadata()
+	}
+	return d
+}
+
+// Describe returns the description of every registered agent, by name.
+func (r *Registry) Describe() []Description {
+	r.mu.RLock()
+	out := make([]Description, 0, len(r.clients))
+	for _, c := range r.clients {
+		out = append(out, Describe(c))
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// schemas caches compiled schemas by their source, which is shared by every
+// call to an agent.
+var schemas = struct {
+	sync.RWMutex
+	compiled map[string]*jsonschema.Schema
+}{compiled: make(map[string]*jsonschema.Schema)}
+
+// schemaURL names every schema to the compiler; schemas are compiled one
+// at a time and cannot refer to each other.
+const schemaURL = "urn:orchestrator:agent-schema"
+
+func compileSchema(schema json.RawMessage) (*jsonschema.Schema, error) {
+	key := string(schema)
+
+	schemas.RLock()
+	compiled, ok := schemas.compiled[key]
+	schemas.RUnlock()
+	if ok {
+		return compiled, nil
+	}
+
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, err
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(schemaURL, doc); err != nil {
+		return nil, err
+	}
+	compiled, err = c.Compile(schemaURL)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas.Lock()
+	schemas.compiled[key] = compiled
+	schemas.Unlock()
+	return compiled, nil
+}
+
+// validateSchema checks data against schema. An empty schema accepts
+// anything.
+func validateSchema(schema, data json.RawMessage) error {
+	if len(schema) == 0 {
+		return nil
+	}
+
+	compiled, err := compileSchema(schema)
+	if err != nil {
+		return fmt.Errorf("compile schema: %w", err)
+	}
+
+	if len(data) == 0 {
+		data = json.RawMessage(`null`)
+	}
+	v, err := jsonschema.UnmarshalJSON(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	return compiled.Validate(v)
+}
@@ -0,0 +1,353 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// latencyDecay is the weight of the newest sample in an endpoint's moving
+// average latency.
+const latencyDecay = 0.3
+
+// Endpoint is one replica of a pooled agent, with its own circuit breaker.
+type Endpoint struct {
+	id      string
+	client  Callable
+	circuit *CircuitBreaker
+
+	inFlight atomic.Int64
+
+	mu        sync.RWMutex
+	latency   time.Duration
+	unhealthy bool
+	lastError string
+}
+
+// ID identifies the endpoint within its pool: the base URL for HTTP agents.
+func (ep *Endpoint) ID() string {
+	return ep.id
+}
+
+// InFlight returns how many calls the endpoint is serving.
+func (ep *Endpoint) InFlight() int64 {
+	return ep.inFlight.Load()
+}
+
+// Latency returns the moving average latency of the endpoint's calls, or
+// zero before its first call.
+func (ep *Endpoint) Latency() time.Duration {
+	ep.mu.RLock()
+	defer ep.mu.RUnlock()
+	return ep.latency
+}
+
+// Available reports whether the endpoint takes calls: it passed its last
+// health check and its circuit is not open.
+func (ep *Endpoint) Available() bool {
+	ep.mu.RLock()
+	unhealthy := ep.unhealthy
+	ep.mu.RUnlock()
+
+	return !unhealthy && ep.circuit.Ready()
+}
+
+func (ep *Endpoint) observe(d time.Duration) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	if ep.latency == 0 {
+		ep.latency = d
+		return
+	}
+	ep.latency = time.Duration(latencyDecay*float64(d) + (1-latencyDecay)*float64(ep.latency))
+}
+
+func (ep *Endpoint) setHealth(err error) {
+	ep.mu.Lock()
+	defer ep.mu.Unlock()
+
+	ep.unhealthy = err != nil
+	ep.lastError = ""
+	if err != nil {
+		ep.lastError = err.Error()
+	}
+}
+
+// EndpointStatus is a snapshot of an Endpoint.
+type EndpointStatus struct {
+	ID        string        `json:"id"`
+	Available bool          `json:"available"`
+	Healthy   bool          `json:"healthy"`
+	Circuit   CircuitState  `json:"circuit"`
+	InFlight  int64         `json:"in_flight"`
+	Latency   time.Duration `json:"latency"`
+	Error     string        `json:"error,omitempty"`
+}
+
+// Pool serves one agent name from several replicas. Each call goes to an
+// available endpoint chosen by the pool's Selector; an endpoint is ejected
+// while its circuit is open or after it fails a health check, and comes
+// back once it recovers.
+type Pool struct {
+	name     string
+	selector Selector
+	breaker  CircuitBreakerConfig
+
+	mu        sync.RWMutex
+	endpoints []*Endpoint
+	// added numbers endpoints without an id of their own; it never
+	// decreases, so that no number is given out twice.
+	added int
+}
+
+type PoolOption func(*Pool)
+
+// WithSelector sets how the pool picks an endpoint. The default is
+// RoundRobin.
+func WithSelector(s Selector) PoolOption {
+	return func(p *Pool) {
+		p.selector = s
+	}
+}
+
+// WithEndpointBreaker configures the circuit breaker of each endpoint.
+func WithEndpointBreaker(config CircuitBreakerConfig) PoolOption {
+	return func(p *Pool) {
+		p.breaker = config
+	}
+}
+
+func NewPool(name string, opts ...PoolOption) *Pool {
+	p := &Pool{
+		name:     name,
+		selector: RoundRobin(),
+		breaker: CircuitBreakerConfig{
+			FailureThreshold: 5,
+			SuccessThreshold: 3,
+			HalfOpenTimeout:  10 * time.Second,
+			ResetTimeout:     30 * time.Second,
+		},
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// Metadata returns the metadata of the first endpoint that has any.
+// Replicas of one agent are expected to agree.
+func (p *Pool) Metadata() Metadata {
+	for _, ep := range p.Endpoints() {
+		if d, ok := ep.client.(Describer); ok {
+			return d.Metadata()
+		}
+	}
+	return Metadata{}
+}
+
+// Add adds client as an endpoint. Clients that have an Endpoint method are
+// identified by it, and adding the same one twice replaces it; others are
+// numbered in the order they were added.
+func (p *Pool) Add(client Callable) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var id string
+	if e, ok := client.(interface{ Endpoint() string }); ok {
+		id = e.Endpoint()
+	} else {
+		id = fmt.Sprintf("%s-%d", p.name, p.added)
+		p.added++
+	}
+
+	breaker := p.breaker
+	ep := &Endpoint{
+		id:      id,
+		client:  client,
+		circuit: NewCircuitBreaker(&breaker),
+	}
+
+	for i, existing := range p.endpoints {
+		if existing.id == id {
+			p.endpoints[i] = ep
+			return
+		}
+	}
+	p.endpoints = append(p.endpoints, ep)
+}
+
+// Remove drops the endpoint with id and reports whether it was there.
+// Calls already in progress on it finish normally.
+func (p *Pool) Remove(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, ep := range p.endpoints {
+		if ep.id == id {
+			p.endpoints = append(p.endpoints[:i:i], p.endpoints[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes the clients of the endpoints that hold resources, such as
+// gRPC connections.
+func (p *Pool) Close() error {
+	var clients []Callable
+	for _, ep := range p.Endpoints() {
+		clients = append(clients, ep.client)
+	}
+	return closeAll(clients)
+}
+
+// Endpoints returns the endpoints of the pool, available or not.
+func (p *Pool) Endpoints() []*Endpoint {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return append([]*Endpoint(nil), p.endpoints...)
+}
+
+func (p *Pool) available() []*Endpoint {
+	var out []*Endpoint
+	for _, ep := range p.Endpoints() {
+		if ep.Available() {
+			out = append(out, ep)
+		}
+	}
+	return out
+}
+
+func (p *Pool) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	candidates := p.available()
+	if len(candidates) == 0 {
+		return nil, &Error{
+			Kind:       KindCircuitOpen,
+			Agent:      p.name,
+			RetryAfter: p.retryAfter(),
+			Err:        fmt.Errorf("no available endpoint: %w", ErrCircuitOpen),
+		}
+	}
+
+	ep := p.selector.Select(ctx, candidates)
+
+	ep.inFlight.Add(1)
+	defer ep.inFlight.Add(-1)
+
+	var output json.RawMessage
+	start := time.Now()
+	err := ep.circuit.Execute(func() error {
+		var err error
+		output, err = ep.client.Call(ctx, input)
+		return err
+	})
+	if err == ErrCircuitOpen {
+		// Another call opened the endpoint's circuit after we picked it.
+		return nil, &Error{
+			Kind:       KindCircuitOpen,
+			Agent:      p.name,
+			RetryAfter: ep.circuit.RetryAfter(),
+			Err:        fmt.Errorf("endpoint %s: %w", ep.id, err),
+		}
+	}
+	switch elapsed := time.Since(start); {
+	case err == nil || IsPermanent(err):
+		ep.observe(elapsed)
+	case !IsCanceled(err):
+		// A failing endpoint must not look fast for failing fast.
+		ep.observe(max(elapsed, meanLatency(p.Endpoints())))
+	}
+	return output, err
+}
+
+// retryAfter returns how long until the first open circuit lets a call
+// through, or zero when endpoints are out for failing health checks.
+func (p *Pool) retryAfter() time.Duration {
+	var wait time.Duration
+	for _, ep := range p.Endpoints() {
+		d := ep.circuit.RetryAfter()
+		if d > 0 && (wait == 0 || d < wait) {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// Cancel forwards the cancellation to every endpoint that supports it.
+// The call has usually returned by the time it is cancelled, so the pool
+// no longer knows which endpoint served it; the others have nothing to
+// cancel and ignore it.
+func (p *Pool) Cancel(ctx context.Context, callID string) error {
+	var errs []error
+	for _, ep := range p.Endpoints() {
+		cancelable, ok := ep.client.(Cancelable)
+		if !ok {
+			continue
+		}
+		if err := cancelable.Cancel(ctx, callID); err != nil {
+			errs = append(errs, fmt.Errorf("endpoint %s: %w", ep.id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HealthCheck checks every endpoint that supports it, ejecting those that
+// fail and restoring those that pass. It fails only when no endpoint is
+// left available.
+func (p *Pool) HealthCheck(ctx context.Context) error {
+	endpoints := p.Endpoints()
+	if len(endpoints) == 0 {
+		return fmt.Errorf("pool %s has no endpoints", p.name)
+	}
+
+	var wg sync.WaitGroup
+	for _, ep := range endpoints {
+		checkable, ok := ep.client.(HealthCheckable)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ep.setHealth(checkable.HealthCheck(ctx))
+		}()
+	}
+	wg.Wait()
+
+	if len(p.available()) == 0 {
+		return fmt.Errorf("pool %s: all %d endpoints ejected", p.name, len(endpoints))
+	}
+	return nil
+}
+
+// Status returns a snapshot of every endpoint.
+func (p *Pool) Status() []EndpointStatus {
+	endpoints := p.Endpoints()
+	out := make([]EndpointStatus, 0, len(endpoints))
+	for _, ep := range endpoints {
+		ep.mu.RLock()
+		status := EndpointStatus{
+			ID:       ep.id,
+			Healthy:  !ep.unhealthy,
+			Circuit:  ep.circuit.State(),
+			InFlight: ep.InFlight(),
+			Latency:  ep.latency,
+			Error:    ep.lastError,
+		}
+		ep.mu.RUnlock()
+		status.Available = ep.Available()
+		out = append(out, status)
+	}
+	return out
+}
@@ -5,22 +5,77 @@ import (
 	"sync"
 )
 
+// BuiltinPrefix starts the names of the agents built into the orchestrator
+// (see package builtin). No other agent may take such a name.
+const BuiltinPrefix = "builtin."
+
 type Registry struct {
 	mu      sync.RWMutex
 	clients map[string]Client
+	// newSelector picks the strategy of pools the registry forms itself.
+	newSelector func() Selector
 }
 
-func NewRegistry() *Registry {
-	return &Registry{
-		clients: make(map[string]Client),
+type RegistryOption func(*Registry)
+
+// WithPoolSelector sets the strategy of pools formed by registering
+// several clients under one name. The default is RoundRobin.
+func WithPoolSelector(newSelector func() Selector) RegistryOption {
+	return func(r *Registry) {
+		r.newSelector = newSelector
 	}
 }
 
+func NewRegistry(opts ...RegistryOption) *Registry {
+	r := &Registry{
+		clients:     make(map[string]Client),
+		newSelector: RoundRobin,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Register makes client serve its name. Registering a second callable
+// client under a name that is taken adds it as a replica: the name then
+// resolves to a Pool of both. Registering a *Pool replaces whatever served
+// the name, as does registering under a name held by a non-callable client.
 func (r *Registry) Register(client Client) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	name := client.Name()
+	callable, isCallable := client.(Callable)
+	if _, isPool := client.(*Pool); isPool || !isCallable {
+		r.clients[name] = client
+		return
+	}
+
+	switch existing := r.clients[name].(type) {
+	case *Pool:
+		existing.Add(callable)
+	case Callable:
+		pool := NewPool(name, WithSelector(r.newSelector()))
+		pool.Add(existing)
+		pool.Add(callable)
+		r.clients[name] = pool
+	default:
+		r.clients[name] = client
+	}
+}
+
+// Replace makes client the only client serving its name, dropping any
+// pool formed for it. It returns the client it replaced, if any, which the
+// caller should close once it is done with it.
+func (r *Registry) Replace(client Client) Client {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old := r.clients[client.Name()]
 	r.clients[client.Name()] = client
+	return old
 }
 
 func (r *Registry) Get(name string) (Client, error) {
@@ -29,7 +84,7 @@ func (r *Registry) Get(name string) (Client, error) {
 
 	c, ok := r.clients[name]
 	if !ok {
-		return nil, fmt.Errorf("agent %s not registered", name)
+		return nil, fmt.Errorf("agent %s not registered: %w", name, ErrAgentNotFound)
 	}
 	return c, nil
 }
@@ -45,11 +100,35 @@ func (r *Registry) List() []string {
 	return names
 }
 
-func (r *Registry) Remove(name string) {
+// Remove drops the agent and returns its client, if any, which the caller
+// should close once it is done with it.
+func (r *Registry) Remove(name string) Client {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
+	old := r.clients[name]
 	delete(r.clients, name)
+	return old
+}
+
+// RemoveEndpoint removes one replica of the agent: the pool endpoint with
+// the given ID, or the agent itself when it is that endpoint. The name is
+// dropped once no endpoint is left.
+func (r *Registry) RemoveEndpoint(name, id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch c := r.clients[name].(type) {
+	case *Pool:
+		c.Remove(id)
+		if len(c.Endpoints()) == 0 {
+			delete(r.clients, name)
+		}
+	case interface{ Endpoint() string }:
+		if c.Endpoint() == id {
+			delete(r.clients, name)
+		}
+	}
 }
 
 func (r *Registry) Has(name string) bool {
@@ -58,4 +137,32 @@ func (r *Registry) Has(name string) bool {
 
 	_, exists := r.clients[name]
 	return exists
-}
\ No newline at end of file
+}
+
+// Pools returns the endpoint status of every pooled agent by name.
+func (r *Registry) Pools() map[string][]EndpointStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string][]EndpointStatus)
+	for name, c := range r.clients {
+		if pool, ok := c.(*Pool); ok {
+			out[name] = pool.Status()
+		}
+	}
+	return out
+}
+
+// Versions returns the version status of every versioned agent by name.
+func (r *Registry) Versions() map[string][]VersionStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[string][]VersionStatus)
+	for name, c := range r.clients {
+		if versioned, ok := c.(*Versioned); ok {
+			out[name] = versioned.Status()
+		}
+	}
+	return out
+}
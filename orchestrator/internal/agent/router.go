@@ -3,17 +3,34 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 )
 
 type Router struct {
 	registry *Registry
+	health   *HealthChecker
 }
 
-func NewRouter(registry *Registry) *Router {
-	return &Router{
+type RouterOption func(*Router)
+
+// WithHealthChecker makes the router fail fast on agents whose cached
+// status in hc is unhealthy, instead of waiting for the call to time out.
+func WithHealthChecker(hc *HealthChecker) RouterOption {
+	return func(r *Router) {
+		r.health = hc
+	}
+}
+
+func NewRouter(registry *Registry, opts ...RouterOption) *Router {
+	r := &Router{
 		registry: registry,
 	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 func (r *Router) Call(
@@ -28,32 +45,114 @@ func (r *Router) Call(
 
 	callable, ok := client.(Callable)
 	if !ok {
-		return nil, ErrNotCallable
+		return nil, newError(KindPermanent, agent, ErrNotCallable)
+	}
+
+	if err := r.checkHealth(agent); err != nil {
+		return nil, err
+	}
+
+	metadata := Describe(client).Metadata
+	if err := validateSchema(metadata.InputSchema, input); err != nil {
+		return nil, newError(KindPermanent, agent, fmt.Errorf("%w: input: %v", ErrSchemaViolation, err))
+	}
+
+	if info := callInfo(ctx); info != nil {
+		// A Versioned agent overrides this with the version it picks.
+		info.Version = metadata.Version
+	}
+
+	output, err := callable.Call(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateSchema(metadata.OutputSchema, output); err != nil {
+		return nil, newError(KindPermanent, agent, fmt.Errorf("%w: output: %v", ErrSchemaViolation, err))
+	}
+	return output, nil
+}
+
+// Cancel asks the agent to abort the call with callID, if the agent
+// supports it. Agents that do not are left alone.
+func (r *Router) Cancel(
+	ctx context.Context,
+	agent string,
+	callID string,
+) error {
+	client, err := r.registry.Get(agent)
+	if err != nil {
+		return err
 	}
 
-	return callable.Call(ctx, input)
+	cancelable, ok := client.(Cancelable)
+	if !ok {
+		return nil
+	}
+	return cancelable.Cancel(ctx, callID)
 }
 
+// CallWithHealthCheck is Call, but an agent is probed before the call when
+// the router has no HealthChecker to consult.
 func (r *Router) CallWithHealthCheck(
 	ctx context.Context,
 	agent string,
 	input json.RawMessage,
 ) (json.RawMessage, error) {
+	if r.health != nil {
+		return r.Call(ctx, agent, input)
+	}
+
 	client, err := r.registry.Get(agent)
 	if err != nil {
 		return nil, err
 	}
 
-	if healthCheckable, ok := client.(interface{ HealthCheck(context.Context) error }); ok {
+	if healthCheckable, ok := client.(HealthCheckable); ok {
 		if err := healthCheckable.HealthCheck(ctx); err != nil {
-			return nil, fmt.Errorf("agent %s health check failed: %w", agent, err)
+			return nil, newError(KindUnhealthy, agent, err)
 		}
 	}
 
-	callable, ok := client.(Callable)
-	if !ok {
-		return nil, ErrNotCallable
+	return r.Call(ctx, agent, input)
+}
+
+// checkHealth fails when the cached status of the agent is unhealthy.
+// Pools only turn unhealthy once every endpoint is ejected; until then
+// they fail over to the endpoints left.
+func (r *Router) checkHealth(agent string) error {
+	if r.health == nil {
+		return nil
 	}
 
-	return callable.Call(ctx, input)
-}
\ No newline at end of file
+	healthy, retryAfter := r.health.Check(agent)
+	if healthy {
+		return nil
+	}
+
+	err := &Error{
+		Kind:       KindUnhealthy,
+		Agent:      agent,
+		RetryAfter: retryAfter,
+		Err:        errors.New("agent failed its last health check"),
+	}
+	if status, ok := r.health.GetStatus(agent); ok && status.Error != "" {
+		err.Err = fmt.Errorf("agent failed its last health check: %s", status.Error)
+	}
+	return err
+}
+
+// ValidateOutput checks an output the agent delivered outside of Call, such
+// as the result of an asynchronous job, against its output schema.
+func (r *Router) ValidateOutput(agent string, output json.RawMessage) error {
+	client, err := r.registry.Get(agent)
+	if err != nil {
+		return err
+	}
+
+	metadata := Describe(client).Metadata
+	if err := validateSchema(metadata.OutputSchema, output); err != nil {
+		return newError(KindPermanent, agent, fmt.Errorf("%w: output: %v", ErrSchemaViolation, err))
+	}
+	return nil
+}
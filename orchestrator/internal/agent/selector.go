@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+)
+
+// Selector picks the endpoint of a Pool that serves the next call. It is
+// only given endpoints that are currently available, and at least one.
+type Selector interface {
+	Select(ctx context.Context, endpoints []*Endpoint) *Endpoint
+}
+
+// Strategy names a Selector in configuration.
+type Strategy string
+
+const (
+	StrategyRoundRobin     Strategy = "round_robin"
+	StrategyLeastInFlight  Strategy = "least_in_flight"
+	StrategyLatency        Strategy = "latency"
+	StrategyConsistentHash Strategy = "consistent_hash"
+)
+
+// NewSelector returns a fresh Selector for strategy. The empty strategy is
+// round robin.
+func NewSelector(strategy Strategy) (Selector, error) {
+	switch strategy {
+	case "", StrategyRoundRobin:
+		return RoundRobin(), nil
+	case StrategyLeastInFlight:
+		return LeastInFlight(), nil
+	case StrategyLatency:
+		return LatencyWeighted(), nil
+	case StrategyConsistentHash:
+		return ConsistentHash(), nil
+	default:
+		return nil, fmt.Errorf("unknown selection strategy %q", strategy)
+	}
+}
+
+type roundRobin struct {
+	next atomic.Uint64
+}
+
+// RoundRobin cycles through the endpoints in turn.
+func RoundRobin() Selector {
+	return &roundRobin{}
+}
+
+func (s *roundRobin) Select(_ context.Context, endpoints []*Endpoint) *Endpoint {
+	n := s.next.Add(1) - 1
+	return endpoints[n%uint64(len(endpoints))]
+}
+
+type leastInFlight struct {
+	tie roundRobin
+}
+
+// LeastInFlight picks the endpoint with the fewest calls in progress,
+// taking turns between endpoints that are equally busy.
+func LeastInFlight() Selector {
+	return &leastInFlight{}
+}
+
+func (s *leastInFlight) Select(ctx context.Context, endpoints []*Endpoint) *Endpoint {
+	// Calls start and finish meanwhile, so the counts are read once.
+	var (
+		idle  []*Endpoint
+		least int64
+	)
+	for _, ep := range endpoints {
+		switch n := ep.InFlight(); {
+		case idle == nil || n < least:
+			idle, least = []*Endpoint{ep}, n
+		case n == least:
+			idle = append(idle, ep)
+		}
+	}
+	return s.tie.Select(ctx, idle)
+}
+
+type latencyWeighted struct{}
+
+// LatencyWeighted picks the endpoint with the lowest moving average
+// latency, scaled by the calls it already has in progress. Endpoints that
+// have not answered yet count as having the mean latency of the others,
+// so they are tried without taking every call of a burst.
+func LatencyWeighted() Selector {
+	return latencyWeighted{}
+}
+
+func (latencyWeighted) Select(_ context.Context, endpoints []*Endpoint) *Endpoint {
+	mean := meanLatency(endpoints)
+	if mean == 0 {
+		// Nothing observed yet: rank by calls in progress alone.
+		mean = 1
+	}
+
+	var (
+		best     *Endpoint
+		bestCost float64
+	)
+	for _, ep := range endpoints {
+		latency := ep.Latency()
+		if latency == 0 {
+			latency = mean
+		}
+		cost := float64(latency) * float64(ep.InFlight()+1)
+		if best == nil || cost < bestCost {
+			best, bestCost = ep, cost
+		}
+	}
+	return best
+}
+
+// meanLatency returns the mean latency of the endpoints that have one, or
+// zero when none has.
+func meanLatency(endpoints []*Endpoint) time.Duration {
+	var (
+		sum time.Duration
+		n   int
+	)
+	for _, ep := range endpoints {
+		if latency := ep.Latency(); latency > 0 {
+			sum += latency
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return sum / time.Duration(n)
+}
+
+type consistentHash struct {
+	fallback leastInFlight
+}
+
+// ConsistentHash sends calls with the same RoutingKey to the same endpoint
+// while it stays available, so that replicas can cache per-key state.
+// When an endpoint leaves the pool only its keys move. Calls without a key
+// go to the least busy endpoint.
+func ConsistentHash() Selector {
+	return &consistentHash{}
+}
+
+// Select uses rendezvous hashing: every endpoint scores the key and the
+// highest score wins.
+func (s *consistentHash) Select(ctx context.Context, endpoints []*Endpoint) *Endpoint {
+	key := RoutingKey(ctx)
+	if key == "" {
+		return s.fallback.Select(ctx, endpoints)
+	}
+
+	var (
+		best      *Endpoint
+		bestScore uint64
+	)
+	for _, ep := range endpoints {
+		h := fnv.New64a()
+		h.Write([]byte(ep.ID()))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if score := h.Sum64(); best == nil || score > bestScore {
+			best, bestScore = ep, score
+		}
+	}
+	return best
+}
@@ -0,0 +1,115 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testEndpoints(n int) []*Endpoint {
+	endpoints := make([]*Endpoint, n)
+	for i := range endpoints {
+		endpoints[i] = &Endpoint{id: fmt.Sprintf("ep-%d", i)}
+	}
+	return endpoints
+}
+
+// TestLeastInFlightConcurrentCalls selects while calls start and finish on
+// every endpoint, so that the counts change between reads.
+func TestLeastInFlightConcurrentCalls(t *testing.T) {
+	// With one endpoint, any change between reads leaves no endpoint as
+	// busy as the least busy one.
+	endpoints := testEndpoints(1)
+
+	ctx, stop := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for _, ep := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				ep.inFlight.Add(1)
+				ep.inFlight.Add(-1)
+			}
+		}()
+	}
+	defer func() {
+		stop()
+		wg.Wait()
+	}()
+
+	for _, selector := range []Selector{LeastInFlight(), ConsistentHash()} {
+		for range 100000 {
+			if selector.Select(context.Background(), endpoints) == nil {
+				t.Fatalf("%T picked no endpoint", selector)
+			}
+		}
+	}
+}
+
+func TestLeastInFlight(t *testing.T) {
+	endpoints := testEndpoints(3)
+	endpoints[0].inFlight.Store(2)
+	endpoints[1].inFlight.Store(1)
+	endpoints[2].inFlight.Store(1)
+
+	selector := LeastInFlight()
+	seen := make(map[string]bool)
+	for range 4 {
+		seen[selector.Select(context.Background(), endpoints).ID()] = true
+	}
+	if len(seen) != 2 || seen["ep-0"] {
+		t.Errorf("picked %v, want ep-1 and ep-2 in turn", seen)
+	}
+}
+
+func TestLatencyWeightedColdEndpoints(t *testing.T) {
+	endpoints := testEndpoints(3)
+	endpoints[0].observe(100 * time.Millisecond)
+	endpoints[1].observe(300 * time.Millisecond)
+	// ep-2 has not answered yet but already serves calls.
+	endpoints[2].inFlight.Store(3)
+
+	if got := LatencyWeighted().Select(context.Background(), endpoints).ID(); got != "ep-0" {
+		t.Errorf("picked %s, want ep-0 over the busy cold endpoint", got)
+	}
+
+	endpoints[2].inFlight.Store(0)
+	if got := LatencyWeighted().Select(context.Background(), endpoints).ID(); got != "ep-0" {
+		t.Errorf("picked %s, want ep-0, faster than the mean of a cold endpoint", got)
+	}
+
+	cold := testEndpoints(2)
+	cold[0].inFlight.Store(1)
+	if got := LatencyWeighted().Select(context.Background(), cold).ID(); got != "ep-1" {
+		t.Errorf("picked %s, want the idle one of two cold endpoints", got)
+	}
+}
+
+// failingClient fails every call transiently.
+type failingClient struct {
+	name string
+}
+
+func (c failingClient) Name() string     { return "agent" }
+func (c failingClient) Endpoint() string { return c.name }
+
+func (c failingClient) Call(context.Context, json.RawMessage) (json.RawMessage, error) {
+	return nil, &Error{Kind: KindTransient, Agent: "agent", Err: errors.New("unavailable")}
+}
+
+func TestPoolObservesTransientFailures(t *testing.T) {
+	pool := NewPool("agent", WithSelector(LatencyWeighted()))
+	pool.Add(failingClient{name: "a"})
+
+	if _, err := pool.Call(context.Background(), nil); err == nil {
+		t.Fatal("Call succeeded")
+	}
+	if pool.Endpoints()[0].Latency() == 0 {
+		t.Error("a transient failure left the endpoint without a latency")
+	}
+}
@@ -0,0 +1,140 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// Streaming agents answer /run with a stream of events instead of one JSON
+// body: either NDJSON, one event per line, or server-sent events, one
+// event per data field. The stream ends with an output or an error event;
+// progress and partial events may come before it.
+const (
+	contentTypeNDJSON = "application/x-ndjson"
+	contentTypeSSE    = "text/event-stream"
+)
+
+// maxStreamEvent bounds the size of one event of a stream.
+const maxStreamEvent = 16 << 20
+
+// streamEvent is one event of a streaming response. Type is progress,
+// partial, output or error; events of other types are skipped.
+type streamEvent struct {
+	Type    string          `json:"type"`
+	Percent float64         `json:"percent,omitempty"`
+	Message string          `json:"message,omitempty"`
+	Output  json.RawMessage `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Retry   bool            `json:"should_retry,omitempty"`
+}
+
+// isStream reports whether resp is a streaming response.
+func isStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == contentTypeNDJSON || mediaType == contentTypeSSE
+}
+
+// readStream reports the progress events of a streaming response and
+// returns its final output.
+func readStream(ctx context.Context, agent string, resp *http.Response) (json.RawMessage, error) {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+
+	next := nextNDJSONEvent
+	if mediaType == contentTypeSSE {
+		next = nextSSEEvent
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64<<10), maxStreamEvent)
+
+	for {
+		data, eventType, err := next(scanner)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, newError(KindTransient, agent, errors.New("stream ended without output"))
+			}
+			return nil, classifyTransportError(agent, fmt.Errorf("read stream: %w", err))
+		}
+
+		var e streamEvent
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, newError(KindTransient, agent, fmt.Errorf("decode stream event: %w", err))
+		}
+		if e.Type == "" {
+			e.Type = eventType
+		}
+
+		switch e.Type {
+		case "progress":
+			reportProgress(ctx, Progress{Percent: e.Percent, Message: e.Message})
+		case "partial":
+			reportProgress(ctx, Progress{Percent: e.Percent, Message: e.Message, Partial: e.Output})
+		case "output":
+			return e.Output, nil
+		case "error":
+			kind := KindPermanent
+			if e.Retry {
+				kind = KindTransient
+			}
+			return nil, newError(kind, agent, fmt.Errorf("agent error: %s", e.Error))
+		}
+	}
+}
+
+// nextNDJSONEvent returns the next non-empty line.
+func nextNDJSONEvent(scanner *bufio.Scanner) ([]byte, string, error) {
+	for scanner.Scan() {
+		if line := bytes.TrimSpace(scanner.Bytes()); len(line) > 0 {
+			return line, "", nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	return nil, "", io.EOF
+}
+
+// nextSSEEvent returns the data of the next server-sent event with data,
+// and its event field.
+func nextSSEEvent(scanner *bufio.Scanner) ([]byte, string, error) {
+	var (
+		data      [][]byte
+		eventType string
+	)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			if len(data) > 0 {
+				return bytes.Join(data, []byte("\n")), eventType, nil
+			}
+			eventType = ""
+			continue
+		}
+
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		value = bytes.TrimPrefix(value, []byte(" "))
+		switch string(field) {
+		case "data":
+			data = append(data, bytes.Clone(value))
+		case "event":
+			eventType = string(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	if len(data) > 0 {
+		return bytes.Join(data, []byte("\n")), eventType, nil
+	}
+	return nil, "", io.EOF
+}
@@ -0,0 +1,499 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"sync"
+)
+
+const (
+	defaultRollbackMinCalls  = 20
+	defaultRollbackTolerance = 0.05
+)
+
+// Rollout decides which version of a Versioned agent serves a call. Rules
+// are tried in order: a pin matching a label of the task, then the canary,
+// then the weighted split, and Stable serves whatever is left.
+//
+// Calls are bucketed by their routing key, so every step of a task lands
+// on the same version; calls without a key are bucketed at random.
+type Rollout struct {
+	Stable string `json:"stable"`
+
+	// Pins route the tasks carrying a label to a fixed version.
+	Pins map[string]string `json:"pins,omitempty"`
+
+	// Canary receives CanaryPercent of the calls that are not pinned.
+	Canary        string  `json:"canary,omitempty"`
+	CanaryPercent float64 `json:"canary_percent,omitempty"`
+
+	// Weights split the calls left over by the canary between versions
+	// by relative weight. Without weights they all go to Stable.
+	Weights map[string]int `json:"weights,omitempty"`
+
+	// AutoRollback stops the canary once it has served RollbackMinCalls
+	// calls and its error rate exceeds that of Stable by more than
+	// RollbackTolerance.
+	AutoRollback      bool    `json:"auto_rollback,omitempty"`
+	RollbackMinCalls  int     `json:"rollback_min_calls,omitempty"`
+	RollbackTolerance float64 `json:"rollback_tolerance,omitempty"`
+
+	// RolledBack names the canary after it was rolled back. It gets no
+	// traffic until a new canary is configured.
+	RolledBack string `json:"rolled_back,omitempty"`
+}
+
+// Validate checks that every version the rollout names is one of versions.
+func (r *Rollout) Validate(versions []string) error {
+	known := func(field, v string) error {
+		if !slices.Contains(versions, v) {
+			return fmt.Errorf("rollout %s: unknown version %q", field, v)
+		}
+		return nil
+	}
+
+	if err := known("stable", r.Stable); err != nil {
+		return err
+	}
+	for label, v := range r.Pins {
+		if err := known("pin "+label, v); err != nil {
+			return err
+		}
+	}
+	if r.Canary != "" {
+		if err := known("canary", r.Canary); err != nil {
+			return err
+		}
+	}
+	if r.CanaryPercent < 0 || r.CanaryPercent > 100 {
+		return errors.New("rollout canary_percent must be between 0 and 100")
+	}
+	for v, w := range r.Weights {
+		if err := known("weight", v); err != nil {
+			return err
+		}
+		if w < 0 {
+			return fmt.Errorf("rollout weight of %q must not be negative", v)
+		}
+	}
+	if r.RollbackMinCalls < 0 || r.RollbackTolerance < 0 {
+		return errors.New("rollout rollback settings must not be negative")
+	}
+	return nil
+}
+
+// canaryActive reports whether the canary takes traffic.
+func (r *Rollout) canaryActive() bool {
+	return r.Canary != "" && r.Canary != r.RolledBack && r.CanaryPercent > 0
+}
+
+// Rollback reports a canary rolled back for its error rate.
+type Rollback struct {
+	Agent        string  `json:"agent"`
+	Version      string  `json:"version"`
+	ErrorRate    float64 `json:"error_rate"`
+	BaselineRate float64 `json:"baseline_rate"`
+}
+
+// VersionStatus is a snapshot of one version of a Versioned agent.
+type VersionStatus struct {
+	Version   string       `json:"version"`
+	Healthy   bool         `json:"healthy"`
+	Circuit   CircuitState `json:"circuit"`
+	Calls     int          `json:"calls"`
+	Failures  int          `json:"failures"`
+	ErrorRate float64      `json:"error_rate"`
+}
+
+type agentVersion struct {
+	name    string
+	client  Callable
+	circuit *CircuitBreaker
+
+	mu        sync.RWMutex
+	unhealthy bool
+}
+
+func (v *agentVersion) available() bool {
+	v.mu.RLock()
+	unhealthy := v.unhealthy
+	v.mu.RUnlock()
+
+	return !unhealthy && v.circuit.Ready()
+}
+
+func (v *agentVersion) errorRate() (float64, int) {
+	calls, failures := v.circuit.Stats()
+	if calls == 0 {
+		return 0, 0
+	}
+	return float64(failures) / float64(calls), calls
+}
+
+// Versioned serves several versions of one agent under its name and
+// routes each call to one of them by its Rollout. Every version has its
+// own circuit breaker, whose stats drive the automatic rollback of a
+// canary. Calls routed to a version that is unhealthy or whose circuit is
+// open go to Stable instead.
+type Versioned struct {
+	name    string
+	breaker CircuitBreakerConfig
+
+	mu         sync.RWMutex
+	versions   []*agentVersion
+	rollout    Rollout
+	onRollback []func(Rollback)
+}
+
+type VersionedOption func(*Versioned)
+
+// WithRollout sets the routing rules. The default sends every call to the
+// first version added.
+func WithRollout(r Rollout) VersionedOption {
+	return func(v *Versioned) {
+		v.rollout = r
+	}
+}
+
+// WithVersionBreaker configures the circuit breaker of each version.
+func WithVersionBreaker(config CircuitBreakerConfig) VersionedOption {
+	return func(v *Versioned) {
+		v.breaker = config
+	}
+}
+
+// OnRollback registers fn to be told of every automatic rollback. fn runs
+// in its own goroutine.
+func OnRollback(fn func(Rollback)) VersionedOption {
+	return func(v *Versioned) {
+		v.onRollback = append(v.onRollback, fn)
+	}
+}
+
+func NewVersioned(name string, opts ...VersionedOption) *Versioned {
+	v := &Versioned{
+		name:    name,
+		breaker: DefaultCircuitBreakerConfig(),
+	}
+
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+func (v *Versioned) Name() string {
+	return v.name
+}
+
+// Add adds client as version, replacing a version of the same name.
+func (v *Versioned) Add(version string, client Callable) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	breaker := v.breaker
+	av := &agentVersion{
+		name:    version,
+		client:  client,
+		circuit: NewCircuitBreaker(&breaker),
+	}
+
+	if v.rollout.Stable == "" {
+		v.rollout.Stable = version
+	}
+	for i, existing := range v.versions {
+		if existing.name == version {
+			v.versions[i] = av
+			return
+		}
+	}
+	v.versions = append(v.versions, av)
+}
+
+// Rollout returns the current routing rules, with RolledBack set once the
+// canary was rolled back.
+func (v *Versioned) Rollout() Rollout {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return v.rollout
+}
+
+// SetRollout replaces the routing rules.
+func (v *Versioned) SetRollout(r Rollout) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	names := make([]string, len(v.versions))
+	for i, av := range v.versions {
+		names[i] = av.name
+	}
+	if err := r.Validate(names); err != nil {
+		return err
+	}
+	v.rollout = r
+	return nil
+}
+
+func (v *Versioned) version(name string) *agentVersion {
+	for _, av := range v.versions {
+		if av.name == name {
+			return av
+		}
+	}
+	return nil
+}
+
+// Close closes the clients of the versions that hold resources, such as
+// gRPC connections or WASM runtimes.
+func (v *Versioned) Close() error {
+	v.mu.RLock()
+	clients := make([]Callable, len(v.versions))
+	for i, av := range v.versions {
+		clients[i] = av.client
+	}
+	v.mu.RUnlock()
+
+	return closeAll(clients)
+}
+
+// Metadata returns the metadata of the stable version.
+func (v *Versioned) Metadata() Metadata {
+	v.mu.RLock()
+	stable := v.version(v.rollout.Stable)
+	v.mu.RUnlock()
+
+	if stable == nil {
+		return Metadata{}
+	}
+	if d, ok := stable.client.(Describer); ok {
+		return d.Metadata()
+	}
+	return Metadata{Version: stable.name}
+}
+
+// pick returns the version the rollout routes the call to, and Stable.
+func (v *Versioned) pick(ctx context.Context) (chosen, stable *agentVersion) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	r := v.rollout
+	stable = v.version(r.Stable)
+	if stable == nil && len(v.versions) > 0 {
+		stable = v.versions[0]
+	}
+
+	for _, label := range TaskLabels(ctx) {
+		if name, ok := r.Pins[label]; ok {
+			if av := v.version(name); av != nil {
+				return av, stable
+			}
+		}
+	}
+
+	key := RoutingKey(ctx)
+	if r.canaryActive() && bucket(key, "canary", 10000) < int(r.CanaryPercent*100) {
+		if av := v.version(r.Canary); av != nil {
+			return av, stable
+		}
+	}
+
+	total := 0
+	names := make([]string, 0, len(r.Weights))
+	for name, w := range r.Weights {
+		// A rolled back canary must not come back through the split.
+		if w > 0 && name != r.RolledBack {
+			total += w
+			names = append(names, name)
+		}
+	}
+	if total > 0 {
+		sort.Strings(names)
+		n := bucket(key, "weights", total)
+		for _, name := range names {
+			n -= r.Weights[name]
+			if n < 0 {
+				if av := v.version(name); av != nil {
+					return av, stable
+				}
+				break
+			}
+		}
+	}
+	return stable, stable
+}
+
+// bucket maps key to [0, n), the same way every time for one key and at
+// random for none. salt decorrelates the rules bucketing the same key.
+func bucket(key, salt string, n int) int {
+	if key == "" {
+		return rand.IntN(n)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(salt))
+	h.Write([]byte(key))
+	return int(h.Sum64() % uint64(n))
+}
+
+func (v *Versioned) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	av, stable := v.pick(ctx)
+	if av == nil {
+		return nil, newError(KindPermanent, v.name, errors.New("no versions"))
+	}
+	if !av.available() && stable != nil {
+		av = stable
+	}
+	if info := callInfo(ctx); info != nil {
+		info.Version = av.name
+	}
+
+	var output json.RawMessage
+	err := av.circuit.Execute(func() error {
+		var err error
+		output, err = av.client.Call(ctx, input)
+		return err
+	})
+	if err == ErrCircuitOpen {
+		return nil, &Error{
+			Kind:       KindCircuitOpen,
+			Agent:      v.name,
+			RetryAfter: av.circuit.RetryAfter(),
+			Err:        fmt.Errorf("version %s: %w", av.name, err),
+		}
+	}
+
+	v.checkCanary()
+	return output, err
+}
+
+// checkCanary rolls the canary back when it fails noticeably more often
+// than Stable.
+func (v *Versioned) checkCanary() {
+	v.mu.Lock()
+	r := v.rollout
+	if !r.AutoRollback || !r.canaryActive() || r.Canary == r.Stable {
+		v.mu.Unlock()
+		return
+	}
+	canary, stable := v.version(r.Canary), v.version(r.Stable)
+	if canary == nil || stable == nil {
+		v.mu.Unlock()
+		return
+	}
+
+	minCalls := r.RollbackMinCalls
+	if minCalls == 0 {
+		minCalls = defaultRollbackMinCalls
+	}
+	tolerance := r.RollbackTolerance
+	if tolerance == 0 {
+		tolerance = defaultRollbackTolerance
+	}
+
+	rate, calls := canary.errorRate()
+	baseline, _ := stable.errorRate()
+	if calls < minCalls || rate <= baseline+tolerance {
+		v.mu.Unlock()
+		return
+	}
+
+	v.rollout.RolledBack = r.Canary
+	hooks := v.onRollback
+	v.mu.Unlock()
+
+	event := Rollback{Agent: v.name, Version: r.Canary, ErrorRate: rate, BaselineRate: baseline}
+	log.Printf("agent %s: rolled back canary %s: error rate %.2f against %.2f for %s",
+		v.name, r.Canary, rate, baseline, r.Stable)
+	for _, fn := range hooks {
+		go fn(event)
+	}
+}
+
+// Cancel forwards the cancellation to every version that supports it, as
+// the call may have been served by any of them.
+func (v *Versioned) Cancel(ctx context.Context, callID string) error {
+	v.mu.RLock()
+	versions := slices.Clone(v.versions)
+	v.mu.RUnlock()
+
+	var errs []error
+	for _, av := range versions {
+		cancelable, ok := av.client.(Cancelable)
+		if !ok {
+			continue
+		}
+		if err := cancelable.Cancel(ctx, callID); err != nil {
+			errs = append(errs, fmt.Errorf("version %s: %w", av.name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HealthCheck checks every version that supports it. Unhealthy versions
+// stop receiving calls; the agent as a whole is only unhealthy when Stable
+// is.
+func (v *Versioned) HealthCheck(ctx context.Context) error {
+	v.mu.RLock()
+	versions := slices.Clone(v.versions)
+	stableName := v.rollout.Stable
+	v.mu.RUnlock()
+
+	var (
+		wg        sync.WaitGroup
+		stableErr error
+	)
+	for _, av := range versions {
+		checkable, ok := av.client.(HealthCheckable)
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := checkable.HealthCheck(ctx)
+
+			av.mu.Lock()
+			av.unhealthy = err != nil
+			av.mu.Unlock()
+
+			if av.name == stableName && err != nil {
+				stableErr = fmt.Errorf("stable version %s: %w", av.name, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return stableErr
+}
+
+// Status returns a snapshot of every version.
+func (v *Versioned) Status() []VersionStatus {
+	v.mu.RLock()
+	versions := slices.Clone(v.versions)
+	v.mu.RUnlock()
+
+	out := make([]VersionStatus, 0, len(versions))
+	for _, av := range versions {
+		calls, failures := av.circuit.Stats()
+		rate, _ := av.errorRate()
+
+		av.mu.RLock()
+		healthy := !av.unhealthy
+		av.mu.RUnlock()
+
+		out = append(out, VersionStatus{
+			Version:   av.name,
+			Healthy:   healthy,
+			Circuit:   av.circuit.State(),
+			Calls:     calls,
+			Failures:  failures,
+			ErrorRate: rate,
+		})
+	}
+	return out
+}
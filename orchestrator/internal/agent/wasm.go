@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+const (
+	// defaultWASMMemory bounds the linear memory of a module.
+	defaultWASMMemory = 64 << 20
+	wasmPageSize      = 64 << 10
+)
+
+// ErrModuleNotFound is returned for a WASM module digest nothing was
+// uploaded under.
+var ErrModuleNotFound = errors.New("wasm module not found")
+
+// WASMModule describes a WebAssembly module uploaded for agents to run.
+type WASMModule struct {
+	// Digest identifies the module by its content: "sha256:" and the
+	// hex SHA-256 of the module.
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ModuleDigest returns the digest a module is stored under.
+func ModuleDigest(module []byte) string {
+	sum := sha256.Sum256(module)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// WASMAgent runs a WebAssembly module in process for every call. The
+// module is a WASI command: it reads the input from stdin and writes its
+// output, one JSON value, to stdout, and fails the call by exiting
+// non-zero, transiently with exit code 75 like an ExecAgent.
+//
+// Modules are sandboxed: each call gets a fresh instance with bounded
+// memory and time, no filesystem, no network, and no host functions other
+// than those of WASI. Modules importing anything else are refused. The
+// runtime meters time, not instructions: a call that runs out of it is
+// interrupted wherever it is.
+type WASMAgent struct {
+	name      string
+	runtime   wazero.Runtime
+	module    wazero.CompiledModule
+	timeout   time.Duration
+	maxMemory int64
+	maxOutput int64
+	metadata  Metadata
+	calls     drain
+}
+
+type WASMAgentOption func(*WASMAgent)
+
+// WithWASMTimeout bounds each call.
+func WithWASMTimeout(timeout time.Duration) WASMAgentOption {
+	return func(a *WASMAgent) {
+		a.timeout = timeout
+	}
+}
+
+// WithWASMMemoryLimit bounds the linear memory of the module, rounded up
+// to whole 64 KiB pages. The default is 64 MiB.
+func WithWASMMemoryLimit(bytes int64) WASMAgentOption {
+	return func(a *WASMAgent) {
+		a.maxMemory = bytes
+	}
+}
+
+// WithWASMMaxOutput bounds how many bytes the module may write to stdout.
+func WithWASMMaxOutput(n int64) WASMAgentOption {
+	return func(a *WASMAgent) {
+		a.maxOutput = n
+	}
+}
+
+// WithWASMMetadata sets what the agent reports through Metadata.
+func WithWASMMetadata(m Metadata) WASMAgentOption {
+	return func(a *WASMAgent) {
+		a.metadata = m
+	}
+}
+
+// NewWASMAgent compiles module into an agent. Close releases it.
+func NewWASMAgent(name string, module []byte, opts ...WASMAgentOption) (*WASMAgent, error) {
+	a := &WASMAgent{
+		name:      name,
+		timeout:   30 * time.Second,
+		maxMemory: defaultWASMMemory,
+		maxOutput: defaultMaxExecOutput,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	ctx := context.Background()
+	pages := (a.maxMemory + wasmPageSize - 1) / wasmPageSize
+	a.runtime = wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(max(pages, 1))).
+		WithCloseOnContextDone(true))
+
+	compiled, err := compileWASM(ctx, a.runtime, module)
+	if err != nil {
+		a.runtime.Close(ctx)
+		return nil, fmt.Errorf("agent %s: %w", name, err)
+	}
+	a.module = compiled
+
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, a.runtime); err != nil {
+		a.runtime.Close(ctx)
+		return nil, fmt.Errorf("agent %s: %w", name, err)
+	}
+	return a, nil
+}
+
+// ValidateWASMModule checks that module compiles and imports nothing but
+// WASI, without keeping it.
+func ValidateWASMModule(module []byte) error {
+	ctx := context.Background()
+	runtime := wazero.NewRuntime(ctx)
+	defer runtime.Close(ctx)
+
+	_, err := compileWASM(ctx, runtime, module)
+	return err
+}
+
+func compileWASM(ctx context.Context, runtime wazero.Runtime, module []byte) (wazero.CompiledModule, error) {
+	compiled, err := runtime.CompileModule(ctx, module)
+	if err != nil {
+		return nil, fmt.Errorf("compile wasm module: %w", err)
+	}
+
+	for _, fn := range compiled.ImportedFunctions() {
+		if mod, name, _ := fn.Import(); mod != wasi_snapshot_preview1.ModuleName {
+			return nil, fmt.Errorf("wasm module imports %s.%s; only %s is available",
+				mod, name, wasi_snapshot_preview1.ModuleName)
+		}
+	}
+	for _, mem := range compiled.ImportedMemories() {
+		mod, name, _ := mem.Import()
+		return nil, fmt.Errorf("wasm module imports memory %s.%s; it must define its own", mod, name)
+	}
+	return compiled, nil
+}
+
+func (a *WASMAgent) Name() string {
+	return a.name
+}
+
+func (a *WASMAgent) Metadata() Metadata {
+	return a.metadata
+}
+
+// Close releases the compiled module once the calls in progress finish.
+// Calls made afterwards fail transiently.
+func (a *WASMAgent) Close() error {
+	a.calls.close()
+	return a.runtime.Close(context.Background())
+}
+
+func (a *WASMAgent) Call(ctx context.Context, input json.RawMessage) (json.RawMessage, error) {
+	if !a.calls.enter() {
+		return nil, newError(KindTransient, a.name, errClosed)
+	}
+	defer a.calls.leave()
+
+	ctx, cancel, timeout := withCallTimeout(ctx, a.timeout)
+	defer cancel()
+	// runCtx also ends when stdout overflows.
+	runCtx, kill := context.WithCancel(ctx)
+	defer kill()
+
+	if len(input) == 0 {
+		input = json.RawMessage(`{}`)
+	}
+
+	stdout := &limitedBuffer{max: a.maxOutput, exceeded: kill}
+	stderr := &tailBuffer{max: maxExecStderr}
+
+	config := wazero.NewModuleConfig().
+		// Instances are anonymous so that calls can run side by side.
+		WithName("").
+		WithArgs(a.name).
+		WithEnv("ORCHESTRATOR_AGENT", a.name).
+		WithEnv("ORCHESTRATOR_CALL_ID", CallID(ctx)).
+		WithStdin(bytes.NewReader(input)).
+		WithStdout(stdout).
+		WithStderr(stderr).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+
+	mod, err := a.runtime.InstantiateModule(runCtx, a.module, config)
+	if mod != nil {
+		mod.Close(context.WithoutCancel(ctx))
+	}
+	if info := callInfo(ctx); info != nil {
+		info.Stderr = stderr.String()
+	}
+
+	var exitErr *sys.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 0 {
+		err = nil
+	}
+
+	switch {
+	case stdout.overflow():
+		return nil, newError(KindPermanent, a.name, fmt.Errorf("output exceeds %d bytes", a.maxOutput))
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, newError(KindTimeout, a.name, fmt.Errorf("module timed out after %s", timeout))
+	case ctx.Err() != nil:
+		return nil, newError(KindCanceled, a.name, ctx.Err())
+	case exitErr != nil && err != nil:
+		err = fmt.Errorf("exit code %d", exitErr.ExitCode())
+		if line := lastLine(stderr.String()); line != "" {
+			err = fmt.Errorf("%w: %s", err, line)
+		}
+		if exitErr.ExitCode() == execTempFail {
+			return nil, newError(KindTransient, a.name, err)
+		}
+		return nil, newError(KindPermanent, a.name, err)
+	case err != nil:
+		// A trap, e.g. running out of memory, recurs with the same input.
+		return nil, newError(KindPermanent, a.name, err)
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(output) {
+		return nil, newError(KindPermanent, a.name, errors.New("stdout is not a JSON value"))
+	}
+	return output, nil
+}
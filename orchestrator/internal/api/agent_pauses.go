@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yeOmaNnn/orchestrator/internal/engine"
+)
+
+// handlePausedAgents lists paused agents (GET) or pauses one (POST with
+// {"agent", "reason"}).
+func (h *Handler) handlePausedAgents(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		pauses, err := h.engine.AgentPauses(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pauses)
+
+	case http.MethodPost:
+		var req struct {
+			Agent  string `json:"agent"`
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Agent == "" {
+			http.Error(w, "agent is required", http.StatusBadRequest)
+			return
+		}
+
+		pause, err := h.engine.PauseAgent(r.Context(), req.Agent, req.Reason)
+		if err != nil {
+			writePauseError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(pause)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// resumeAgent lifts the pause of the agent named in the path (DELETE).
+func (h *Handler) resumeAgent(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	agent := strings.TrimPrefix(r.URL.Path, "/admin/paused-agents/")
+	if agent == "" || strings.Contains(agent, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := h.engine.ResumeAgent(r.Context(), agent); err != nil {
+		writePauseError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writePauseError(w http.ResponseWriter, err error) {
+	if errors.Is(err, engine.ErrPausesDisabled) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/catalog"
+)
+
+// Agents serves the agent catalog, HTTP agents registered at runtime, and
+// describes the other agents of this process.
+type Agents struct {
+	catalog *catalog.Catalog
+	// registry is nil in processes that do not call agents.
+	registry *agent.Registry
+}
+
+func NewAgents(catalog *catalog.Catalog, registry *agent.Registry) *Agents {
+	return &Agents{catalog: catalog, registry: registry}
+}
+
+// agentListing is an entry of GET /agents. Config is only set for catalog
+// agents; the others were registered by the process itself or discovered
+// from an agent host.
+type agentListing struct {
+	agent.Description
+	Config *agent.Config `json:"config,omitempty"`
+}
+
+func (a *Agents) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/agents", a.handleAgents)
+	mux.HandleFunc("/agents/", a.handleAgentByName)
+}
+
+// handleAgents lists every known agent with its metadata (GET) or
+// registers an agent (POST with an agent.Config).
+func (a *Agents) handleAgents(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		configs, err := a.catalog.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, a.listing(configs))
+
+	case http.MethodPost:
+		var cfg agent.Config
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := a.catalog.Register(r.Context(), &cfg); err != nil {
+			writeAgentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, cfg)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAgentByName reads (GET), replaces (PUT) or deregisters (DELETE) the
+// agent named in the path.
+func (a *Agents) handleAgentByName(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/agents/")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		cfg, err := a.catalog.Get(r.Context(), name)
+		if err != nil {
+			writeAgentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, cfg)
+
+	case http.MethodPut:
+		var cfg agent.Config
+		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if cfg.Name != "" && cfg.Name != name {
+			http.Error(w, "name does not match the path", http.StatusBadRequest)
+			return
+		}
+		cfg.Name = name
+
+		if err := a.catalog.Update(r.Context(), &cfg); err != nil {
+			writeAgentError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, cfg)
+
+	case http.MethodDelete:
+		if err := a.catalog.Deregister(r.Context(), name); err != nil {
+			writeAgentError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *Agents) listing(configs []agent.Config) []agentListing {
+	out := make([]agentListing, 0, len(configs))
+	inCatalog := make(map[string]bool, len(configs))
+	for i := range configs {
+		cfg := &configs[i]
+		inCatalog[cfg.Name] = true
+		out = append(out, agentListing{
+			Description: agent.Description{Name: cfg.Name, Metadata: cfg.Metadata},
+			Config:      cfg,
+		})
+	}
+
+	if a.registry != nil {
+		for _, d := range a.registry.Describe() {
+			if !inCatalog[d.Name] {
+				out = append(out, agentListing{Description: d})
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+func writeAgentError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, catalog.ErrInvalidConfig):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, catalog.ErrExecNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, catalog.ErrAgentExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, agent.ErrAgentNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/callback"
+	"github.com/yeOmaNnn/orchestrator/internal/runner"
+)
+
+// maxCallbackBody bounds the result an agent may post to a callback.
+const maxCallbackBody = 16 << 20
+
+// Callbacks receives the results of asynchronous agent jobs at the URLs
+// signed by signer.
+type Callbacks struct {
+	signer *callback.Signer
+	runner *runner.Runner
+}
+
+func NewCallbacks(signer *callback.Signer, runner *runner.Runner) *Callbacks {
+	return &Callbacks{
+		signer: signer,
+		runner: runner,
+	}
+}
+
+func (c *Callbacks) Register(mux *http.ServeMux) {
+	mux.HandleFunc(callback.Path, c.handleCallback)
+}
+
+// handleCallback settles a step with the result its job posts:
+//
+//	POST /callbacks/{stepID}?attempt=N&sig=...
+//	{"output": {...}} or {"error": "...", "should_retry": false}
+//
+// A result for a job the step no longer awaits, e.g. one that was already
+// settled by polling or whose step was cancelled, is answered 409.
+func (c *Callbacks) handleCallback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	stepID, err := uuid.Parse(strings.TrimPrefix(r.URL.Path, callback.Path))
+	if err != nil {
+		http.Error(w, "invalid step id", http.StatusBadRequest)
+		return
+	}
+	q := r.URL.Query()
+	attempt, err := strconv.Atoi(q.Get("attempt"))
+	if err != nil {
+		http.Error(w, "invalid attempt", http.StatusBadRequest)
+		return
+	}
+	if !c.signer.Verify(stepID, attempt, q.Get("sig")) {
+		http.Error(w, "invalid signature", http.StatusForbidden)
+		return
+	}
+
+	var result agent.JobResult
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCallbackBody)).Decode(&result); err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	err = c.runner.Complete(r.Context(), stepID, attempt, result)
+	switch {
+	case errors.Is(err, runner.ErrJobNotFound):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/engine"
+	"github.com/yeOmaNnn/orchestrator/internal/storage"
+)
+
+func (h *Handler) listDeadLetters(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	filter := storage.DeadLetterFilter{
+		Agent: q.Get("agent"),
+		Limit: 100,
+	}
+
+	if v := q.Get("task_id"); v != "" {
+		taskID, err := uuid.Parse(v)
+		if err != nil {
+			http.Error(w, "invalid task_id", http.StatusBadRequest)
+			return
+		}
+		filter.TaskID = &taskID
+	}
+	if v := q.Get("requeued"); v != "" {
+		requeued, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "invalid requeued", http.StatusBadRequest)
+			return
+		}
+		filter.Requeued = &requeued
+	}
+	if v := q.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		filter.Limit = limit
+	}
+
+	dls, err := h.deadLetters.List(r.Context(), filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dls)
+}
+
+func (h *Handler) handleDeadLetterByID(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	path := strings.TrimPrefix(r.URL.Path, "/admin/dead-letters/")
+	parts := strings.Split(path, "/")
+
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		http.Error(w, "invalid dead letter id", http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		h.getDeadLetter(w, r, id)
+	case len(parts) == 2 && parts[1] == "requeue":
+		h.requeueDeadLetter(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) getDeadLetter(
+	w http.ResponseWriter,
+	r *http.Request,
+	id uuid.UUID,
+) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	dl, err := h.deadLetters.GetByID(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dl)
+}
+
+func (h *Handler) requeueDeadLetter(
+	w http.ResponseWriter,
+	r *http.Request,
+	id uuid.UUID,
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Input json.RawMessage `json:"input"`
+		Agent string          `json:"agent"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+
+	dl, err := h.deadLetters.GetByID(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	revived, err := h.engine.RequeueDeadLetter(r.Context(), id, engine.RequeueOptions{
+		Input: req.Input,
+		Agent: req.Agent,
+	})
+	switch {
+	case errors.Is(err, engine.ErrAlreadyRequeued),
+		errors.Is(err, engine.ErrTaskNotRevivable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if revived {
+		go h.engine.RunTaskLoop(context.WithoutCancel(r.Context()), dl.TaskID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "requeued",
+		"step_id": dl.StepID,
+		"task_id": dl.TaskID,
+		"revived": revived,
+	})
+}
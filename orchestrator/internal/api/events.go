@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/events"
+)
+
+// keepaliveInterval is how often an idle event stream sends a comment, so
+// that proxies keep the connection open.
+const keepaliveInterval = 15 * time.Second
+
+// streamTaskEvents streams the events of a task as server-sent events, from
+// now until the client disconnects.
+func (h *Handler) streamTaskEvents(
+	w http.ResponseWriter,
+	r *http.Request,
+	taskID uuid.UUID,
+) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if _, err := h.taskRepo.GetByID(r.Context(), taskID); err != nil {
+		http.Error(w, "task not found", http.StatusNotFound)
+		return
+	}
+
+	h.streamEvents(w, r, taskID)
+}
+
+// streamAgentEvents streams the health transitions of agents, as seen by
+// every process that checks agent health, as server-sent events:
+//
+//	GET /agent-events
+func (h *Handler) streamAgentEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.streamEvents(w, r, events.AgentTopic)
+}
+
+// streamEvents streams the events published under topic until the client
+// disconnects.
+func (h *Handler) streamEvents(w http.ResponseWriter, r *http.Request, topic uuid.UUID) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	sub, cancel := h.events.Subscribe(topic)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-keepalive.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
+
+		case e, ok := <-sub:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+			flusher.Flush()
+		}
+	}
+}
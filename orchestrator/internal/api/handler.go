@@ -1,44 +1,63 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	
-	"strings"
+
 	"github.com/google/uuid"
+	"strings"
+	"time"
 
 	"github.com/yeOmaNnn/orchestrator/internal/domain"
 	"github.com/yeOmaNnn/orchestrator/internal/engine"
+	"github.com/yeOmaNnn/orchestrator/internal/events"
 	"github.com/yeOmaNnn/orchestrator/internal/storage"
 )
 
 type Handler struct {
-	engine *engine.Engine
-	taskRepo storage.TaskRepository
-	stepRepo storage.StepRepository
+	engine     *engine.Engine
+	taskRepo   storage.TaskRepository
+	stepRepo   storage.StepRepository
+	workerRepo storage.WorkerRepository
+
+	deadLetters storage.DeadLetterRepository
+	schedules   storage.ScheduleRepository
+	events      events.Bus
 }
 
 func NewHandler(
-	engine *engine.Engine, 
-	taskRepo storage.TaskRepository, 
+	engine *engine.Engine,
+	taskRepo storage.TaskRepository,
 	stepRepo storage.StepRepository,
+	workerRepo storage.WorkerRepository,
+	deadLetters storage.DeadLetterRepository,
+	schedules storage.ScheduleRepository,
+	bus events.Bus,
 ) *Handler {
 	return &Handler{
-		engine: engine,
-		taskRepo: taskRepo,
-		stepRepo: stepRepo,
+		engine:      engine,
+		taskRepo:    taskRepo,
+		stepRepo:    stepRepo,
+		workerRepo:  workerRepo,
+		deadLetters: deadLetters,
+		schedules:   schedules,
+		events:      bus,
 	}
 }
 
 func (h *Handler) Register(mux *http.ServeMux) {
-	mux.HandleFunc("/health", h.health)
 	mux.HandleFunc("/tasks", h.createTask)
 	mux.HandleFunc("/tasks/", h.handleTaskByID)
-}
-
-func (h *Handler) health(w http.ResponseWriter, _ *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("ok"))
+	mux.HandleFunc("/agent-events", h.streamAgentEvents)
+	mux.HandleFunc("/workers", h.listWorkers)
+	mux.HandleFunc("/schedules", h.handleSchedules)
+	mux.HandleFunc("/schedules/", h.handleScheduleByID)
+	mux.HandleFunc("/admin/dead-letters", h.listDeadLetters)
+	mux.HandleFunc("/admin/dead-letters/", h.handleDeadLetterByID)
+	mux.HandleFunc("/admin/paused-agents", h.handlePausedAgents)
+	mux.HandleFunc("/admin/paused-agents/", h.resumeAgent)
 }
 
 func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) {
@@ -49,6 +68,14 @@ func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		Goal string `json:"goal"`
+
+		// Deadline and TimeoutSeconds are alternative ways to bound the
+		// whole task; Deadline wins when both are set.
+		Deadline       *time.Time `json:"deadline"`
+		TimeoutSeconds int        `json:"timeout_seconds"`
+
+		// Labels tag the task, e.g. to pin it to a version of an agent.
+		Labels []string `json:"labels"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -56,24 +83,29 @@ func (h *Handler) createTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	task := domain.Task {
-		ID: uuid.New(), 
-		Goal: req.Goal, 
-		Status: domain.TaskPending,
+	now := time.Now()
+	task := domain.Task{
+		ID:        uuid.New(),
+		Goal:      req.Goal,
+		Status:    domain.TaskPending,
+		CreatedAt: now,
+		Deadline:  req.Deadline,
+		Labels:    req.Labels,
 	}
-
-	if err := h.taskRepo.Create(r.Context(), &task); err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+	if task.Deadline == nil && req.TimeoutSeconds > 0 {
+		deadline := now.Add(time.Duration(req.TimeoutSeconds) * time.Second)
+		task.Deadline = &deadline
+	}
+	if task.Deadline != nil && !task.Deadline.After(now) {
+		http.Error(w, "deadline is in the past", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.engine.InitTaskExecution(r.Context(), task); err != nil {
+	if err := h.engine.SubmitTask(r.Context(), &task); err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
-	go h.engine.RunTaskLoop(r.Context(), task.ID)
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(task)
 
@@ -89,7 +121,7 @@ func (h *Handler) getTask(w http.ResponseWriter, r *http.Request) {
 	taskID, err := uuid.Parse(idStr)
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		return 
+		return
 	}
 
 	task, err := h.taskRepo.GetByID(r.Context(), taskID)
@@ -101,10 +133,10 @@ func (h *Handler) getTask(w http.ResponseWriter, r *http.Request) {
 	steps, _ := h.stepRepo.GetByTask(r.Context(), taskID)
 
 	resp := struct {
-		Task *domain.Task `json:"task"`
+		Task  *domain.Task  `json:"task"`
 		Steps []domain.Step `json:"steps"`
-	} {
-		Task: task, 
+	}{
+		Task:  task,
 		Steps: steps,
 	}
 
@@ -119,6 +151,11 @@ func (h *Handler) handleTaskByID(
 	path := strings.TrimPrefix(r.URL.Path, "/tasks/")
 	parts := strings.Split(path, "/")
 
+	if len(parts) == 1 {
+		h.getTask(w, r)
+		return
+	}
+
 	if len(parts) != 2 {
 		http.NotFound(w, r)
 		return
@@ -135,6 +172,16 @@ func (h *Handler) handleTaskByID(
 	switch action {
 	case "cancel":
 		h.handleCancelTask(w, r, taskID)
+	case "retry":
+		h.handleRetryTask(w, r, taskID)
+	case "rerun":
+		h.handleRerunTask(w, r, taskID)
+	case "pause":
+		h.handlePauseTask(w, r, taskID)
+	case "resume":
+		h.handleResumeTask(w, r, taskID)
+	case "events":
+		h.streamTaskEvents(w, r, taskID)
 	default:
 		http.NotFound(w, r)
 	}
@@ -163,22 +210,131 @@ func (h *Handler) handleCancelTask(
 	})
 }
 
-func (h *Handler) cancelTask(
+func (h *Handler) handlePauseTask(
 	w http.ResponseWriter,
 	r *http.Request,
+	taskID uuid.UUID,
 ) {
-	id := chi.URLParam(r, "id")
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
 
-	taskID, err := uuid.Parse(id)
+	err := h.engine.PauseTask(r.Context(), taskID)
+	switch {
+	case errors.Is(err, engine.ErrTaskNotPausable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status": string(domain.TaskPaused),
+	})
+}
+
+func (h *Handler) handleResumeTask(
+	w http.ResponseWriter,
+	r *http.Request,
+	taskID uuid.UUID,
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	err := h.engine.ResumeTask(r.Context(), taskID)
+	switch {
+	case errors.Is(err, engine.ErrTaskNotPaused):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"status": string(domain.TaskRunning),
+	})
+}
+
+func (h *Handler) handleRetryTask(
+	w http.ResponseWriter,
+	r *http.Request,
+	taskID uuid.UUID,
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	reset, err := h.engine.RetryTask(r.Context(), taskID)
+	h.restarted(w, r, taskID, reset, err)
+}
+
+func (h *Handler) handleRerunTask(
+	w http.ResponseWriter,
+	r *http.Request,
+	taskID uuid.UUID,
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	from, err := uuid.Parse(r.URL.Query().Get("from"))
 	if err != nil {
-		http.Error(w, "invalid id", 400)
+		http.Error(w, "invalid from step id", http.StatusBadRequest)
 		return
 	}
 
-	if err := h.engine.CancelTask(r.Context(), taskID); err != nil {
-		http.Error(w, err.Error(), 500)
+	reset, err := h.engine.RerunFrom(r.Context(), taskID, from)
+	h.restarted(w, r, taskID, reset, err)
+}
+
+// restarted answers a retry or rerun request and, on success, starts a new
+// loop for the task that was put back to RUNNING.
+func (h *Handler) restarted(
+	w http.ResponseWriter,
+	r *http.Request,
+	taskID uuid.UUID,
+	reset []uuid.UUID,
+	err error,
+) {
+	switch {
+	case errors.Is(err, engine.ErrTaskNotRevivable):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	go h.engine.RunTaskLoop(context.WithoutCancel(r.Context()), taskID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":      string(domain.TaskRunning),
+		"reset_steps": reset,
+	})
+}
+
+func (h *Handler) listWorkers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	workers, err := h.workerRepo.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(workers)
 }
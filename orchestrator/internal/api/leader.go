@@ -0,0 +1,49 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/yeOmaNnn/orchestrator/internal/leader"
+)
+
+// LeaderStatus serves the current leader of the singleton loops.
+type LeaderStatus struct {
+	elector *leader.Elector
+}
+
+func NewLeaderStatus(elector *leader.Elector) *LeaderStatus {
+	return &LeaderStatus{elector: elector}
+}
+
+func (l *LeaderStatus) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/admin/leader", l.getLeader)
+}
+
+func (l *LeaderStatus) getLeader(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	info, ok, err := l.elector.Current(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp := struct {
+		Leader   *leader.Info `json:"leader"`
+		Self     string       `json:"self"`
+		IsLeader bool         `json:"is_leader"`
+	}{
+		Self:     l.elector.ID(),
+		IsLeader: l.elector.IsLeader(),
+	}
+	if ok {
+		resp.Leader = &info
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Probes serves liveness and readiness endpoints. A process is live as long
+// as it answers; it is ready only between startup and the start of a drain.
+type Probes struct {
+	ready atomic.Bool
+}
+
+func NewProbes() *Probes {
+	return &Probes{}
+}
+
+func (p *Probes) SetReady(ready bool) {
+	p.ready.Store(ready)
+}
+
+func (p *Probes) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/health", p.health)
+	mux.HandleFunc("/ready", p.readiness)
+}
+
+func (p *Probes) health(w http.ResponseWriter, _ *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+func (p *Probes) readiness(w http.ResponseWriter, _ *http.Request) {
+	if !p.ready.Load() {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ready"))
+}
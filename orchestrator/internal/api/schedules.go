@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+	"github.com/yeOmaNnn/orchestrator/internal/engine"
+)
+
+// scheduleRequest is the writable part of a schedule.
+type scheduleRequest struct {
+	Name            string               `json:"name"`
+	Cron            string               `json:"cron"`
+	IntervalSeconds int                  `json:"interval_seconds"`
+	Timezone        string               `json:"timezone"`
+	Goal            string               `json:"goal"`
+	TimeoutSeconds  int                  `json:"timeout_seconds"`
+	Overlap         domain.OverlapPolicy `json:"overlap"`
+	CatchUp         domain.CatchUpPolicy `json:"catch_up"`
+	Paused          bool                 `json:"paused"`
+}
+
+func (r scheduleRequest) schedule() domain.Schedule {
+	return domain.Schedule{
+		Name:            r.Name,
+		Cron:            r.Cron,
+		IntervalSeconds: r.IntervalSeconds,
+		Timezone:        r.Timezone,
+		Goal:            r.Goal,
+		TimeoutSeconds:  r.TimeoutSeconds,
+		Overlap:         r.Overlap,
+		CatchUp:         r.CatchUp,
+		Paused:          r.Paused,
+	}
+}
+
+func (h *Handler) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := h.schedules.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, schedules)
+
+	case http.MethodPost:
+		var req scheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s := req.schedule()
+		if err := h.engine.CreateSchedule(r.Context(), &s); err != nil {
+			writeScheduleError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, s)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) handleScheduleByID(
+	w http.ResponseWriter,
+	r *http.Request,
+) {
+	path := strings.TrimPrefix(r.URL.Path, "/schedules/")
+	parts := strings.Split(path, "/")
+
+	id, err := uuid.Parse(parts[0])
+	if err != nil {
+		http.Error(w, "invalid schedule id", http.StatusBadRequest)
+		return
+	}
+
+	if len(parts) == 1 {
+		h.handleSchedule(w, r, id)
+		return
+	}
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch parts[1] {
+	case "pause":
+		h.scheduleAction(w, r, id, h.engine.PauseSchedule)
+	case "resume":
+		h.scheduleAction(w, r, id, h.engine.ResumeSchedule)
+	case "tasks":
+		h.listScheduleTasks(w, r, id)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (h *Handler) handleSchedule(
+	w http.ResponseWriter,
+	r *http.Request,
+	id uuid.UUID,
+) {
+	switch r.Method {
+	case http.MethodGet:
+		s, err := h.schedules.GetByID(r.Context(), id)
+		if err != nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+
+	case http.MethodPut:
+		var req scheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		s, err := h.engine.UpdateSchedule(r.Context(), id, req.schedule())
+		if err != nil {
+			writeScheduleError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, s)
+
+	case http.MethodDelete:
+		if err := h.engine.DeleteSchedule(r.Context(), id); err != nil {
+			writeScheduleError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *Handler) scheduleAction(
+	w http.ResponseWriter,
+	r *http.Request,
+	id uuid.UUID,
+	action func(ctx context.Context, id uuid.UUID) (*domain.Schedule, error),
+) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s, err := action(r.Context(), id)
+	if err != nil {
+		writeScheduleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// listScheduleTasks returns the tasks a schedule spawned, newest first.
+func (h *Handler) listScheduleTasks(
+	w http.ResponseWriter,
+	r *http.Request,
+	id uuid.UUID,
+) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	tasks, err := h.taskRepo.ListBySchedule(r.Context(), id, limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, tasks)
+}
+
+func writeScheduleError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, engine.ErrSchedulesDisabled):
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+	case errors.Is(err, engine.ErrScheduleConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/catalog"
+	"github.com/yeOmaNnn/orchestrator/internal/storage"
+)
+
+// maxWASMModule bounds the size of an uploaded module.
+const maxWASMModule = 32 << 20
+
+// WASMModules stores the WebAssembly modules that wasm agents of the
+// catalog run by digest.
+type WASMModules struct {
+	modules storage.WASMModuleRepository
+	catalog *catalog.Catalog
+}
+
+func NewWASMModules(modules storage.WASMModuleRepository, catalog *catalog.Catalog) *WASMModules {
+	return &WASMModules{modules: modules, catalog: catalog}
+}
+
+func (m *WASMModules) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/wasm-modules", m.handleModules)
+	mux.HandleFunc("/wasm-modules/", m.handleModuleByDigest)
+}
+
+// handleModules lists the uploaded modules (GET) or uploads one (POST with
+// the module as the raw body). Uploading a module that is already stored
+// returns it as it was first stored.
+func (m *WASMModules) handleModules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		modules, err := m.modules.List(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, modules)
+
+	case http.MethodPost:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWASMModule))
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := agent.ValidateWASMModule(data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		module := agent.WASMModule{
+			Digest: agent.ModuleDigest(data),
+			Size:   int64(len(data)),
+		}
+		if err := m.modules.Put(r.Context(), &module, data); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, module)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// handleModuleByDigest deletes the module named in the path (DELETE). A
+// module that a catalog agent runs cannot be deleted.
+func (m *WASMModules) handleModuleByDigest(w http.ResponseWriter, r *http.Request) {
+	digest := strings.TrimPrefix(r.URL.Path, "/wasm-modules/")
+	if digest == "" || strings.Contains(digest, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	configs, err := m.catalog.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, cfg := range configs {
+		if cfg.WASM != nil && cfg.WASM.Module == digest {
+			http.Error(w, "wasm module is run by agent "+cfg.Name, http.StatusConflict)
+			return
+		}
+	}
+
+	ok, err := m.modules.Delete(r.Context(), digest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		http.Error(w, agent.ErrModuleNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,29 @@
+package app
+
+import (
+	"crypto/rand"
+	"errors"
+	"log"
+
+	"github.com/yeOmaNnn/orchestrator/internal/callback"
+)
+
+// CallbackSigner returns the signer of the callback URLs handed to agents
+// that run steps as asynchronous jobs, or nil when baseURL is empty and
+// jobs can only be polled. Every process sharing storage must use the same
+// secret; a process on its own storage may do without and get a random one.
+func CallbackSigner(baseURL, secret string, shared bool) (*callback.Signer, error) {
+	if baseURL == "" {
+		return nil, nil
+	}
+	key := []byte(secret)
+	if secret == "" {
+		if shared {
+			return nil, errors.New("callbacks need -callback-secret when storage is shared")
+		}
+		key = make([]byte, 32)
+		rand.Read(key)
+		log.Printf("callbacks: no secret set; callback URLs are only valid until restart")
+	}
+	return callback.NewSigner(baseURL, key)
+}
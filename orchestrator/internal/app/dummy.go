@@ -0,0 +1,78 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/agent/builtin"
+	"github.com/yeOmaNnn/orchestrator/internal/planner"
+)
+
+type DummyPlanner struct{}
+
+func (p *DummyPlanner) Plan(
+	ctx context.Context,
+	req planner.PlanRequest,
+) (planner.PlanResponse, error) {
+
+	inputBytes, err := json.Marshal(map[string]any{
+		"text": "hello",
+	})
+	if err != nil {
+		return planner.PlanResponse{}, err
+	}
+
+	step := planner.PlannedStep{
+		ID:        uuid.New(),
+		Agent:     "agent1",
+		Input:     inputBytes,
+		DependsOn: []uuid.UUID{},
+	}
+
+	return planner.PlanResponse{
+		Steps: []planner.PlannedStep{step},
+	}, nil
+}
+
+type DummyAgent struct{}
+
+func (a *DummyAgent) Name() string {
+	return "agent1"
+}
+
+func (a *DummyAgent) Metadata() agent.Metadata {
+	return agent.Metadata{
+		Description: "Answers every call with ok.",
+		InputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {"text": {"type": "string"}}
+		}`),
+		OutputSchema: json.RawMessage(`{
+			"type": "object",
+			"properties": {"result": {"type": "string"}},
+			"required": ["result"]
+		}`),
+		Tags:    []string{"test"},
+		Version: "1",
+	}
+}
+
+func (a *DummyAgent) Call(
+	ctx context.Context,
+	input json.RawMessage,
+) (json.RawMessage, error) {
+
+	return json.RawMessage(`{"result":"ok"}`), nil
+}
+
+// NewRegistry returns the agent registry every process starts with: the
+// built-in agents and the dummy agent.
+func NewRegistry(opts ...builtin.Option) *agent.Registry {
+	registry := agent.NewRegistry()
+	builtin.Register(registry, opts...)
+	registry.Register(&DummyAgent{})
+	return registry
+}
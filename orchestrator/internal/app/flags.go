@@ -0,0 +1,58 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/yeOmaNnn/orchestrator/internal/retry"
+)
+
+// SplitList splits a comma-separated flag value, dropping empty entries.
+func SplitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// ParseAgentLabels parses "agent=label,label;other=label" into the labels
+// each agent requires of its worker.
+func ParseAgentLabels(s string) (map[string][]string, error) {
+	out := make(map[string][]string)
+	for _, entry := range strings.Split(s, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, labels, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid agent labels %q: want agent=label,label", entry)
+		}
+		out[strings.TrimSpace(name)] = SplitList(labels)
+	}
+	return out, nil
+}
+
+// ParseRetryPolicies parses a JSON object mapping agent names to retry
+// policies, e.g. {"agent1":{"strategy":"fixed","max_attempts":5,"initial_delay_seconds":1}}.
+func ParseRetryPolicies(s string) (map[string]retry.Policy, error) {
+	out := make(map[string]retry.Policy)
+	if strings.TrimSpace(s) == "" {
+		return out, nil
+	}
+
+	if err := json.Unmarshal([]byte(s), &out); err != nil {
+		return nil, fmt.Errorf("invalid retry policies: %w", err)
+	}
+	for agent, p := range out {
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("retry policy for %s: %w", agent, err)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,39 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/events"
+)
+
+// publishTimeout bounds publishing one health transition.
+const publishTimeout = 5 * time.Second
+
+// PublishHealth publishes the health transitions of checker on bus as
+// AgentHealth events, where GET /agent-events streams them.
+func PublishHealth(checker *agent.HealthChecker, bus events.Bus) {
+	checker.Subscribe(func(e agent.HealthEvent) {
+		healthy := e.Healthy
+		event := events.Event{
+			Type:    events.AgentHealth,
+			TaskID:  events.AgentTopic,
+			Agent:   e.Agent,
+			Healthy: &healthy,
+			Error:   e.Error,
+			At:      e.At,
+		}
+		// Subscribers must not block the checker; publishing may go to
+		// the database.
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+			defer cancel()
+
+			if err := bus.Publish(ctx, event); err != nil {
+				log.Printf("publish health of agent %s: %v", e.Agent, err)
+			}
+		}()
+	})
+}
@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// StartAgentHosts registers the agents of every agent host in urls and
+// keeps them in sync until ctx is done. A host that is down at startup is
+// picked up by a later sync.
+func StartAgentHosts(
+	ctx context.Context,
+	registry *agent.Registry,
+	urls []string,
+	interval time.Duration,
+) {
+	for _, u := range urls {
+		host := agent.NewAgentHost(u, registry)
+		if err := host.Sync(ctx); err != nil {
+			log.Print(err)
+		}
+		go host.Run(ctx, interval)
+	}
+}
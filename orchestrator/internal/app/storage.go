@@ -0,0 +1,115 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/yeOmaNnn/orchestrator/internal/events"
+	"github.com/yeOmaNnn/orchestrator/internal/leader"
+	"github.com/yeOmaNnn/orchestrator/internal/storage"
+	"github.com/yeOmaNnn/orchestrator/internal/storage/memory"
+	"github.com/yeOmaNnn/orchestrator/internal/storage/postgres"
+)
+
+// Storage bundles the repositories shared by the API and worker processes.
+type Storage struct {
+	Tasks   storage.TaskRepository
+	Steps   storage.StepRepository
+	Workers storage.WorkerRepository
+
+	DeadLetters storage.DeadLetterRepository
+	AgentPauses storage.AgentPauseRepository
+	Schedules   storage.ScheduleRepository
+
+	AgentCatalog storage.AgentCatalogRepository
+	Modules      storage.WASMModuleRepository
+
+	// Events delivers task events to their subscribers; on shared storage
+	// across processes, once ListenEvents runs.
+	Events events.Bus
+
+	// Shared reports whether the repositories are visible to other
+	// processes. In-memory storage is private to this process.
+	Shared bool
+
+	db          *sql.DB
+	leaderLocks *memory.LeaderLocks
+}
+
+// OpenStorage connects to Postgres when dsn is set and falls back to
+// in-memory repositories otherwise.
+func OpenStorage(dsn string) (*Storage, error) {
+	if dsn == "" {
+		return &Storage{
+			Tasks:   memory.NewTaskRepo(),
+			Steps:   memory.NewStepRepo(),
+			Workers: memory.NewWorkerRepo(),
+
+			DeadLetters: memory.NewDeadLetterRepo(),
+			AgentPauses: memory.NewAgentPauseRepo(),
+			Schedules:   memory.NewScheduleRepo(),
+
+			AgentCatalog: memory.NewAgentCatalogRepo(),
+			Modules:      memory.NewWASMModuleRepo(),
+
+			Events: events.NewMemoryBus(),
+
+			leaderLocks: memory.NewLeaderLocks(),
+		}, nil
+	}
+
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("ping database: %w", err)
+	}
+
+	return &Storage{
+		Tasks:   postgres.NewTaskRepo(db),
+		Steps:   postgres.NewStepRepo(db),
+		Workers: postgres.NewWorkerRepo(db),
+
+		DeadLetters: postgres.NewDeadLetterRepo(db),
+		AgentPauses: postgres.NewAgentPauseRepo(db),
+		Schedules:   postgres.NewScheduleRepo(db),
+
+		AgentCatalog: postgres.NewAgentCatalogRepo(db),
+		Modules:      postgres.NewWASMModuleRepo(db),
+
+		Events: postgres.NewEventBus(db),
+
+		Shared: true,
+		db:     db,
+	}, nil
+}
+
+// LeaderBackend returns the lock holder campaigns on for the leadership
+// called name: a Postgres advisory lock on shared storage, an in-process
+// lock otherwise.
+func (s *Storage) LeaderBackend(name, holder string) leader.Backend {
+	if s.db != nil {
+		return postgres.NewLeaderLock(s.db, name, holder)
+	}
+	return s.leaderLocks.Backend(name, holder)
+}
+
+// ListenEvents receives the events published by other processes until
+// ctx is done. Processes that serve event subscribers run it.
+func (s *Storage) ListenEvents(ctx context.Context) {
+	if bus, ok := s.Events.(*postgres.EventBus); ok {
+		bus.Listen(ctx)
+	}
+}
+
+func (s *Storage) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
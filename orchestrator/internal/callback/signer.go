@@ -0,0 +1,71 @@
+// Package callback signs the URLs agents report the results of
+// asynchronous jobs to, so that only the agent a step was handed to can
+// settle it.
+package callback
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Path is where the API serves callbacks; the step ID follows it.
+const Path = "/callbacks/"
+
+// Signer makes and checks callback URLs. A URL is bound to one attempt of
+// one step: it cannot settle another step, nor a later attempt of the same
+// one.
+type Signer struct {
+	baseURL string
+	secret  []byte
+}
+
+// NewSigner returns a Signer for callbacks to the API at baseURL, e.g.
+// https://orchestrator.example.com.
+func NewSigner(baseURL string, secret []byte) (*Signer, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("callback url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("callback url %q must be an absolute http(s) URL", baseURL)
+	}
+	if len(secret) < 16 {
+		return nil, errors.New("callback secret must be at least 16 bytes")
+	}
+	return &Signer{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		secret:  secret,
+	}, nil
+}
+
+// URL returns the callback URL for attempt of the step.
+func (s *Signer) URL(stepID uuid.UUID, attempt int) string {
+	q := url.Values{}
+	q.Set("attempt", strconv.Itoa(attempt))
+	q.Set("sig", s.sign(stepID, attempt))
+	return s.baseURL + Path + stepID.String() + "?" + q.Encode()
+}
+
+// Verify reports whether sig is the signature of attempt of the step.
+func (s *Signer) Verify(stepID uuid.UUID, attempt int, sig string) bool {
+	got, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	want, _ := hex.DecodeString(s.sign(stepID, attempt))
+	return hmac.Equal(got, want)
+}
+
+func (s *Signer) sign(stepID uuid.UUID, attempt int) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s:%d", stepID, attempt)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,373 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/storage"
+)
+
+var (
+	ErrAgentExists   = errors.New("agent is already registered")
+	ErrInvalidConfig = errors.New("invalid agent config")
+
+	// ErrExecNotAllowed rejects an exec agent whose command is outside the
+	// directories exec agents may run from.
+	ErrExecNotAllowed = errors.New("exec agent command is not allowed")
+)
+
+// Catalog registers agents at runtime. Configs are persisted in
+// storage, which is the source of truth: every instance loads them into
+// its registry on startup and picks up changes made elsewhere on each
+// Sync.
+type Catalog struct {
+	repo     storage.AgentCatalogRepository
+	registry *agent.Registry
+	execDirs []string
+	modules  storage.WASMModuleRepository
+
+	mu sync.Mutex
+	// applied holds the UpdatedAt of every catalog agent in the registry.
+	applied map[string]time.Time
+}
+
+type Option func(*Catalog)
+
+// WithRegistry keeps registry in step with the catalog. Processes that do
+// not call agents only edit the catalog and need no registry.
+func WithRegistry(registry *agent.Registry) Option {
+	return func(c *Catalog) {
+		c.registry = registry
+	}
+}
+
+// WithExecDirs lets exec agents run programs in dirs. Without it no exec
+// agent can be registered: a config is enough to run any program on every
+// worker.
+func WithExecDirs(dirs ...string) Option {
+	return func(c *Catalog) {
+		c.execDirs = append(c.execDirs, dirs...)
+	}
+}
+
+// WithWASMModules resolves the uploaded modules that wasm agents name by
+// digest. Without it only wasm agents of a module file can be registered.
+func WithWASMModules(modules storage.WASMModuleRepository) Option {
+	return func(c *Catalog) {
+		c.modules = modules
+	}
+}
+
+func New(repo storage.AgentCatalogRepository, opts ...Option) *Catalog {
+	c := &Catalog{
+		repo:    repo,
+		applied: make(map[string]time.Time),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Register adds a new agent. A catalog agent replaces any agent of the same
+// name that the process registered itself.
+func (c *Catalog) Register(ctx context.Context, cfg *agent.Config) error {
+	if err := c.validate(ctx, cfg); err != nil {
+		return err
+	}
+
+	ok, err := c.repo.Create(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("agent %s: %w", cfg.Name, ErrAgentExists)
+	}
+
+	c.apply(ctx, *cfg)
+	return nil
+}
+
+// Update replaces the config of a registered agent.
+func (c *Catalog) Update(ctx context.Context, cfg *agent.Config) error {
+	if err := c.validate(ctx, cfg); err != nil {
+		return err
+	}
+
+	ok, err := c.repo.Update(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("agent %s: %w", cfg.Name, agent.ErrAgentNotFound)
+	}
+
+	c.apply(ctx, *cfg)
+	return nil
+}
+
+// Deregister removes an agent. Steps already planned for it fail until it
+// is registered again.
+func (c *Catalog) Deregister(ctx context.Context, name string) error {
+	ok, err := c.repo.Delete(ctx, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("agent %s: %w", name, agent.ErrAgentNotFound)
+	}
+
+	c.remove(name)
+	return nil
+}
+
+func (c *Catalog) Get(ctx context.Context, name string) (*agent.Config, error) {
+	return c.repo.Get(ctx, name)
+}
+
+func (c *Catalog) List(ctx context.Context) ([]agent.Config, error) {
+	return c.repo.List(ctx)
+}
+
+// Sync brings the registry in line with storage: agents added or changed
+// since the last sync are (re)built and agents deleted are removed.
+func (c *Catalog) Sync(ctx context.Context) error {
+	if c.registry == nil {
+		return nil
+	}
+
+	configs, err := c.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(configs))
+	for _, cfg := range configs {
+		seen[cfg.Name] = true
+
+		c.mu.Lock()
+		applied, ok := c.applied[cfg.Name]
+		c.mu.Unlock()
+		if ok && applied.Equal(cfg.UpdatedAt) {
+			continue
+		}
+		c.apply(ctx, cfg)
+	}
+
+	c.mu.Lock()
+	var gone []string
+	for name := range c.applied {
+		if !seen[name] {
+			gone = append(gone, name)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, name := range gone {
+		c.remove(name)
+	}
+	return nil
+}
+
+// Run syncs the registry every interval until ctx is done.
+func (c *Catalog) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Sync(ctx); err != nil {
+				log.Printf("catalog: sync: %v", err)
+			}
+		}
+	}
+}
+
+func (c *Catalog) apply(ctx context.Context, cfg agent.Config) {
+	if c.registry == nil {
+		return
+	}
+
+	if err := c.allowExec(&cfg); err != nil {
+		log.Printf("catalog: agent %s: %v", cfg.Name, err)
+		return
+	}
+	client, err := c.build(ctx, cfg)
+	if err != nil {
+		// Stored configs were validated on write; this one was written by
+		// a newer version with stricter rules or by hand.
+		log.Printf("catalog: agent %s: %v", cfg.Name, err)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, known := c.applied[cfg.Name]
+	retire(c.registry.Replace(client))
+	c.applied[cfg.Name] = cfg.UpdatedAt
+
+	location := cfg.BaseURL
+	switch {
+	case cfg.Exec != nil:
+		location = cfg.Exec.Command[0]
+	case cfg.WASM != nil && cfg.WASM.Path != "":
+		location = cfg.WASM.Path
+	case cfg.WASM != nil:
+		location = cfg.WASM.Module
+	}
+	if known {
+		log.Printf("catalog: agent %s updated", cfg.Name)
+	} else {
+		log.Printf("catalog: agent %s registered at %s", cfg.Name, location)
+	}
+}
+
+// build creates the client of cfg, loading the module of a wasm agent
+// that names an uploaded one.
+func (c *Catalog) build(ctx context.Context, cfg agent.Config) (agent.Client, error) {
+	if cfg.WASM == nil || cfg.WASM.Module == "" {
+		return agent.NewFromConfig(cfg, agent.OnRollback(c.recordRollback))
+	}
+	if c.modules == nil {
+		return nil, fmt.Errorf("wasm module %s: %w", cfg.WASM.Module, agent.ErrModuleNotFound)
+	}
+
+	module, err := c.modules.Get(ctx, cfg.WASM.Module)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.WASMAgent(module)
+}
+
+// recordRollback persists a canary rolled back by one instance, so that
+// every instance stops routing to it.
+func (c *Catalog) recordRollback(rb agent.Rollback) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cfg, err := c.repo.Get(ctx, rb.Agent)
+	if err != nil {
+		log.Printf("catalog: agent %s: record rollback: %v", rb.Agent, err)
+		return
+	}
+	if cfg.Rollout == nil || cfg.Rollout.Canary != rb.Version || cfg.Rollout.RolledBack == rb.Version {
+		// Already recorded, or the rollout changed meanwhile.
+		return
+	}
+
+	cfg.Rollout.RolledBack = rb.Version
+	if err := c.Update(ctx, cfg); err != nil {
+		log.Printf("catalog: agent %s: record rollback: %v", rb.Agent, err)
+	}
+}
+
+func (c *Catalog) remove(name string) {
+	if c.registry == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.applied[name]; !ok {
+		return
+	}
+	retire(c.registry.Remove(name))
+	delete(c.applied, name)
+	log.Printf("catalog: agent %s deregistered", name)
+}
+
+// retire closes a client dropped from the registry, such as the gRPC
+// connection or WASM runtime it holds. Calls already in progress on it
+// finish first, so it is closed in the background.
+func retire(client agent.Client) {
+	closer, ok := client.(io.Closer)
+	if !ok {
+		return
+	}
+	go func() {
+		if err := closer.Close(); err != nil {
+			log.Printf("catalog: agent %s: close: %v", client.Name(), err)
+		}
+	}()
+}
+
+func (c *Catalog) validate(ctx context.Context, cfg *agent.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	if err := c.allowExec(cfg); err != nil {
+		return err
+	}
+	return c.checkModule(ctx, cfg)
+}
+
+// checkModule checks that the module a wasm agent runs is there: uploaded,
+// or a file this process can compile. Workers resolve the module again
+// when they build the agent.
+func (c *Catalog) checkModule(ctx context.Context, cfg *agent.Config) error {
+	if cfg.WASM == nil {
+		return nil
+	}
+
+	var (
+		module []byte
+		err    error
+	)
+	switch {
+	case cfg.WASM.Module == "":
+		module, err = os.ReadFile(cfg.WASM.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// The file need only be present on the workers.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	case c.modules == nil:
+		err = agent.ErrModuleNotFound
+	default:
+		module, err = c.modules.Get(ctx, cfg.WASM.Module)
+	}
+	if errors.Is(err, agent.ErrModuleNotFound) {
+		return fmt.Errorf("%w: wasm module %s was not uploaded", ErrInvalidConfig, cfg.WASM.Module)
+	}
+	if err != nil {
+		return err
+	}
+	if err := agent.ValidateWASMModule(module); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return nil
+}
+
+// allowExec checks that an exec agent's program lies in one of the exec
+// directories.
+func (c *Catalog) allowExec(cfg *agent.Config) error {
+	if cfg.Exec == nil || len(cfg.Exec.Command) == 0 {
+		return nil
+	}
+	program := filepath.Clean(cfg.Exec.Command[0])
+	for _, dir := range c.execDirs {
+		rel, err := filepath.Rel(filepath.Clean(dir), program)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrExecNotAllowed, program)
+}
@@ -0,0 +1,11 @@
+package domain
+
+import "time"
+
+// AgentPause stops dispatch of every step for Agent, across all tasks, until
+// it is lifted. It is meant for incidents where an agent misbehaves.
+type AgentPause struct {
+	Agent    string    `json:"agent"`
+	Reason   string    `json:"reason"`
+	PausedAt time.Time `json:"paused_at"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attempt records one call of a step's agent.
+type Attempt struct {
+	Number     int       `json:"number"`
+	WorkerID   string    `json:"worker_id,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Error      string    `json:"error,omitempty"`
+	// Version is the version of the agent that served the attempt, when
+	// the agent has one.
+	Version string `json:"version,omitempty"`
+	// JobID is the asynchronous job the agent ran the attempt as.
+	JobID string `json:"job_id,omitempty"`
+	// Stderr is the end of what an exec agent wrote to stderr.
+	Stderr string `json:"stderr,omitempty"`
+}
+
+// DeadLetter captures a step that exhausted its retries, with everything
+// needed to inspect and requeue it.
+type DeadLetter struct {
+	ID         uuid.UUID       `json:"id"`
+	StepID     uuid.UUID       `json:"step_id"`
+	TaskID     uuid.UUID       `json:"task_id"`
+	Agent      string          `json:"agent"`
+	Input      json.RawMessage `json:"input"`
+	Attempts   []Attempt       `json:"attempts"`
+	LastError  string          `json:"last_error"`
+	CreatedAt  time.Time       `json:"created_at"`
+	RequeuedAt *time.Time      `json:"requeued_at"`
+}
+
+func NewDeadLetter(step Step) *DeadLetter {
+	return &DeadLetter{
+		ID:        uuid.New(),
+		StepID:    step.ID,
+		TaskID:    step.TaskID,
+		Agent:     step.Agent,
+		Input:     step.Input,
+		Attempts:  step.History,
+		LastError: step.LastError,
+		CreatedAt: time.Now(),
+	}
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+// OverlapPolicy decides what a schedule does when it fires while a task it
+// spawned earlier is still active.
+type OverlapPolicy string
+
+const (
+	// OverlapSkip drops the run.
+	OverlapSkip OverlapPolicy = "skip"
+	// OverlapQueue holds the run until the active task finishes.
+	OverlapQueue OverlapPolicy = "queue"
+	// OverlapCancel cancels the active task and starts a new one.
+	OverlapCancel OverlapPolicy = "cancel"
+)
+
+// CatchUpPolicy decides what a schedule does with runs it missed while no
+// orchestrator was running.
+type CatchUpPolicy string
+
+const (
+	// CatchUpNone drops missed runs. A run that is only slightly late
+	// still happens.
+	CatchUpNone CatchUpPolicy = "none"
+	// CatchUpOnce runs once for any number of missed runs.
+	CatchUpOnce CatchUpPolicy = "once"
+	// CatchUpAll runs every missed run, up to MaxCatchUpRuns.
+	CatchUpAll CatchUpPolicy = "all"
+)
+
+// MaxCatchUpRuns bounds how many missed or queued runs a schedule keeps.
+const MaxCatchUpRuns = 100
+
+// Schedule creates a task for Goal on a cron expression or a fixed
+// interval. Cron expressions use the standard five fields and are read in
+// Timezone.
+type Schedule struct {
+	ID              uuid.UUID     `json:"id"`
+	Name            string        `json:"name"`
+	Cron            string        `json:"cron,omitempty"`
+	IntervalSeconds int           `json:"interval_seconds,omitempty"`
+	Timezone        string        `json:"timezone"`
+	Goal            string        `json:"goal"`
+	TimeoutSeconds  int           `json:"timeout_seconds,omitempty"`
+	Overlap         OverlapPolicy `json:"overlap"`
+	CatchUp         CatchUpPolicy `json:"catch_up"`
+	Paused          bool          `json:"paused"`
+	NextRunAt       time.Time     `json:"next_run_at"`
+	LastRunAt       *time.Time    `json:"last_run_at"`
+	QueuedRuns      int           `json:"queued_runs"`
+	CreatedAt       time.Time     `json:"created_at"`
+	UpdatedAt       time.Time     `json:"updated_at"`
+
+	// Version changes on every write, so that concurrent tickers can
+	// claim a run without firing it twice.
+	Version int `json:"-"`
+}
+
+// Validate checks the schedule and fills in default policies.
+func (s *Schedule) Validate() error {
+	if s.Goal == "" {
+		return errors.New("goal is required")
+	}
+	if (s.Cron == "") == (s.IntervalSeconds <= 0) {
+		return errors.New("exactly one of cron and interval_seconds is required")
+	}
+	if s.Timezone == "" {
+		s.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("timezone: %w", err)
+	}
+	if s.Cron != "" {
+		if _, err := cron.ParseStandard(s.Cron); err != nil {
+			return fmt.Errorf("cron: %w", err)
+		}
+	}
+
+	switch s.Overlap {
+	case "":
+		s.Overlap = OverlapSkip
+	case OverlapSkip, OverlapQueue, OverlapCancel:
+	default:
+		return fmt.Errorf("unknown overlap policy %q", s.Overlap)
+	}
+
+	switch s.CatchUp {
+	case "":
+		s.CatchUp = CatchUpNone
+	case CatchUpNone, CatchUpOnce, CatchUpAll:
+	default:
+		return fmt.Errorf("unknown catch-up policy %q", s.CatchUp)
+	}
+	return nil
+}
+
+// Next returns the first run strictly after t.
+func (s *Schedule) Next(t time.Time) (time.Time, error) {
+	if s.IntervalSeconds > 0 {
+		return t.Add(time.Duration(s.IntervalSeconds) * time.Second), nil
+	}
+
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+	sched, err := cron.ParseStandard(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return sched.Next(t.In(loc)).UTC(), nil
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/retry"
 )
 
 type StepStatus string
@@ -13,32 +15,75 @@ const (
 	StepWaiting    StepStatus = "WAITING"
 	StepInProgress StepStatus = "IN_PROGRESS"
 	StepDone       StepStatus = "DONE"
-	StepFailed     StepStatus = "FAILED"  
+	StepFailed     StepStatus = "FAILED"
 	StepError      StepStatus = "ERROR"
 	StepCancelled  StepStatus = "CANCELLED"
+
+	// StepUnschedulable marks a ready step that no live worker has been
+	// able to serve for too long. It returns to StepWaiting as soon as an
+	// eligible worker shows up.
+	StepUnschedulable StepStatus = "UNSCHEDULABLE"
+
+	// StepAwaitingCallback marks a step whose agent accepted the call as
+	// an asynchronous job. No worker holds it while the job runs; the
+	// agent's callback or a status poll settles it.
+	StepAwaitingCallback StepStatus = "AWAITING_CALLBACK"
 )
 
 type Step struct {
-	ID             uuid.UUID      `json:"id"`
-	TaskID         uuid.UUID      `json:"task_id"`
-	Agent          string         `json:"agent"`
+	ID             uuid.UUID       `json:"id"`
+	TaskID         uuid.UUID       `json:"task_id"`
+	Agent          string          `json:"agent"`
 	Input          json.RawMessage `json:"input"`
 	Output         json.RawMessage `json:"output"`
-	Status         StepStatus     `json:"status"`
-	RetryCount     int            `json:"retry_count"`
-	MaxRetries     int            `json:"max_retries"`
-	DependsOn      []uuid.UUID    `json:"depends_on"`
-	Attempt        int            `json:"attempt"`
-	MaxAttempts    int            `json:"max_attempts"`
-	NextRunAt      *time.Time     `json:"next_run_at"`
-	LastError      string         `json:"last_error"`
-	TimeoutSeconds int            `json:"timeout_seconds"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	LockedAt       *time.Time     `json:"locked_at"`    
-	LockedBy       *string        `json:"locked_by"`    
-	StartedAt      *time.Time     `json:"started_at"`   
-	FinishedAt     *time.Time     `json:"finished_at"`  
+	Status         StepStatus      `json:"status"`
+	RetryCount     int             `json:"retry_count"`
+	DependsOn      []uuid.UUID     `json:"depends_on"`
+	RequiredLabels []string        `json:"required_labels"`
+	Attempt        int             `json:"attempt"`
+	RetryPolicy    *retry.Policy   `json:"retry_policy"`
+	FirstAttemptAt *time.Time      `json:"first_attempt_at"`
+	History        []Attempt       `json:"history"`
+	NextRunAt      *time.Time      `json:"next_run_at"`
+	LastError      string          `json:"last_error"`
+	TimeoutSeconds int             `json:"timeout_seconds"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	LockedAt       *time.Time      `json:"locked_at"`
+	LockedBy       *string         `json:"locked_by"`
+	StartedAt      *time.Time      `json:"started_at"`
+	FinishedAt     *time.Time      `json:"finished_at"`
+
+	// Progress is what a streaming agent last reported about the attempt
+	// in progress, or the last attempt.
+	Progress *StepProgress `json:"progress"`
+
+	// Job is the asynchronous job the step is awaiting, while it is
+	// StepAwaitingCallback.
+	Job *StepJob `json:"job"`
+}
+
+// StepProgress is the progress a streaming agent reports while it works.
+type StepProgress struct {
+	// Percent is how much of the work is done, from 0 to 100.
+	Percent float64 `json:"percent"`
+	Message string  `json:"message,omitempty"`
+	// Partial is the latest partial output.
+	Partial   json.RawMessage `json:"partial,omitempty"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// StepJob is an asynchronous job an agent runs for a step.
+type StepJob struct {
+	ID string `json:"id"`
+	// StatusURL is where the job's status can be polled, if the agent
+	// offers it.
+	StatusURL  string    `json:"status_url,omitempty"`
+	AcceptedAt time.Time `json:"accepted_at"`
+	// Deadline is when the step fails if the job has not reported back.
+	Deadline     time.Time  `json:"deadline"`
+	PollInterval int        `json:"poll_interval_seconds,omitempty"`
+	NextPollAt   *time.Time `json:"next_poll_at,omitempty"`
 }
 
 func NewStep(taskID uuid.UUID, agent string, input json.RawMessage) *Step {
@@ -50,49 +95,69 @@ func NewStep(taskID uuid.UUID, agent string, input json.RawMessage) *Step {
 		Input:          input,
 		Status:         StepWaiting,
 		RetryCount:     0,
-		MaxRetries:     3,                     
 		Attempt:        0,
-		MaxAttempts:    3,                     
-		TimeoutSeconds: 30,                    
+		TimeoutSeconds: 30,
 		CreatedAt:      now,
 		UpdatedAt:      now,
 		DependsOn:      []uuid.UUID{},
 	}
 }
 
-func (s *Step) CanRetry() bool {
-	if s.Status != StepFailed && s.Status != StepError {
-		return false
-	}
-	
-	if s.RetryCount >= s.MaxRetries {
-		return false
-	}
-	
-	if s.Attempt >= s.MaxAttempts {
-		return false
-	}
-	
-	if s.NextRunAt != nil && time.Now().Before(*s.NextRunAt) {
-		return false
+// Policy returns the step's retry policy, or the default one for steps
+// created without a policy.
+func (s *Step) Policy() retry.Policy {
+	if s.RetryPolicy != nil {
+		return *s.RetryPolicy
 	}
-	
-	return true
+	return retry.DefaultPolicy()
 }
 
-func (s *Step) MarkForRetry(delay time.Duration) {
+func (s *Step) MarkForRetry(delay time.Duration, err error) {
 	now := time.Now()
 	nextRun := now.Add(delay)
-	
+
 	s.Status = StepWaiting
 	s.RetryCount++
-	s.Attempt++
+	s.LastError = err.Error()
 	s.NextRunAt = &nextRun
 	s.LockedAt = nil
 	s.LockedBy = nil
 	s.UpdatedAt = now
 }
 
+// Defer puts the step back to WAITING until delay has passed without
+// counting the attempt, for failures that never reached the agent.
+func (s *Step) Defer(delay time.Duration, err error) {
+	now := time.Now()
+	nextRun := now.Add(delay)
+
+	s.Status = StepWaiting
+	s.LastError = err.Error()
+	s.NextRunAt = &nextRun
+	s.StartedAt = nil
+	s.LockedAt = nil
+	s.LockedBy = nil
+	s.UpdatedAt = now
+}
+
+// Reset returns a finished step to WAITING as if it had never run, keeping
+// its attempt history.
+func (s *Step) Reset() {
+	s.Status = StepWaiting
+	s.Output = nil
+	s.RetryCount = 0
+	s.Attempt = 0
+	s.FirstAttemptAt = nil
+	s.NextRunAt = nil
+	s.LastError = ""
+	s.LockedAt = nil
+	s.LockedBy = nil
+	s.StartedAt = nil
+	s.FinishedAt = nil
+	s.Job = nil
+	s.UpdatedAt = time.Now()
+}
+
 func (s *Step) MarkInProgress(workerID string) {
 	now := time.Now()
 	s.Status = StepInProgress
@@ -103,6 +168,16 @@ func (s *Step) MarkInProgress(workerID string) {
 	s.UpdatedAt = now
 }
 
+// MarkAwaiting releases the step while the agent runs job.
+func (s *Step) MarkAwaiting(job StepJob) {
+	now := time.Now()
+	s.Status = StepAwaitingCallback
+	s.Job = &job
+	s.LockedAt = nil
+	s.LockedBy = nil
+	s.UpdatedAt = now
+}
+
 func (s *Step) MarkDone(output json.RawMessage) {
 	now := time.Now()
 	s.Status = StepDone
@@ -121,4 +196,15 @@ func (s *Step) MarkFailed(err error) {
 	s.LockedAt = nil
 	s.LockedBy = nil
 	s.UpdatedAt = now
-}
\ No newline at end of file
+}
+
+// MarkError records a failure the step will not be retried after.
+func (s *Step) MarkError(err error) {
+	now := time.Now()
+	s.Status = StepError
+	s.LastError = err.Error()
+	s.FinishedAt = &now
+	s.LockedAt = nil
+	s.LockedBy = nil
+	s.UpdatedAt = now
+}
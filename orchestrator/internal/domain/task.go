@@ -13,6 +13,14 @@ const (
 	TaskCompleted TaskStatus = "COMPLETED"
 	TaskFailed    TaskStatus = "FAILED"
 	TaskCanceled  TaskStatus = "CANCELED"
+
+	// TaskPaused keeps all task state but stops new steps from being
+	// dispatched. Steps already running finish normally.
+	TaskPaused TaskStatus = "PAUSED"
+
+	// TaskTimedOut marks a task whose deadline passed before it finished.
+	// Its remaining steps are cancelled.
+	TaskTimedOut TaskStatus = "TIMED_OUT"
 )
 
 type Task struct {
@@ -20,4 +28,23 @@ type Task struct {
 	Goal      string
 	Status    TaskStatus
 	CreatedAt time.Time
+
+	// Deadline optionally bounds the whole task, retries and backoff
+	// included.
+	Deadline *time.Time
+
+	// ScheduleID is set on tasks spawned by a schedule.
+	ScheduleID *uuid.UUID
+
+	// Labels tag the task, e.g. to pin its steps to a version of an agent.
+	Labels []string
+}
+
+// Remaining reports how long the task has left before its deadline, and
+// false if it has none.
+func (t Task) Remaining() (time.Duration, bool) {
+	if t.Deadline == nil {
+		return 0, false
+	}
+	return time.Until(*t.Deadline), true
 }
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"slices"
+	"time"
+)
+
+// Worker is a scheduler process that executes steps against shared storage.
+// Capabilities lists the agents the worker can call; an empty list means it
+// serves every agent. Labels advertise what the worker has access to
+// (networks, GPUs, secrets) and are matched against Step.RequiredLabels.
+type Worker struct {
+	ID           string    `json:"id"`
+	Hostname     string    `json:"hostname"`
+	Capabilities []string  `json:"capabilities"`
+	Labels       []string  `json:"labels"`
+	StartedAt    time.Time `json:"started_at"`
+	HeartbeatAt  time.Time `json:"heartbeat_at"`
+}
+
+func (w Worker) CanServe(agent string) bool {
+	return len(w.Capabilities) == 0 || slices.Contains(w.Capabilities, agent)
+}
+
+func (w Worker) HasLabels(labels []string) bool {
+	for _, want := range labels {
+		if !slices.Contains(w.Labels, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// Eligible reports whether the worker may run the step.
+func (w Worker) Eligible(step Step) bool {
+	return w.CanServe(step.Agent) && w.HasLabels(step.RequiredLabels)
+}
+
+func (w Worker) Alive(ttl time.Duration) bool {
+	return time.Since(w.HeartbeatAt) < ttl
+}
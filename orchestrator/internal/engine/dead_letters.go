@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+var (
+	ErrDeadLettersDisabled = errors.New("dead letters are not enabled")
+	ErrAlreadyRequeued     = errors.New("dead letter already requeued")
+	ErrTaskNotRevivable    = errors.New("task cannot be revived")
+)
+
+// RequeueOptions overrides the dead-lettered step before it runs again.
+// Zero values keep the original input and agent.
+type RequeueOptions struct {
+	Input json.RawMessage
+	Agent string
+}
+
+// RequeueDeadLetter puts a dead-lettered step back to WAITING, optionally
+// with edited input or another agent. A task that failed because of the
+// step goes back to RUNNING; revived reports whether that happened, in which
+// case the caller must start a new RunTaskLoop for it. Steps of cancelled
+// and timed-out tasks cannot be requeued.
+func (e *Engine) RequeueDeadLetter(
+	ctx context.Context,
+	id uuid.UUID,
+	opts RequeueOptions,
+) (revived bool, err error) {
+	if e.deadLetters == nil {
+		return false, ErrDeadLettersDisabled
+	}
+
+	dl, err := e.deadLetters.GetByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	if dl.RequeuedAt != nil {
+		return false, ErrAlreadyRequeued
+	}
+
+	task, err := e.taskRepo.GetByID(ctx, dl.TaskID)
+	if err != nil {
+		return false, err
+	}
+	switch task.Status {
+	case domain.TaskCanceled:
+		return false, fmt.Errorf("%w: task is %s", ErrTaskNotRevivable, task.Status)
+	case domain.TaskTimedOut:
+		return false, errTimedOut(task)
+	}
+
+	step, err := e.stepRepo.GetByID(ctx, dl.StepID)
+	if err != nil {
+		return false, err
+	}
+	if step.Status != domain.StepError {
+		return false, fmt.Errorf("step %s is %s, not %s", step.ID, step.Status, domain.StepError)
+	}
+
+	if len(opts.Input) > 0 {
+		step.Input = opts.Input
+	}
+	if opts.Agent != "" && opts.Agent != step.Agent {
+		step.Agent = opts.Agent
+		step.RequiredLabels = mergeLabels(step.RequiredLabels, e.agentLabels[step.Agent])
+	}
+	step.Reset()
+
+	if err := e.stepRepo.Update(ctx, step); err != nil {
+		return false, err
+	}
+	if err := e.deadLetters.MarkRequeued(ctx, id); err != nil {
+		return false, err
+	}
+
+	if task.Status == domain.TaskFailed {
+		if err := e.taskRepo.UpdateStatus(ctx, task.ID, domain.TaskRunning); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
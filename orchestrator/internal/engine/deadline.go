@@ -0,0 +1,159 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+// durationAlpha weighs the latest observed step duration in the per-agent
+// moving average.
+const durationAlpha = 0.3
+
+// durationEstimator predicts how long a step of an agent takes from the
+// steps it has seen complete.
+type durationEstimator struct {
+	mu      sync.Mutex
+	byAgent map[string]time.Duration
+}
+
+func newDurationEstimator() *durationEstimator {
+	return &durationEstimator{
+		byAgent: make(map[string]time.Duration),
+	}
+}
+
+func (d *durationEstimator) observe(agent string, took time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	prev, ok := d.byAgent[agent]
+	if !ok {
+		d.byAgent[agent] = took
+		return
+	}
+	d.byAgent[agent] = time.Duration(durationAlpha*float64(took) + (1-durationAlpha)*float64(prev))
+}
+
+// estimate returns the expected duration of a step of agent, or zero when
+// none has been observed yet.
+func (d *durationEstimator) estimate(agent string) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.byAgent[agent]
+}
+
+// timeOut cancels what is left of a task whose deadline has passed.
+func (e *Engine) timeOut(ctx context.Context, task *domain.Task) error {
+	if err := e.stepRepo.CancelByTask(ctx, task.ID); err != nil {
+		return err
+	}
+
+	ok, err := e.taskRepo.TransitionStatus(ctx, task.ID, task.Status, domain.TaskTimedOut)
+	if err != nil {
+		return err
+	}
+	if ok {
+		log.Printf("engine: task %s timed out at deadline %s", task.ID, task.Deadline.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// slaMonitor follows a task with a deadline and warns once each time its
+// predicted completion moves past the deadline.
+type slaMonitor struct {
+	observed map[uuid.UUID]bool
+	atRisk   bool
+}
+
+func (e *Engine) checkSLA(
+	m *slaMonitor,
+	task *domain.Task,
+	steps []domain.Step,
+) {
+	for _, s := range steps {
+		if s.Status != domain.StepDone || m.observed[s.ID] {
+			continue
+		}
+		m.observed[s.ID] = true
+		if s.StartedAt != nil && s.FinishedAt != nil {
+			e.durations.observe(s.Agent, s.FinishedAt.Sub(*s.StartedAt))
+		}
+	}
+
+	if task.Deadline == nil {
+		return
+	}
+
+	now := time.Now()
+	predicted := now.Add(e.criticalPath(steps, now))
+	atRisk := predicted.After(*task.Deadline)
+	if atRisk && !m.atRisk {
+		log.Printf(
+			"engine: SLA risk: task %s is predicted to finish at %s, after its deadline %s",
+			task.ID, predicted.Format(time.RFC3339), task.Deadline.Format(time.RFC3339),
+		)
+	}
+	m.atRisk = atRisk
+}
+
+// criticalPath predicts how long the unfinished steps will take, following
+// the longest chain of dependencies and honouring pending backoff.
+func (e *Engine) criticalPath(steps []domain.Step, now time.Time) time.Duration {
+	byID := make(map[uuid.UUID]domain.Step, len(steps))
+	for _, s := range steps {
+		byID[s.ID] = s
+	}
+
+	finish := make(map[uuid.UUID]time.Duration, len(steps))
+	var finishOf func(id uuid.UUID) time.Duration
+	finishOf = func(id uuid.UUID) time.Duration {
+		if d, ok := finish[id]; ok {
+			return d
+		}
+		// Guards against cycles; a well-formed plan has none.
+		finish[id] = 0
+
+		s, ok := byID[id]
+		if !ok {
+			return 0
+		}
+
+		var d time.Duration
+		switch s.Status {
+		case domain.StepDone, domain.StepError, domain.StepCancelled, domain.StepFailed:
+			return 0
+
+		case domain.StepInProgress, domain.StepAwaitingCallback:
+			d = e.durations.estimate(s.Agent)
+			if s.StartedAt != nil {
+				d -= now.Sub(*s.StartedAt)
+			}
+			d = max(d, 0)
+
+		default:
+			var start time.Duration
+			if s.NextRunAt != nil {
+				start = max(s.NextRunAt.Sub(now), 0)
+			}
+			for _, dep := range s.DependsOn {
+				start = max(start, finishOf(dep))
+			}
+			d = start + e.durations.estimate(s.Agent)
+		}
+
+		finish[id] = d
+		return d
+	}
+
+	var longest time.Duration
+	for _, s := range steps {
+		longest = max(longest, finishOf(s.ID))
+	}
+	return longest
+}
@@ -2,53 +2,207 @@ package engine
 
 import (
 	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"time"
+
 	"github.com/google/uuid"
 
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
 	"github.com/yeOmaNnn/orchestrator/internal/domain"
 	"github.com/yeOmaNnn/orchestrator/internal/planner"
-	"github.com/yeOmaNnn/orchestrator/internal/scheduler"
+	"github.com/yeOmaNnn/orchestrator/internal/retry"
 	"github.com/yeOmaNnn/orchestrator/internal/storage"
 )
 
+// Engine plans tasks and tracks them to completion. It never executes steps
+// itself; that is the job of scheduler workers, which may run in this process
+// or in separate worker processes sharing the same storage.
 type Engine struct {
-	planner   planner.Client
-	scheduler *scheduler.Scheduler
-	taskRepo  storage.TaskRepository
-	stepRepo  storage.StepRepository
+	planner    planner.Client
+	taskRepo   storage.TaskRepository
+	stepRepo   storage.StepRepository
+	workerRepo storage.WorkerRepository
+
+	deadLetters storage.DeadLetterRepository
+	pausesRepo  storage.AgentPauseRepository
+	schedules   storage.ScheduleRepository
+	agents      storage.AgentCatalogRepository
+	directory   AgentDirectory
+
+	agentLabels        map[string][]string
+	unschedulableAfter time.Duration
+
+	retryPolicies      map[string]retry.Policy
+	defaultRetryPolicy retry.Policy
+
+	durations *durationEstimator
+}
+
+type Option func(*Engine)
+
+// WithAgentLabels declares labels an agent requires of the worker running
+// it. They are added to every step planned for that agent.
+func WithAgentLabels(labels map[string][]string) Option {
+	return func(e *Engine) {
+		e.agentLabels = labels
+	}
+}
+
+// WithRetryPolicies sets per-agent retry policies for steps whose plan does
+// not carry its own policy.
+func WithRetryPolicies(policies map[string]retry.Policy) Option {
+	return func(e *Engine) {
+		e.retryPolicies = policies
+	}
+}
+
+// WithDefaultRetryPolicy sets the policy for steps with neither a step nor
+// an agent policy.
+func WithDefaultRetryPolicy(p retry.Policy) Option {
+	return func(e *Engine) {
+		e.defaultRetryPolicy = p
+	}
+}
+
+// WithUnschedulableDetection marks ready steps UNSCHEDULABLE once no live
+// worker in repo has been able to serve them for d.
+func WithUnschedulableDetection(repo storage.WorkerRepository, d time.Duration) Option {
+	return func(e *Engine) {
+		e.workerRepo = repo
+		e.unschedulableAfter = d
+	}
+}
+
+// WithDeadLetters enables requeueing steps from the dead-letter store.
+func WithDeadLetters(repo storage.DeadLetterRepository) Option {
+	return func(e *Engine) {
+		e.deadLetters = repo
+	}
+}
+
+// WithAgentPauses enables pausing dispatch per agent.
+func WithAgentPauses(repo storage.AgentPauseRepository) Option {
+	return func(e *Engine) {
+		e.pausesRepo = repo
+	}
+}
+
+// WithAgentCatalog lets agents registered at runtime set their default
+// retry policy with MaxRetries.
+func WithAgentCatalog(repo storage.AgentCatalogRepository) Option {
+	return func(e *Engine) {
+		e.agents = repo
+	}
+}
+
+// AgentDirectory lists the agents a process can call. *agent.Registry
+// implements it.
+type AgentDirectory interface {
+	Describe() []agent.Description
+}
+
+// WithAgentDirectory offers the agents in d to the planner, alongside the
+// agent catalog.
+func WithAgentDirectory(d AgentDirectory) Option {
+	return func(e *Engine) {
+		e.directory = d
+	}
+}
+
+// WithSchedules enables recurring tasks stored in repo.
+func WithSchedules(repo storage.ScheduleRepository) Option {
+	return func(e *Engine) {
+		e.schedules = repo
+	}
 }
 
 func New(
 	planner planner.Client,
-	scheduler *scheduler.Scheduler,
 	taskRepo storage.TaskRepository,
 	stepRepo storage.StepRepository,
+	opts ...Option,
 ) *Engine {
-	return &Engine{
-		planner:   planner,
-		scheduler: scheduler,
-		taskRepo:  taskRepo,
-		stepRepo:  stepRepo,
+	e := &Engine{
+		planner:            planner,
+		taskRepo:           taskRepo,
+		stepRepo:           stepRepo,
+		defaultRetryPolicy: retry.DefaultPolicy(),
+		durations:          newDurationEstimator(),
 	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
 }
 
 func (e *Engine) InitTaskExecution(
-	ctx  context.Context,
+	ctx context.Context,
 	task domain.Task,
 ) error {
 
+	configs := e.agentConfigs(ctx)
+
 	plan, err := e.planner.Plan(ctx, planner.PlanRequest{
 		TaskID: task.ID,
 		Goal:   task.Goal,
+		Agents: e.describeAgents(configs),
 	})
 	if err != nil {
 		return err
 	}
 
 	steps := planner.MapToDomainSteps(task.ID, plan.Steps)
+	for i := range steps {
+		steps[i].RequiredLabels = mergeLabels(
+			steps[i].RequiredLabels,
+			e.agentLabels[steps[i].Agent],
+		)
+		if steps[i].RetryPolicy == nil {
+			policy := e.retryPolicyFor(steps[i].Agent, configs)
+			steps[i].RetryPolicy = &policy
+		}
+		if err := steps[i].RetryPolicy.Validate(); err != nil {
+			return fmt.Errorf("step %s: %w", steps[i].ID, err)
+		}
+		if plan.Steps[i].TimeoutSeconds < 0 {
+			return fmt.Errorf("step %s: timeout_seconds must not be negative", steps[i].ID)
+		}
+		if cfg, ok := configs[steps[i].Agent]; ok && plan.Steps[i].TimeoutSeconds == 0 && cfg.TimeoutSeconds > 0 {
+			// The step may take as long as its agent is allowed to.
+			steps[i].TimeoutSeconds = cfg.TimeoutSeconds
+		}
+	}
 
 	return e.stepRepo.CreateMany(ctx, steps)
 }
 
+// SubmitTask stores a new task, plans it and starts following it in the
+// background. The loop outlives ctx; only its values are kept.
+func (e *Engine) SubmitTask(
+	ctx context.Context,
+	task *domain.Task,
+) error {
+	if task.CreatedAt.IsZero() {
+		task.CreatedAt = time.Now()
+	}
+
+	if err := e.taskRepo.Create(ctx, task); err != nil {
+		return err
+	}
+
+	if err := e.InitTaskExecution(ctx, *task); err != nil {
+		return err
+	}
+
+	go e.RunTaskLoop(context.WithoutCancel(ctx), task.ID)
+	return nil
+}
+
 func (e *Engine) RunTask(
 	ctx context.Context,
 	taskID uuid.UUID,
@@ -56,22 +210,88 @@ func (e *Engine) RunTask(
 	return e.RunTaskLoop(ctx, taskID)
 }
 
-
 func (e *Engine) CancelTask(
-	ctx context.Context, 
-	taskID uuid.UUID, 
+	ctx context.Context,
+	taskID uuid.UUID,
 ) error {
 	if err := e.stepRepo.CancelByTask(ctx, taskID); err != nil {
-		return err 
+		return err
 	}
 
 	if err := e.taskRepo.UpdateStatus(
-		ctx, 
-		taskID, 
+		ctx,
+		taskID,
 		domain.TaskCanceled,
 	); err != nil {
-		return err 
+		return err
 	}
 
-	return nil 
-}
\ No newline at end of file
+	return nil
+}
+
+// retryPolicyFor returns the configured policy of the agent or, failing
+// that, the default policy with the MaxRetries of its catalog entry.
+func (e *Engine) retryPolicyFor(
+	name string,
+	configs map[string]agent.Config,
+) retry.Policy {
+	if p, ok := e.retryPolicies[name]; ok {
+		return p
+	}
+	p := e.defaultRetryPolicy
+	if cfg, ok := configs[name]; ok && cfg.MaxRetries > 0 {
+		p.MaxAttempts = cfg.MaxRetries + 1
+	}
+	return p
+}
+
+// describeAgents merges the agent directory with the catalog. The
+// directory wins: it holds what this process actually calls.
+func (e *Engine) describeAgents(configs map[string]agent.Config) []agent.Description {
+	var out []agent.Description
+	seen := make(map[string]bool)
+	if e.directory != nil {
+		for _, d := range e.directory.Describe() {
+			out = append(out, d)
+			seen[d.Name] = true
+		}
+	}
+	for name, cfg := range configs {
+		if !seen[name] {
+			out = append(out, agent.Description{Name: name, Metadata: cfg.Metadata})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+// agentConfigs returns the agent catalog by name. Without a catalog, or
+// when it cannot be read, it is empty.
+func (e *Engine) agentConfigs(ctx context.Context) map[string]agent.Config {
+	out := make(map[string]agent.Config)
+	if e.agents == nil {
+		return out
+	}
+
+	configs, err := e.agents.List(ctx)
+	if err != nil {
+		log.Printf("engine: list agent catalog: %v", err)
+		return out
+	}
+	for _, c := range configs {
+		out[c.Name] = c
+	}
+	return out
+}
+
+func mergeLabels(step, agent []string) []string {
+	out := slices.Clone(step)
+	for _, l := range agent {
+		if !slices.Contains(out, l) {
+			out = append(out, l)
+		}
+	}
+	return out
+}
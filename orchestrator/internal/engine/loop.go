@@ -15,9 +15,12 @@ func (e *Engine) RunTaskLoop(
 	taskID uuid.UUID,
 ) error {
 
-	if err := e.taskRepo.UpdateStatus(
+	// Revived tasks are already RUNNING and a task may have been paused
+	// before its loop started; only a fresh task is switched here.
+	if _, err := e.taskRepo.TransitionStatus(
 		ctx,
 		taskID,
+		domain.TaskPending,
 		domain.TaskRunning,
 	); err != nil {
 		return err
@@ -26,6 +29,8 @@ func (e *Engine) RunTaskLoop(
 	ticker := time.NewTicker(schedulerInterval)
 	defer ticker.Stop()
 
+	sla := &slaMonitor{observed: make(map[uuid.UUID]bool)}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -33,16 +38,37 @@ func (e *Engine) RunTaskLoop(
 
 		case <-ticker.C:
 
-			if err := e.scheduler.Schedule(ctx, taskID); err != nil {
-				_ = e.taskRepo.UpdateStatus(ctx, taskID, domain.TaskFailed)
+			task, err := e.taskRepo.GetByID(ctx, taskID)
+			if err != nil {
 				return err
 			}
 
+			if left, ok := task.Remaining(); ok && left <= 0 &&
+				(task.Status == domain.TaskRunning || task.Status == domain.TaskPaused) {
+				return e.timeOut(ctx, task)
+			}
+
+			switch task.Status {
+			case domain.TaskPaused:
+				// Nothing is dispatched while paused, so an idle task
+				// must not be taken for a finished one.
+				continue
+			case domain.TaskRunning:
+			default:
+				// Cancelled, or concluded by someone else.
+				return nil
+			}
+
 			steps, err := e.stepRepo.GetByTask(ctx, taskID)
 			if err != nil {
 				return err
 			}
 
+			if e.workerRepo != nil {
+				e.checkSchedulable(ctx, steps)
+			}
+			e.checkSLA(sla, task, steps)
+
 			var (
 				hasActive bool
 				hasError  bool
@@ -50,20 +76,31 @@ func (e *Engine) RunTaskLoop(
 
 			for _, s := range steps {
 				switch s.Status {
-				case domain.StepWaiting, domain.StepInProgress:
+				case domain.StepWaiting, domain.StepInProgress, domain.StepUnschedulable,
+					domain.StepAwaitingCallback:
 					hasActive = true
 				case domain.StepError:
 					hasError = true
 				}
 			}
 
-			if hasError {
-				_ = e.taskRepo.UpdateStatus(ctx, taskID, domain.TaskFailed)
-				return nil
+			var final domain.TaskStatus
+			switch {
+			case hasError:
+				final = domain.TaskFailed
+			case !hasActive:
+				final = domain.TaskCompleted
+			default:
+				continue
 			}
 
-			if !hasActive {
-				_ = e.taskRepo.UpdateStatus(ctx, taskID, domain.TaskCompleted)
+			// Only conclude a task that is still running; it may have
+			// been paused or cancelled since it was read.
+			ok, err := e.taskRepo.TransitionStatus(ctx, taskID, domain.TaskRunning, final)
+			if err != nil {
+				return err
+			}
+			if ok {
 				return nil
 			}
 		}
@@ -0,0 +1,117 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+var (
+	ErrTaskNotPausable = errors.New("task cannot be paused")
+	ErrTaskNotPaused   = errors.New("task is not paused")
+	ErrPausesDisabled  = errors.New("agent pauses are not enabled")
+)
+
+// PauseTask stops new steps of a pending or running task from being
+// dispatched. Steps already running finish normally and all state is kept.
+func (e *Engine) PauseTask(
+	ctx context.Context,
+	taskID uuid.UUID,
+) error {
+	for _, from := range []domain.TaskStatus{domain.TaskRunning, domain.TaskPending} {
+		ok, err := e.taskRepo.TransitionStatus(ctx, taskID, from, domain.TaskPaused)
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task.Status == domain.TaskPaused {
+		return nil
+	}
+	return fmt.Errorf("%w: task is %s", ErrTaskNotPausable, task.Status)
+}
+
+// ResumeTask lets a paused task dispatch steps again. Its loop keeps
+// running while paused, so no new loop is needed.
+func (e *Engine) ResumeTask(
+	ctx context.Context,
+	taskID uuid.UUID,
+) error {
+	ok, err := e.taskRepo.TransitionStatus(ctx, taskID, domain.TaskPaused, domain.TaskRunning)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrTaskNotPaused
+	}
+	return nil
+}
+
+// PauseAgent stops dispatch of steps for agent across all tasks, for every
+// worker sharing the storage.
+func (e *Engine) PauseAgent(
+	ctx context.Context,
+	agent string,
+	reason string,
+) (*domain.AgentPause, error) {
+	if e.pausesRepo == nil {
+		return nil, ErrPausesDisabled
+	}
+
+	pause := &domain.AgentPause{
+		Agent:  agent,
+		Reason: reason,
+	}
+	if err := e.pausesRepo.Pause(ctx, pause); err != nil {
+		return nil, err
+	}
+	return pause, nil
+}
+
+func (e *Engine) ResumeAgent(
+	ctx context.Context,
+	agent string,
+) error {
+	if e.pausesRepo == nil {
+		return ErrPausesDisabled
+	}
+	return e.pausesRepo.Resume(ctx, agent)
+}
+
+func (e *Engine) AgentPauses(
+	ctx context.Context,
+) ([]domain.AgentPause, error) {
+	if e.pausesRepo == nil {
+		return []domain.AgentPause{}, nil
+	}
+	return e.pausesRepo.List(ctx)
+}
+
+// pausedAgents lists agents whose steps are held back on purpose, so they
+// are not mistaken for unschedulable ones.
+func (e *Engine) pausedAgents(ctx context.Context) []string {
+	pauses, err := e.AgentPauses(ctx)
+	if err != nil {
+		return nil
+	}
+
+	agents := make([]string, 0, len(pauses))
+	for _, p := range pauses {
+		if !slices.Contains(agents, p.Agent) {
+			agents = append(agents, p.Agent)
+		}
+	}
+	return agents
+}
@@ -0,0 +1,188 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+	"github.com/yeOmaNnn/orchestrator/internal/storage"
+)
+
+// RetryTask resets the failed and cancelled steps of a finished task to
+// WAITING and puts the task back to RUNNING. Completed steps keep their
+// outputs. The caller must start a new RunTaskLoop for the task. A task
+// that timed out is not retried: its deadline has passed for good.
+func (e *Engine) RetryTask(
+	ctx context.Context,
+	taskID uuid.UUID,
+) ([]uuid.UUID, error) {
+	if _, err := e.finishedTask(ctx, taskID, domain.TaskFailed, domain.TaskCanceled); err != nil {
+		return nil, err
+	}
+
+	steps, err := e.stepRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reset []domain.Step
+	for _, s := range steps {
+		switch s.Status {
+		case domain.StepError, domain.StepFailed, domain.StepCancelled:
+			reset = append(reset, s)
+		}
+	}
+	if len(reset) == 0 {
+		return nil, fmt.Errorf("%w: no failed or cancelled steps", ErrTaskNotRevivable)
+	}
+
+	return e.resetSteps(ctx, taskID, reset)
+}
+
+// RerunFrom resets stepID and every step that transitively depends on it,
+// whatever their status, and puts the task back to RUNNING. Steps upstream
+// of stepID must be DONE; their outputs are reused. The caller must start a
+// new RunTaskLoop for the task. Like RetryTask it refuses timed-out tasks.
+func (e *Engine) RerunFrom(
+	ctx context.Context,
+	taskID uuid.UUID,
+	stepID uuid.UUID,
+) ([]uuid.UUID, error) {
+	if _, err := e.finishedTask(ctx, taskID, domain.TaskCompleted, domain.TaskFailed, domain.TaskCanceled); err != nil {
+		return nil, err
+	}
+
+	steps, err := e.stepRepo.GetByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uuid.UUID]domain.Step, len(steps))
+	for _, s := range steps {
+		byID[s.ID] = s
+	}
+
+	from, ok := byID[stepID]
+	if !ok {
+		return nil, fmt.Errorf("step %s not found in task %s", stepID, taskID)
+	}
+	for _, dep := range from.DependsOn {
+		if byID[dep].Status != domain.StepDone {
+			return nil, fmt.Errorf("%w: upstream step %s is %s", ErrTaskNotRevivable, dep, byID[dep].Status)
+		}
+	}
+
+	return e.resetSteps(ctx, taskID, dependents(steps, stepID))
+}
+
+func (e *Engine) finishedTask(
+	ctx context.Context,
+	taskID uuid.UUID,
+	allowed ...domain.TaskStatus,
+) (*domain.Task, error) {
+	task, err := e.taskRepo.GetByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.Status == domain.TaskTimedOut {
+		return nil, errTimedOut(task)
+	}
+	if !slices.Contains(allowed, task.Status) {
+		return nil, fmt.Errorf("%w: task is %s", ErrTaskNotRevivable, task.Status)
+	}
+	return task, nil
+}
+
+// errTimedOut refuses to revive a task whose deadline passed: it would be
+// timed out again at once, and the steps of a requeued dead letter would
+// never be dispatched.
+func errTimedOut(task *domain.Task) error {
+	return fmt.Errorf("%w: task is %s; its deadline passed, start a new task instead",
+		ErrTaskNotRevivable, task.Status)
+}
+
+func (e *Engine) resetSteps(
+	ctx context.Context,
+	taskID uuid.UUID,
+	steps []domain.Step,
+) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, 0, len(steps))
+	for _, s := range steps {
+		s.Reset()
+		if err := e.stepRepo.Update(ctx, &s); err != nil {
+			return nil, err
+		}
+		ids = append(ids, s.ID)
+	}
+
+	if err := e.resolveDeadLetters(ctx, taskID, ids); err != nil {
+		return nil, err
+	}
+
+	if err := e.taskRepo.UpdateStatus(ctx, taskID, domain.TaskRunning); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// resolveDeadLetters marks pending dead letters of steps that are running
+// again as requeued, so they no longer show up as needing attention.
+func (e *Engine) resolveDeadLetters(
+	ctx context.Context,
+	taskID uuid.UUID,
+	stepIDs []uuid.UUID,
+) error {
+	if e.deadLetters == nil {
+		return nil
+	}
+
+	pending := false
+	dls, err := e.deadLetters.List(ctx, storage.DeadLetterFilter{
+		TaskID:   &taskID,
+		Requeued: &pending,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dl := range dls {
+		if !slices.Contains(stepIDs, dl.StepID) {
+			continue
+		}
+		if err := e.deadLetters.MarkRequeued(ctx, dl.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dependents returns the step with rootID followed by every step that
+// depends on it directly or transitively.
+func dependents(steps []domain.Step, rootID uuid.UUID) []domain.Step {
+	children := make(map[uuid.UUID][]domain.Step)
+	var root domain.Step
+	for _, s := range steps {
+		if s.ID == rootID {
+			root = s
+		}
+		for _, dep := range s.DependsOn {
+			children[dep] = append(children[dep], s)
+		}
+	}
+
+	seen := map[uuid.UUID]bool{rootID: true}
+	out := []domain.Step{root}
+	for i := 0; i < len(out); i++ {
+		for _, child := range children[out[i].ID] {
+			if seen[child.ID] {
+				continue
+			}
+			seen[child.ID] = true
+			out = append(out, child)
+		}
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+// workerTTL is how long a worker counts as alive after its last heartbeat.
+const workerTTL = 30 * time.Second
+
+// checkSchedulable flags ready steps that no live worker could pick up for
+// longer than unschedulableAfter, and returns flagged steps to WAITING once
+// an eligible worker is back.
+func (e *Engine) checkSchedulable(
+	ctx context.Context,
+	steps []domain.Step,
+) {
+	now := time.Now()
+	byID := make(map[uuid.UUID]domain.Step, len(steps))
+	for _, s := range steps {
+		byID[s.ID] = s
+	}
+
+	paused := e.pausedAgents(ctx)
+
+	var candidates []domain.Step
+	for _, s := range steps {
+		if slices.Contains(paused, s.Agent) {
+			continue
+		}
+		switch s.Status {
+		case domain.StepUnschedulable:
+			candidates = append(candidates, s)
+		case domain.StepWaiting:
+			since, ready := readySince(s, byID)
+			if ready && now.Sub(since) >= e.unschedulableAfter {
+				candidates = append(candidates, s)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	workers, err := e.workerRepo.List(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, s := range candidates {
+		eligible := false
+		for _, w := range workers {
+			if w.Alive(workerTTL) && w.Eligible(s) {
+				eligible = true
+				break
+			}
+		}
+
+		switch {
+		case !eligible && s.Status == domain.StepWaiting:
+			reason := fmt.Sprintf(
+				"unschedulable: no live worker serves agent %q with labels %v",
+				s.Agent, s.RequiredLabels,
+			)
+			if ok, _ := e.stepRepo.TransitionStatus(
+				ctx, s.ID, domain.StepWaiting, domain.StepUnschedulable, reason,
+			); ok {
+				log.Printf("engine: step %s of task %s is %s", s.ID, s.TaskID, reason)
+			}
+
+		case eligible && s.Status == domain.StepUnschedulable:
+			_, _ = e.stepRepo.TransitionStatus(
+				ctx, s.ID, domain.StepUnschedulable, domain.StepWaiting, "",
+			)
+		}
+	}
+}
+
+// readySince returns when the step became eligible to run: after its last
+// update, its backoff and all of its dependencies.
+func readySince(
+	step domain.Step,
+	all map[uuid.UUID]domain.Step,
+) (time.Time, bool) {
+	since := step.UpdatedAt
+	if step.NextRunAt != nil {
+		if step.NextRunAt.After(time.Now()) {
+			return time.Time{}, false
+		}
+		if step.NextRunAt.After(since) {
+			since = *step.NextRunAt
+		}
+	}
+
+	for _, depID := range step.DependsOn {
+		dep, ok := all[depID]
+		if !ok {
+			continue
+		}
+		if dep.Status != domain.StepDone {
+			return time.Time{}, false
+		}
+		if dep.FinishedAt != nil && dep.FinishedAt.After(since) {
+			since = *dep.FinishedAt
+		}
+	}
+
+	return since, true
+}
@@ -0,0 +1,322 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+var (
+	ErrSchedulesDisabled = errors.New("schedules are not enabled")
+	ErrScheduleConflict  = errors.New("schedule was changed concurrently")
+)
+
+// catchUpGrace is how late a run may be and still count as on time under
+// CatchUpNone.
+const catchUpGrace = time.Minute
+
+// CreateSchedule validates the schedule and plans its first run.
+func (e *Engine) CreateSchedule(
+	ctx context.Context,
+	s *domain.Schedule,
+) error {
+	if e.schedules == nil {
+		return ErrSchedulesDisabled
+	}
+	if err := s.Validate(); err != nil {
+		return err
+	}
+
+	next, err := s.Next(time.Now())
+	if err != nil {
+		return err
+	}
+	s.ID = uuid.New()
+	s.NextRunAt = next
+	s.QueuedRuns = 0
+	s.LastRunAt = nil
+
+	return e.schedules.Create(ctx, s)
+}
+
+// UpdateSchedule replaces the definition of a schedule, keeping its run
+// state. Changing the timing plans the next run from now.
+func (e *Engine) UpdateSchedule(
+	ctx context.Context,
+	id uuid.UUID,
+	def domain.Schedule,
+) (*domain.Schedule, error) {
+	return e.modifySchedule(ctx, id, func(s *domain.Schedule) error {
+		retimed := s.Cron != def.Cron ||
+			s.IntervalSeconds != def.IntervalSeconds ||
+			s.Timezone != def.Timezone
+
+		s.Name = def.Name
+		s.Cron = def.Cron
+		s.IntervalSeconds = def.IntervalSeconds
+		s.Timezone = def.Timezone
+		s.Goal = def.Goal
+		s.TimeoutSeconds = def.TimeoutSeconds
+		s.Overlap = def.Overlap
+		s.CatchUp = def.CatchUp
+		if err := s.Validate(); err != nil {
+			return err
+		}
+
+		if retimed {
+			next, err := s.Next(time.Now())
+			if err != nil {
+				return err
+			}
+			s.NextRunAt = next
+		}
+		return nil
+	})
+}
+
+// PauseSchedule stops a schedule from spawning tasks. Tasks it already
+// spawned are not affected.
+func (e *Engine) PauseSchedule(
+	ctx context.Context,
+	id uuid.UUID,
+) (*domain.Schedule, error) {
+	return e.modifySchedule(ctx, id, func(s *domain.Schedule) error {
+		s.Paused = true
+		return nil
+	})
+}
+
+// ResumeSchedule lets a paused schedule spawn tasks again. Runs missed
+// while paused are not caught up; the next run is planned from now.
+func (e *Engine) ResumeSchedule(
+	ctx context.Context,
+	id uuid.UUID,
+) (*domain.Schedule, error) {
+	return e.modifySchedule(ctx, id, func(s *domain.Schedule) error {
+		if !s.Paused {
+			return nil
+		}
+		next, err := s.Next(time.Now())
+		if err != nil {
+			return err
+		}
+		s.Paused = false
+		s.NextRunAt = next
+		s.QueuedRuns = 0
+		return nil
+	})
+}
+
+func (e *Engine) DeleteSchedule(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	if e.schedules == nil {
+		return ErrSchedulesDisabled
+	}
+	return e.schedules.Delete(ctx, id)
+}
+
+func (e *Engine) modifySchedule(
+	ctx context.Context,
+	id uuid.UUID,
+	modify func(*domain.Schedule) error,
+) (*domain.Schedule, error) {
+	if e.schedules == nil {
+		return nil, ErrSchedulesDisabled
+	}
+
+	s, err := e.schedules.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := modify(s); err != nil {
+		return nil, err
+	}
+
+	ok, err := e.schedules.Update(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrScheduleConflict
+	}
+	return s, nil
+}
+
+// RunSchedules spawns tasks for due schedules every interval until ctx is
+// done. Several instances may run it against shared storage: a run is
+// claimed by updating the schedule's version before any task is created.
+func (e *Engine) RunSchedules(ctx context.Context, interval time.Duration) {
+	if e.schedules == nil {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			e.tickSchedules(ctx)
+		}
+	}
+}
+
+func (e *Engine) tickSchedules(ctx context.Context) {
+	now := time.Now()
+
+	due, err := e.schedules.ListDue(ctx, now)
+	if err != nil {
+		log.Printf("engine: list due schedules: %v", err)
+		return
+	}
+
+	for _, s := range due {
+		if err := e.fireSchedule(ctx, s, now); err != nil {
+			log.Printf("engine: schedule %s: %v", s.ID, err)
+		}
+	}
+}
+
+// fireSchedule works out how many runs of s are due, applies its overlap
+// policy against the tasks it spawned earlier, claims the result and only
+// then spawns tasks.
+func (e *Engine) fireSchedule(
+	ctx context.Context,
+	s domain.Schedule,
+	now time.Time,
+) error {
+	runs, next, err := dueRuns(&s, now)
+	if err != nil {
+		return err
+	}
+
+	active, err := e.taskRepo.ListActiveBySchedule(ctx, s.ID)
+	if err != nil {
+		return err
+	}
+
+	pending := min(runs+s.QueuedRuns, domain.MaxCatchUpRuns)
+	spawn := 0
+	var cancel []uuid.UUID
+
+	switch s.Overlap {
+	case domain.OverlapSkip:
+		if len(active) == 0 && pending > 0 {
+			spawn = 1
+		}
+		pending = 0
+
+	case domain.OverlapCancel:
+		if pending > 0 {
+			for _, t := range active {
+				cancel = append(cancel, t.ID)
+			}
+			spawn = 1
+		}
+		pending = 0
+
+	case domain.OverlapQueue:
+		if len(active) == 0 && pending > 0 {
+			spawn = 1
+			pending--
+		}
+	}
+
+	if spawn == 0 && next.Equal(s.NextRunAt) && pending == s.QueuedRuns {
+		// Queued runs still waiting for the active task.
+		return nil
+	}
+
+	s.NextRunAt = next
+	s.QueuedRuns = pending
+	if spawn > 0 {
+		s.LastRunAt = &now
+	}
+
+	ok, err := e.schedules.Update(ctx, &s)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Another instance handled this run.
+		return nil
+	}
+
+	for _, id := range cancel {
+		if err := e.CancelTask(ctx, id); err != nil {
+			log.Printf("engine: schedule %s: cancel previous task %s: %v", s.ID, id, err)
+		}
+	}
+
+	for i := 0; i < spawn; i++ {
+		task := scheduledTask(s, now)
+		if err := e.SubmitTask(ctx, &task); err != nil {
+			return fmt.Errorf("spawn task: %w", err)
+		}
+		log.Printf("engine: schedule %s spawned task %s", s.ID, task.ID)
+	}
+	return nil
+}
+
+// dueRuns returns how many runs of s its catch-up policy allows at now,
+// and when the following run is.
+func dueRuns(s *domain.Schedule, now time.Time) (int, time.Time, error) {
+	if s.NextRunAt.After(now) {
+		return 0, s.NextRunAt, nil
+	}
+
+	missed := 0
+	last := s.NextRunAt
+	for t := s.NextRunAt; !t.After(now) && missed < domain.MaxCatchUpRuns; {
+		missed++
+		last = t
+
+		var err error
+		if t, err = s.Next(t); err != nil {
+			return 0, time.Time{}, err
+		}
+	}
+
+	next, err := s.Next(now)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	switch s.CatchUp {
+	case domain.CatchUpAll:
+		return missed, next, nil
+	case domain.CatchUpOnce:
+		return 1, next, nil
+	default:
+		if now.Sub(last) <= catchUpGrace {
+			return 1, next, nil
+		}
+		return 0, next, nil
+	}
+}
+
+func scheduledTask(s domain.Schedule, now time.Time) domain.Task {
+	scheduleID := s.ID
+	task := domain.Task{
+		ID:         uuid.New(),
+		Goal:       s.Goal,
+		Status:     domain.TaskPending,
+		CreatedAt:  now,
+		ScheduleID: &scheduleID,
+	}
+	if s.TimeoutSeconds > 0 {
+		deadline := now.Add(time.Duration(s.TimeoutSeconds) * time.Second)
+		task.Deadline = &deadline
+	}
+	return task
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunStaleLockSweeper returns steps locked for longer than ttl to WAITING
+// every interval, recovering steps whose worker died mid-call. ttl must be
+// longer than any step timeout. Only one replica needs to run it.
+func (e *Engine) RunStaleLockSweeper(
+	ctx context.Context,
+	ttl time.Duration,
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := e.stepRepo.ReleaseStaleLocks(ctx, ttl); err != nil && ctx.Err() == nil {
+				log.Printf("engine: release stale locks: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package events delivers what happens to a task while it runs to the
+// clients following it.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type Type string
+
+const (
+	// StepProgress carries progress reported by a streaming agent.
+	StepProgress Type = "step.progress"
+	// StepStatus reports the status a step settled in after it ran:
+	// DONE, ERROR, or WAITING to run again.
+	StepStatus Type = "step.status"
+	// AgentHealth reports an agent turning unhealthy or recovering. It
+	// belongs to no task and is published under AgentTopic.
+	AgentHealth Type = "agent.health"
+)
+
+// AgentTopic is the task ID that events about agents rather than tasks
+// are published and subscribed under.
+var AgentTopic = uuid.Nil
+
+// Event is something that happened to a step of a task, or to an agent.
+type Event struct {
+	Type     Type                 `json:"type"`
+	TaskID   uuid.UUID            `json:"task_id"`
+	StepID   uuid.UUID            `json:"step_id"`
+	Agent    string               `json:"agent"`
+	Status   domain.StepStatus    `json:"status,omitempty"`
+	Progress *domain.StepProgress `json:"progress,omitempty"`
+	// Healthy and Error are set on AgentHealth events.
+	Healthy *bool     `json:"healthy,omitempty"`
+	Error   string    `json:"error,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// Bus delivers events to the subscribers of their task. Delivery is best
+// effort: a subscriber that falls behind misses events rather than
+// slowing down the step that publishes them.
+type Bus interface {
+	Publish(ctx context.Context, e Event) error
+
+	// Subscribe returns the events of the task published from now on.
+	// cancel stops the subscription and closes the channel.
+	Subscribe(taskID uuid.UUID) (events <-chan Event, cancel func())
+}
+
+// subscriberBuffer is how many events a subscriber may fall behind by.
+const subscriberBuffer = 64
+
+// MemoryBus delivers events within the process.
+type MemoryBus struct {
+	mu   sync.Mutex
+	subs map[uuid.UUID]map[chan Event]struct{}
+}
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		subs: make(map[uuid.UUID]map[chan Event]struct{}),
+	}
+}
+
+func (b *MemoryBus) Publish(ctx context.Context, e Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs[e.TaskID] {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(taskID uuid.UUID) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[taskID] == nil {
+		b.subs[taskID] = make(map[chan Event]struct{})
+	}
+	b.subs[taskID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			delete(b.subs[taskID], ch)
+			if len(b.subs[taskID]) == 0 {
+				delete(b.subs, taskID)
+			}
+			close(ch)
+		})
+	}
+	return ch, cancel
+}
@@ -0,0 +1,220 @@
+// Package leader elects one process among replicas sharing storage to run
+// singleton background loops, such as the schedule ticker.
+package leader
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLost is returned by Backend.Renew when leadership has passed to
+// someone else.
+var ErrLost = errors.New("leadership lost")
+
+// Info describes the current holder of a leadership.
+type Info struct {
+	Name       string    `json:"name"`
+	Holder     string    `json:"holder"`
+	Token      int64     `json:"token"`
+	AcquiredAt time.Time `json:"acquired_at"`
+	RenewedAt  time.Time `json:"renewed_at"`
+}
+
+// Backend is the lock an Elector campaigns for. Every successful acquire
+// hands out a fencing token larger than any before it, so work done by a
+// deposed leader can be told apart from its successor's.
+type Backend interface {
+	// Name returns the name of the lock, under which storage looks up
+	// its current token to fence writes.
+	Name() string
+
+	// TryAcquire takes the lock if it is free and returns its new token.
+	TryAcquire(ctx context.Context) (int64, bool, error)
+
+	// Renew confirms the lock is still held under token.
+	Renew(ctx context.Context, token int64) error
+
+	Release(ctx context.Context) error
+
+	// Current reports who holds the lock, if anyone ever did.
+	Current(ctx context.Context) (Info, bool, error)
+}
+
+// Loop is a singleton background loop. It must return once ctx is done;
+// ctx is cancelled as soon as leadership is lost.
+type Loop func(ctx context.Context)
+
+type Elector struct {
+	backend Backend
+	id      string
+
+	retryInterval time.Duration
+	renewInterval time.Duration
+
+	mu    sync.Mutex
+	loops map[string]Loop
+
+	token atomic.Int64
+}
+
+type Option func(*Elector)
+
+// WithRetryInterval sets how often a follower tries to take over.
+func WithRetryInterval(d time.Duration) Option {
+	return func(e *Elector) {
+		e.retryInterval = d
+	}
+}
+
+// WithRenewInterval sets how often the leader confirms it still holds the
+// lock.
+func WithRenewInterval(d time.Duration) Option {
+	return func(e *Elector) {
+		e.renewInterval = d
+	}
+}
+
+func New(backend Backend, id string, opts ...Option) *Elector {
+	e := &Elector{
+		backend:       backend,
+		id:            id,
+		retryInterval: 2 * time.Second,
+		renewInterval: time.Second,
+		loops:         make(map[string]Loop),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Register adds a loop to run while this process is leader. Loops must be
+// registered before Run.
+func (e *Elector) Register(name string, loop Loop) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.loops[name] = loop
+}
+
+func (e *Elector) ID() string {
+	return e.id
+}
+
+// IsLeader reports whether this process currently leads.
+func (e *Elector) IsLeader() bool {
+	return e.token.Load() > 0
+}
+
+// Token returns the fencing token of the current leadership, or 0 when
+// this process is not the leader.
+func (e *Elector) Token() int64 {
+	return e.token.Load()
+}
+
+func (e *Elector) Current(ctx context.Context) (Info, bool, error) {
+	return e.backend.Current(ctx)
+}
+
+// Run campaigns for leadership until ctx is done, running the registered
+// loops whenever this process leads.
+func (e *Elector) Run(ctx context.Context) {
+	for {
+		token, ok, err := e.backend.TryAcquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Printf("leader: acquire: %v", err)
+		}
+		if ok {
+			e.lead(ctx, token)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.retryInterval):
+		}
+	}
+}
+
+func (e *Elector) lead(parent context.Context, token int64) {
+	ctx, cancel := context.WithCancel(withFence(parent, Fence{Lease: e.backend.Name(), Token: token}))
+	e.token.Store(token)
+	log.Printf("leader: %s is leader with token %d", e.id, token)
+
+	var wg sync.WaitGroup
+	e.mu.Lock()
+	for name, loop := range e.loops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			loop(ctx)
+			if ctx.Err() == nil {
+				log.Printf("leader: loop %s returned while leading", name)
+			}
+		}()
+	}
+	e.mu.Unlock()
+
+	ticker := time.NewTicker(e.renewInterval)
+	defer ticker.Stop()
+
+renew:
+	for {
+		select {
+		case <-parent.Done():
+			break renew
+		case <-ticker.C:
+			if err := e.backend.Renew(parent, token); err != nil {
+				if parent.Err() == nil {
+					log.Printf("leader: %s stepping down: %v", e.id, err)
+				}
+				break renew
+			}
+		}
+	}
+
+	// Stop the loops before anyone else can take over.
+	cancel()
+	wg.Wait()
+	e.token.Store(0)
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(parent), 5*time.Second)
+	defer cancelRelease()
+	if err := e.backend.Release(releaseCtx); err != nil {
+		log.Printf("leader: release: %v", err)
+	}
+}
+
+// Fence identifies the leadership a loop runs under. Storage that sees a
+// Fence in the context of a write makes the write only while Token is
+// still the current token of the lock named Lease, so a deposed leader
+// whose loops have not stopped yet cannot overwrite its successor's work.
+// The memory store needs no fence: it lives in one process, whose elector
+// stops the loops before the lock can pass on.
+type Fence struct {
+	Lease string
+	Token int64
+}
+
+type fenceKey struct{}
+
+func withFence(ctx context.Context, fence Fence) context.Context {
+	return context.WithValue(ctx, fenceKey{}, fence)
+}
+
+// FenceFrom returns the fence of the leadership a loop runs under.
+func FenceFrom(ctx context.Context) (Fence, bool) {
+	fence, ok := ctx.Value(fenceKey{}).(Fence)
+	return fence, ok
+}
+
+// TokenFrom returns the fencing token of the leadership a loop runs under.
+func TokenFrom(ctx context.Context) (int64, bool) {
+	fence, ok := FenceFrom(ctx)
+	return fence.Token, ok
+}
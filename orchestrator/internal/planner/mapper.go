@@ -6,22 +6,23 @@ import (
 )
 
 func MapToDomainSteps(
-	taskID uuid.UUID, 
-	planned []PlannedStep, 
+	taskID uuid.UUID,
+	planned []PlannedStep,
 ) []domain.Step {
 	steps := make([]domain.Step, 0, len(planned))
 
 	for _, ps := range planned {
-		step :=  domain.Step{
-			ID: ps.ID,
-			TaskID: taskID,
-			Agent: ps.Agent,
-			Input: ps.Input,
-			Status: domain.StepWaiting,
-			RetryCount: 0,
-			DependsOn: ps.DependsOn,
+		step := domain.NewStep(taskID, ps.Agent, ps.Input)
+		step.ID = ps.ID
+		if ps.DependsOn != nil {
+			step.DependsOn = ps.DependsOn
 		}
-		steps = append(steps, step)
+		step.RequiredLabels = ps.RequiredLabels
+		step.RetryPolicy = ps.RetryPolicy
+		if ps.TimeoutSeconds != 0 {
+			step.TimeoutSeconds = ps.TimeoutSeconds
+		}
+		steps = append(steps, *step)
 	}
 	return steps
-}
\ No newline at end of file
+}
@@ -3,21 +3,32 @@ package planner
 import (
 	"encoding/json"
 	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/retry"
 )
 
 type PlanRequest struct {
 	TaskID uuid.UUID `json:"task_id"`
 	Goal   string    `json:"goal"`
+
+	// Agents are the agents steps can be planned for, with what they do
+	// and the input they take.
+	Agents []agent.Description `json:"agents,omitempty"`
 }
 
 type PlannedStep struct {
-	ID uuid.UUID `json:"id"`
-	Agent string `json:"agent"`
-	Input json.RawMessage `json:"input"`
-	DependsOn []uuid.UUID `json:"depends_on"`
+	ID             uuid.UUID       `json:"id"`
+	Agent          string          `json:"agent"`
+	Input          json.RawMessage `json:"input"`
+	DependsOn      []uuid.UUID     `json:"depends_on"`
+	RequiredLabels []string        `json:"required_labels,omitempty"`
+	RetryPolicy    *retry.Policy   `json:"retry_policy,omitempty"`
+	// TimeoutSeconds bounds each attempt of the step. Zero takes the
+	// timeout of the agent's catalog entry, or the default.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
 }
 
 type PlanResponse struct {
 	Steps []PlannedStep `json:"steps"`
 }
-
@@ -0,0 +1,189 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Class groups errors by how a retry policy should treat them.
+type Class string
+
+const (
+	ClassTransient Class = "transient"
+	ClassTimeout   Class = "timeout"
+	ClassPermanent Class = "permanent"
+
+	// ClassDeferred failures say nothing about the step, e.g. the call was
+	// refused by an open circuit breaker. The step is tried again later
+	// without counting an attempt.
+	ClassDeferred Class = "deferred"
+)
+
+// Classifier is implemented by errors that know their own retry class.
+type Classifier interface {
+	RetryClass() Class
+}
+
+// Delayer is implemented by errors that carry a requested wait before the
+// next attempt, such as an HTTP Retry-After.
+type Delayer interface {
+	RetryDelay() time.Duration
+}
+
+// After returns the wait requested by err, if any. It overrides the
+// backoff strategy.
+func After(err error) (time.Duration, bool) {
+	var d Delayer
+	if errors.As(err, &d) && d.RetryDelay() > 0 {
+		return d.RetryDelay(), true
+	}
+	return 0, false
+}
+
+// Classify returns the retry class of err. Errors that do not classify
+// themselves are transient, except for deadlines, which are timeouts.
+func Classify(err error) Class {
+	var c Classifier
+	if errors.As(err, &c) {
+		return c.RetryClass()
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ClassTimeout
+	}
+	return ClassTransient
+}
+
+// Policy is the single description of how a step is retried. It is stored
+// with the step, so a step keeps the policy it was planned with. Delays
+// are in whole seconds.
+type Policy struct {
+	// Strategy is one of exponential, linear, fixed or none.
+	Strategy            string  `json:"strategy"`
+	MaxAttempts         int     `json:"max_attempts"`
+	InitialDelaySeconds int     `json:"initial_delay_seconds"`
+	MaxDelaySeconds     int     `json:"max_delay_seconds,omitempty"`
+	Factor              float64 `json:"factor,omitempty"`
+	IncrementSeconds    int     `json:"increment_seconds,omitempty"`
+	Jitter              bool    `json:"jitter,omitempty"`
+
+	// RetryOn lists the error classes worth another attempt. Empty means
+	// transient and timeout errors.
+	RetryOn []Class `json:"retry_on,omitempty"`
+
+	// BudgetSeconds caps the time between the first attempt and the start
+	// of the next one. Zero means no budget.
+	BudgetSeconds int `json:"budget_seconds,omitempty"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		Strategy:            "exponential",
+		MaxAttempts:         3,
+		InitialDelaySeconds: 1,
+		MaxDelaySeconds:     30,
+		Factor:              2,
+		Jitter:              true,
+	}
+}
+
+func (p Policy) Validate() error {
+	switch p.Strategy {
+	case "exponential", "linear", "fixed", "none":
+	default:
+		return fmt.Errorf("unknown retry strategy %q", p.Strategy)
+	}
+	if p.MaxAttempts < 1 {
+		return fmt.Errorf("max_attempts must be at least 1")
+	}
+	if p.Strategy != "none" && p.InitialDelaySeconds < 1 {
+		return fmt.Errorf("initial_delay_seconds must be at least 1")
+	}
+	if p.MaxDelaySeconds < 0 || p.IncrementSeconds < 0 || p.BudgetSeconds < 0 {
+		return fmt.Errorf("delays must not be negative")
+	}
+	for _, c := range p.RetryOn {
+		switch c {
+		case ClassTransient, ClassTimeout, ClassPermanent:
+		default:
+			return fmt.Errorf("unknown error class %q", c)
+		}
+	}
+	return nil
+}
+
+// Backoff builds the strategy that computes delays between attempts.
+func (p Policy) Backoff() BackoffStrategy {
+	initialDelay := seconds(p.InitialDelaySeconds)
+	maxDelay := seconds(p.MaxDelaySeconds)
+	if maxDelay <= 0 {
+		maxDelay = time.Hour
+	}
+
+	switch p.Strategy {
+	case "exponential":
+		factor := p.Factor
+		if factor <= 0 {
+			factor = 2
+		}
+		return &ExponentialBackoff{
+			InitialDelay: initialDelay,
+			MaxDelay:     maxDelay,
+			Factor:       factor,
+			Jitter:       p.Jitter,
+		}
+	case "linear":
+		return &LinearBackoff{
+			InitialDelay: initialDelay,
+			MaxDelay:     maxDelay,
+			Increment:    seconds(p.IncrementSeconds),
+			Jitter:       p.Jitter,
+		}
+	case "fixed":
+		return &FixedBackoff{
+			Delay:  initialDelay,
+			Jitter: p.Jitter,
+		}
+	default:
+		return &NoBackoff{}
+	}
+}
+
+func (p Policy) Retryable(err error) bool {
+	retryOn := p.RetryOn
+	if len(retryOn) == 0 {
+		retryOn = []Class{ClassTransient, ClassTimeout}
+	}
+	return slices.Contains(retryOn, Classify(err))
+}
+
+// Next decides what happens after attempt (1-based) failed with err. It
+// returns the delay before the next attempt, or false when the step should
+// give up.
+func (p Policy) Next(
+	attempt int,
+	firstAttemptAt time.Time,
+	err error,
+) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts || !p.Retryable(err) {
+		return 0, false
+	}
+
+	delay := p.Backoff().NextDelay(attempt - 1)
+	if d, ok := After(err); ok {
+		delay = d
+	}
+
+	budget := seconds(p.BudgetSeconds)
+	if budget > 0 && time.Since(firstAttemptAt)+delay > budget {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+func seconds(n int) time.Duration {
+	return time.Duration(n) * time.Second
+}
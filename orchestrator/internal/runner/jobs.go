@@ -0,0 +1,205 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+// OutputValidator is implemented by clients that can check an output an
+// agent delivered outside of a call against the agent's output schema.
+type OutputValidator interface {
+	ValidateOutput(agent string, output json.RawMessage) error
+}
+
+// await releases a step whose agent accepted the attempt as an
+// asynchronous job. The attempt stays open in the step's history until
+// Complete or PollJobs settles it.
+func (r *Runner) await(
+	ctx context.Context,
+	step *domain.Step,
+	owner string,
+	job *agent.AsyncJob,
+	version string,
+) error {
+	step.Attempt++
+	attempt := attemptRecord(*step, nil)
+	attempt.FinishedAt = time.Time{}
+	attempt.Version = version
+	attempt.JobID = job.ID
+	step.History = append(step.History, attempt)
+	if step.FirstAttemptAt == nil {
+		first := attempt.StartedAt
+		step.FirstAttemptAt = &first
+	}
+
+	now := time.Now()
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = r.jobTimeout
+	}
+	stepJob := domain.StepJob{
+		ID:         job.ID,
+		StatusURL:  job.StatusURL,
+		AcceptedAt: now,
+		Deadline:   now.Add(timeout),
+	}
+	if job.StatusURL != "" {
+		interval := job.PollInterval
+		if interval <= 0 {
+			interval = r.jobPollInterval
+		}
+		next := now.Add(interval)
+		stepJob.PollInterval = int(interval / time.Second)
+		stepJob.NextPollAt = &next
+	}
+	if r.callbacks == nil && job.StatusURL == "" {
+		log.Printf("runner: step %s: job %s can neither call back nor be polled; it fails at %s",
+			step.ID, job.ID, stepJob.Deadline.Format(time.RFC3339))
+	}
+
+	step.MarkAwaiting(stepJob)
+	return r.settle(ctx, step, owner)
+}
+
+// Complete settles attempt of a step awaiting an asynchronous job with the
+// job's result. It returns ErrJobNotFound if the step is not awaiting that
+// attempt.
+func (r *Runner) Complete(
+	ctx context.Context,
+	stepID uuid.UUID,
+	attempt int,
+	result agent.JobResult,
+) error {
+	step, err := r.stepsRepo.GetByID(ctx, stepID)
+	if err != nil {
+		return err
+	}
+	if step.Status != domain.StepAwaitingCallback || step.Attempt != attempt {
+		return ErrJobNotFound
+	}
+	return r.finishJob(ctx, step, result.Output, result.Err(step.Agent))
+}
+
+// finishJob concludes the open attempt of an awaiting step.
+func (r *Runner) finishJob(ctx context.Context, step *domain.Step, output json.RawMessage, err error) error {
+	if err == nil {
+		if validator, ok := r.client.(OutputValidator); ok {
+			err = validator.ValidateOutput(step.Agent, output)
+		}
+	}
+
+	attempt := step.Attempt
+	if n := len(step.History); n > 0 {
+		last := &step.History[n-1]
+		last.FinishedAt = time.Now()
+		if err != nil {
+			last.Error = err.Error()
+		}
+	}
+	step.Job = nil
+
+	jobErr := err
+	err = r.conclude(ctx, step, output, err, func(ctx context.Context, step *domain.Step) error {
+		ok, err := r.stepsRepo.UpdateIfAwaiting(ctx, step, attempt)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrJobNotFound
+		}
+		r.publishStatus(ctx, step)
+		return nil
+	})
+	if jobErr != nil && err == jobErr {
+		// The job's own failure is settled; callers only need to know
+		// whether the result was recorded.
+		return nil
+	}
+	return err
+}
+
+// PollJobs fails the awaiting steps whose job is past its deadline and
+// polls the status of the jobs that are due for it.
+func (r *Runner) PollJobs(ctx context.Context) error {
+	steps, err := r.stepsRepo.ListAwaiting(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range steps {
+		step := &steps[i]
+		job := step.Job
+		if job == nil {
+			continue
+		}
+
+		switch {
+		case now.After(job.Deadline):
+			err = &agent.Error{
+				Kind:  agent.KindPermanent,
+				Agent: step.Agent,
+				Err:   fmt.Errorf("job %s: %w", job.ID, ErrJobDeadline),
+			}
+			err = r.finishJob(ctx, step, nil, err)
+		case job.NextPollAt != nil && !now.Before(*job.NextPollAt):
+			err = r.pollJob(ctx, step)
+		default:
+			continue
+		}
+		if err != nil && !errors.Is(err, ErrJobNotFound) {
+			log.Printf("runner: step %s: job %s: %v", step.ID, job.ID, err)
+		}
+	}
+	return nil
+}
+
+// pollJob asks the agent how the job of step is doing, and settles the
+// step if it has ended.
+func (r *Runner) pollJob(ctx context.Context, step *domain.Step) error {
+	job := *step.Job
+
+	result, err := agent.PollJob(ctx, r.pollClient, step.Agent, job.StatusURL)
+	if err != nil {
+		// Polling is only a fallback; the next poll or the callback may
+		// still get through.
+		log.Printf("runner: step %s: poll job %s: %v", step.ID, job.ID, err)
+	}
+	if result != nil {
+		return r.finishJob(ctx, step, result.Output, result.Err(step.Agent))
+	}
+
+	next := time.Now().Add(time.Duration(job.PollInterval) * time.Second)
+	job.NextPollAt = &next
+	step.Job = &job
+	if _, err := r.stepsRepo.UpdateIfAwaiting(ctx, step, step.Attempt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RunJobs calls PollJobs every interval until ctx is done.
+func (r *Runner) RunJobs(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.PollJobs(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("runner: poll jobs: %v", err)
+			}
+		}
+	}
+}
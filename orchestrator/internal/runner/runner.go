@@ -4,60 +4,156 @@ import (
 	//"bytes"
 	"context"
 	"encoding/json"
-	//"errors"
-	//"net/http"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
 	"time"
 
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+	"github.com/yeOmaNnn/orchestrator/internal/callback"
 	"github.com/yeOmaNnn/orchestrator/internal/domain"
+	"github.com/yeOmaNnn/orchestrator/internal/events"
+	"github.com/yeOmaNnn/orchestrator/internal/retry"
 	"github.com/yeOmaNnn/orchestrator/internal/storage"
 )
 
 type AgentClient interface {
 	Call(
-		ctx   context.Context, 
-		agent string, 
-		input json.RawMessage, 
-	) (json.RawMessage, error) 
+		ctx context.Context,
+		agent string,
+		input json.RawMessage,
+	) (json.RawMessage, error)
 }
 
+// AgentCanceler is implemented by clients that can ask an agent to abort a
+// call. The runner uses it, best effort, when a running step is cancelled.
+type AgentCanceler interface {
+	Cancel(
+		ctx context.Context,
+		agent string,
+		callID string,
+	) error
+}
+
+var (
+	// ErrCancelled is the cause callers give when they cancel the context of
+	// a running step because the step itself was cancelled.
+	ErrCancelled = errors.New("step cancelled")
+
+	// ErrTaskDeadline is the cause callers give to a step context bounded
+	// by its task's deadline.
+	ErrTaskDeadline = errors.New("task deadline exceeded")
+
+	// ErrNotOwned is returned when the outcome of an attempt was dropped
+	// because the step was cancelled or taken over while it ran.
+	ErrNotOwned = errors.New("step is no longer owned by this worker")
+
+	// ErrJobNotFound is returned when the result of an asynchronous job
+	// arrives for a step that is not awaiting it: the job was settled
+	// already, the step was cancelled, or it moved on to another attempt.
+	ErrJobNotFound = errors.New("step is not awaiting this job")
+
+	// ErrJobDeadline fails a step whose asynchronous job did not report
+	// back in time.
+	ErrJobDeadline = errors.New("async job deadline exceeded")
+)
+
+// cancelTimeout bounds the best-effort cancel call to an agent.
+const cancelTimeout = 5 * time.Second
+
+// deferDelay is how long a deferred step waits when the error carries no
+// hint of its own.
+const deferDelay = 5 * time.Second
+
+// Runner executes one attempt of a step and settles its outcome. It is the
+// only place that applies retry policies: success, retry with backoff and
+// giving up are all decided and persisted here.
 type Runner struct {
-	stepsRepo  storage.StepRepository
-	client     AgentClient
+	stepsRepo   storage.StepRepository
+	deadLetters storage.DeadLetterRepository
+	client      AgentClient
+	events      events.Bus
+	callbacks   *callback.Signer
 
-	maxRetries int 
-	timeout    time.Duration
+	// timeout bounds attempts of steps that carry no timeout of their
+	// own.
+	timeout         time.Duration
+	jobTimeout      time.Duration
+	jobPollInterval time.Duration
+	pollClient      *http.Client
 }
 
 func New(
-	stepsRepo storage.StepRepository, 
-	client    AgentClient, 
-	opts      ...Option, 
+	stepsRepo storage.StepRepository,
+	client AgentClient,
+	opts ...Option,
 ) *Runner {
 	r := &Runner{
-		stepsRepo:  stepsRepo, 
-		client:     client, 
-		maxRetries: 3,
-		timeout:    30 * time.Second,
+		stepsRepo: stepsRepo,
+		client:    client,
+		timeout:   30 * time.Second,
+
+		jobTimeout:      24 * time.Hour,
+		jobPollInterval: time.Minute,
+		pollClient:      &http.Client{Timeout: 30 * time.Second},
 	}
 
 	for _, opt := range opts {
 		opt(r)
 	}
 
-	return r 
+	return r
+}
+
+type Option func(*Runner)
+
+// WithTimeout bounds the attempts of steps without a timeout of their own;
+// the default is 30 seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(r *Runner) {
+		r.timeout = d
+	}
 }
 
-type Option func(*Runner) 
+// WithEvents publishes the progress of steps and the status they settle
+// in to the subscribers of their task.
+func WithEvents(bus events.Bus) Option {
+	return func(r *Runner) {
+		r.events = bus
+	}
+}
 
-func WithMaxRetries(n int) Option {
+// WithCallbacks hands agents a URL signed by signer to report the result
+// of an asynchronous job to.
+func WithCallbacks(signer *callback.Signer) Option {
 	return func(r *Runner) {
-		r.maxRetries = n
+		r.callbacks = signer
 	}
 }
 
-func WithTimeout(d time.Duration) Option {
+// WithJobTimeout bounds how long an asynchronous job may take before its
+// step fails, for agents that configure no bound of their own. The default
+// is a day.
+func WithJobTimeout(d time.Duration) Option {
 	return func(r *Runner) {
-		r.timeout = d
+		r.jobTimeout = d
+	}
+}
+
+// WithJobPollInterval sets how often the status of an asynchronous job is
+// polled when its agent offers a status URL but no interval. The default
+// is a minute.
+func WithJobPollInterval(d time.Duration) Option {
+	return func(r *Runner) {
+		r.jobPollInterval = d
+	}
+}
+
+// WithDeadLetters captures steps that give up in repo.
+func WithDeadLetters(repo storage.DeadLetterRepository) Option {
+	return func(r *Runner) {
+		r.deadLetters = repo
 	}
 }
 
@@ -65,27 +161,244 @@ func (r *Runner) Run(
 	ctx context.Context,
 	step domain.Step,
 ) error {
-		ctx, cancel := context.WithTimeout(ctx, r.timeout)
-		defer cancel()
-		output, err := r.client.Call(ctx, step.Agent, step.Input)
-		if err != nil {
-			step.RetryCount++
-
-			if step.RetryCount >= r.maxRetries {
-				step.Status = domain.StepError
-			} else {
-				step.Status = domain.StepWaiting
-			}
+	parent := ctx
+	timeout := r.timeout
+	if step.TimeoutSeconds > 0 {
+		timeout = time.Duration(step.TimeoutSeconds) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var owner string
+	if step.LockedBy != nil {
+		owner = *step.LockedBy
+	}
+	callID := fmt.Sprintf("%s-%d", step.ID, step.Attempt+1)
+	ctx = agent.WithCallID(ctx, callID)
+	// Steps of one task share a routing key, so that a pooled agent can
+	// keep serving them from the replica that has their context cached.
+	ctx = agent.WithRoutingKey(ctx, step.TaskID.String())
+	if r.callbacks != nil {
+		ctx = agent.WithCallbackURL(ctx, r.callbacks.URL(step.ID, step.Attempt+1))
+	}
+	ctx, info := agent.WithCallInfo(ctx)
+	step.Progress = nil
+	ctx = agent.WithProgress(ctx, r.progress(parent, &step, owner))
+
+	output, err := r.client.Call(ctx, step.Agent, step.Input)
+	if err != nil && errors.Is(parent.Err(), context.Canceled) {
+		if errors.Is(context.Cause(parent), ErrCancelled) {
+			// The step is already CANCELLED in storage.
+			r.cancelAgent(parent, step.Agent, callID)
+			return ErrCancelled
+		}
+		// The caller gave up on the step (shutdown); leave its state for
+		// the caller to settle.
+		return err
+	}
+	if err != nil && errors.Is(context.Cause(parent), ErrTaskDeadline) {
+		// Not the step's fault and not worth a retry: the task is over.
+		r.cancelAgent(parent, step.Agent, callID)
+		_, _ = r.stepsRepo.TransitionStatus(
+			context.WithoutCancel(parent),
+			step.ID,
+			domain.StepInProgress,
+			domain.StepCancelled,
+			ErrTaskDeadline.Error(),
+		)
+		return ErrTaskDeadline
+	}
+
+	// Settle the attempt even if its own deadline has passed.
+	persistCtx := context.WithoutCancel(parent)
+
+	if job, ok := agent.AsAsyncJob(err); ok {
+		return r.await(persistCtx, &step, owner, job, info.Version)
+	}
+
+	if err != nil && retry.Classify(err) == retry.ClassDeferred {
+		// The agent was never reached; try later without using up an
+		// attempt.
+		delay, ok := retry.After(err)
+		if !ok {
+			delay = deferDelay
+		}
+		step.Defer(delay, err)
+		if uerr := r.settle(persistCtx, &step, owner); uerr != nil {
+			return errors.Join(err, uerr)
+		}
+		return err
+	}
+
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		err = fmt.Errorf("timeout exceeded: %w", err)
+	}
+
+	step.Attempt++
+	attempt := attemptRecord(step, err)
+	attempt.Version = info.Version
+	attempt.Stderr = info.Stderr
+	step.History = append(step.History, attempt)
+	if step.FirstAttemptAt == nil {
+		first := step.History[len(step.History)-1].StartedAt
+		step.FirstAttemptAt = &first
+	}
+
+	return r.conclude(persistCtx, &step, output, err, func(ctx context.Context, step *domain.Step) error {
+		return r.settle(ctx, step, owner)
+	})
+}
+
+// conclude settles a finished attempt, recorded in the step's history, as
+// done, to be retried or given up on, and persists the step with save.
+func (r *Runner) conclude(
+	ctx context.Context,
+	step *domain.Step,
+	output json.RawMessage,
+	err error,
+	save func(context.Context, *domain.Step) error,
+) error {
+	if err == nil {
+		step.MarkDone(output)
+		return save(ctx, step)
+	}
+
+	policy := step.Policy()
+	delay, retrying := policy.Next(step.Attempt, *step.FirstAttemptAt, err)
+	if retrying {
+		step.MarkForRetry(delay, err)
+	} else {
+		step.MarkError(err)
+	}
+
+	if uerr := save(ctx, step); uerr != nil {
+		return errors.Join(err, uerr)
+	}
+
+	if !retrying && r.deadLetters != nil {
+		if derr := r.deadLetters.Create(ctx, domain.NewDeadLetter(*step)); derr != nil {
+			return errors.Join(err, fmt.Errorf("dead letter: %w", derr))
+		}
+	}
+	return err
+}
+
+// settle persists the outcome of an attempt unless the step stopped being
+// ours while it ran: a cancelled step must never come back as DONE.
+func (r *Runner) settle(ctx context.Context, step *domain.Step, owner string) error {
+	ok, err := r.stepsRepo.UpdateIfOwned(ctx, step, owner)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotOwned
+	}
+
+	r.publishStatus(ctx, step)
+	return nil
+}
+
+func (r *Runner) publishStatus(ctx context.Context, step *domain.Step) {
+	r.publish(ctx, events.Event{
+		Type:   events.StepStatus,
+		TaskID: step.TaskID,
+		StepID: step.ID,
+		Agent:  step.Agent,
+		Status: step.Status,
+		At:     time.Now(),
+	})
+}
 
-			_ = r.stepsRepo.Update(ctx, &step)
-			return err
+// progressInterval bounds how often the progress of a step is persisted.
+// Every progress event is published, and the last one is persisted with
+// the outcome of the attempt.
+const progressInterval = time.Second
+
+// progress returns the function streaming agents report the progress of
+// step to. Reports only add to the progress so far: a partial output
+// stays until the next one.
+func (r *Runner) progress(parent context.Context, step *domain.Step, owner string) func(agent.Progress) {
+	var persisted time.Time
+
+	return func(p agent.Progress) {
+		now := time.Now()
+		progress := domain.StepProgress{}
+		if step.Progress != nil {
+			progress = *step.Progress
+		}
+		progress.UpdatedAt = now
+		if p.Percent > 0 {
+			progress.Percent = p.Percent
+		}
+		if p.Message != "" {
+			progress.Message = p.Message
+		}
+		if p.Partial != nil {
+			progress.Partial = p.Partial
+		}
+		step.Progress = &progress
+
+		ctx := context.WithoutCancel(parent)
+		if now.Sub(persisted) >= progressInterval {
+			persisted = now
+			if _, err := r.stepsRepo.UpdateProgress(ctx, step.ID, owner, &progress); err != nil {
+				log.Printf("runner: step %s: persist progress: %v", step.ID, err)
+			}
 		}
-	step.Output = output
-	step.Status = domain.StepDone
 
-	return r.stepsRepo.Update(ctx, &step)
+		r.publish(ctx, events.Event{
+			Type:     events.StepProgress,
+			TaskID:   step.TaskID,
+			StepID:   step.ID,
+			Agent:    step.Agent,
+			Progress: &progress,
+			At:       now,
+		})
+	}
+}
+
+func (r *Runner) publish(ctx context.Context, e events.Event) {
+	if r.events == nil {
+		return
+	}
+	if err := r.events.Publish(ctx, e); err != nil {
+		log.Printf("runner: step %s: publish %s: %v", e.StepID, e.Type, err)
+	}
+}
+
+// cancelAgent tells the agent to stop working on callID, if it can.
+func (r *Runner) cancelAgent(parent context.Context, agentName, callID string) {
+	canceler, ok := r.client.(AgentCanceler)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(parent), cancelTimeout)
+	defer cancel()
+
+	if err := canceler.Cancel(ctx, agentName, callID); err != nil {
+		log.Printf("runner: cancel call %s on agent %s: %v", callID, agentName, err)
+	}
 }
 
+func attemptRecord(step domain.Step, err error) domain.Attempt {
+	now := time.Now()
+	a := domain.Attempt{
+		Number:     step.Attempt,
+		StartedAt:  now,
+		FinishedAt: now,
+	}
+	if step.StartedAt != nil {
+		a.StartedAt = *step.StartedAt
+	}
+	if step.LockedBy != nil {
+		a.WorkerID = *step.LockedBy
+	}
+	if err != nil {
+		a.Error = err.Error()
+	}
+	return a
+}
 
 // type HTTPAgentClient struct {
 // 	baseURL string
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateSmoothing weights the latest tick in the acquisition rate average.
+const rateSmoothing = 0.2
+
+type stats struct {
+	busy           atomic.Int64
+	acquired       atomic.Int64
+	released       atomic.Int64
+	saturatedTicks atomic.Int64
+
+	mu       sync.Mutex
+	lastTick time.Time
+	rate     float64
+}
+
+func (st *stats) observeTick(acquired int, saturated bool) {
+	st.acquired.Add(int64(acquired))
+	if saturated {
+		st.saturatedTicks.Add(1)
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	now := time.Now()
+	if !st.lastTick.IsZero() {
+		if elapsed := now.Sub(st.lastTick).Seconds(); elapsed > 0 {
+			instant := float64(acquired) / elapsed
+			st.rate = rateSmoothing*instant + (1-rateSmoothing)*st.rate
+		}
+	}
+	st.lastTick = now
+}
+
+// Metrics reports queue depth, acquisition rate and saturation of this
+// worker's scheduler.
+func (s *Scheduler) Metrics() map[string]interface{} {
+	s.stats.mu.Lock()
+	rate := s.stats.rate
+	s.stats.mu.Unlock()
+
+	busy := int(s.stats.busy.Load())
+	queued := len(s.queue)
+
+	saturation := 0.0
+	if s.maxParallel > 0 {
+		saturation = float64(busy+queued) / float64(s.maxParallel)
+	}
+
+	return map[string]interface{}{
+		"worker_id":             s.self.ID,
+		"max_parallel":          s.maxParallel,
+		"in_flight":             busy,
+		"queue_depth":           queued,
+		"queue_capacity":        cap(s.queue),
+		"saturation":            saturation,
+		"acquired_total":        s.stats.acquired.Load(),
+		"acquired_per_second":   rate,
+		"released_total":        s.stats.released.Load(),
+		"saturated_ticks_total": s.stats.saturatedTicks.Load(),
+	}
+}
@@ -2,208 +2,226 @@ package scheduler
 
 import (
 	"context"
-	"errors"
+	"log"
+	"os"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
 	"github.com/yeOmaNnn/orchestrator/internal/domain"
 	"github.com/yeOmaNnn/orchestrator/internal/runner"
 	"github.com/yeOmaNnn/orchestrator/internal/storage"
 )
 
 type Scheduler struct {
-	workerID    string
-	stepsRepo 	storage.StepRepository
-	taskRepo  	storage.TaskRepository
-	runner    	*runner.Runner
-	 
-	maxParallel int
-
-	queue 		chan domain.Step
-
-	ticker      *time.Ticker
-	stop        chan struct{}
-	wg          sync.WaitGroup
+	self        domain.Worker
+	stepsRepo   storage.StepRepository
+	taskRepo    storage.TaskRepository
+	workersRepo storage.WorkerRepository
+	pausesRepo  storage.AgentPauseRepository
+	health      AgentHealth
+	runner      *runner.Runner
+
+	// capabilities, when set, is consulted on every tick so that agents
+	// registered at runtime are served without a restart.
+	capabilities func() []string
+
+	maxParallel        int
+	heartbeatInterval  time.Duration
+	cancelPollInterval time.Duration
+
+	queue chan queued
+	stats stats
+
+	// running holds the cancel function of every step being executed, so
+	// a step cancelled in storage can be aborted mid-call.
+	runningMu sync.Mutex
+	running   map[uuid.UUID]context.CancelCauseFunc
+
+	// execCtx is the parent of every step execution. It is independent of
+	// the context passed to Run so that in-flight steps survive the end of
+	// acquisition and are only aborted when a drain runs out of time.
+	execCtx    context.Context
+	execCancel context.CancelFunc
+
+	stop      chan struct{}
+	stopOnce  sync.Once
+	acquiring sync.WaitGroup
+	wg        sync.WaitGroup
 }
 
-func New(
-	stepsRepo   storage.StepRepository,
-	taskRepo    storage.TaskRepository,
-	runner      *runner.Runner,
-	maxParallel int,
-) *Scheduler {
-	return &Scheduler{
-		workerID: uuid.NewString(),
-		stepsRepo:   stepsRepo,
-		taskRepo:    taskRepo,
-		runner:      runner,
-		maxParallel: maxParallel,
-		queue: make(chan domain.Step, maxParallel*2),
-		stop: make(chan struct{}),
-	}
+// queued is an acquired step waiting for a worker, with the deadline of its
+// task if it has one and the task's labels.
+type queued struct {
+	step     domain.Step
+	deadline *time.Time
+	labels   []string
 }
 
+type Option func(*Scheduler)
 
-func (s *Scheduler) Schedule(
-	ctx context.Context,
-	taskID uuid.UUID,
-) error {
-
-	steps, err := s.stepsRepo.GetByTask(ctx, taskID)
-	if err != nil {
-		return err
+// WithWorkerID overrides the generated worker ID. Stable IDs let a restarted
+// worker take over its own registration.
+func WithWorkerID(id string) Option {
+	return func(s *Scheduler) {
+		s.self.ID = id
 	}
+}
 
-	stepByID := make(map[uuid.UUID]domain.Step, len(steps))
-	for _, step := range steps {
-		stepByID[step.ID] = step
+// WithCapabilities limits the worker to steps for the given agents.
+func WithCapabilities(agents ...string) Option {
+	return func(s *Scheduler) {
+		s.self.Capabilities = agents
 	}
+}
 
-	var readySteps []domain.Step
-	now := time.Now()
-
-	for _, step := range steps {
-		if step.Status != domain.StepWaiting {
-			continue
-		}
-
-		if step.NextRunAt != nil && step.NextRunAt.After(now) {
-			continue
-		}
-
-		if dependenciesDone(step, stepByID) {
-			readySteps = append(readySteps, step)
-		}
+// WithCapabilitySource keeps the worker's agents in step with agents(),
+// e.g. the names in an agent registry that changes at runtime. It takes
+// precedence over WithCapabilities.
+func WithCapabilitySource(agents func() []string) Option {
+	return func(s *Scheduler) {
+		s.capabilities = agents
 	}
+}
 
-	if len(readySteps) == 0 {
-		return nil
+// WithLabels advertises capability labels. Steps that require labels are
+// only handed to workers that carry all of them.
+func WithLabels(labels ...string) Option {
+	return func(s *Scheduler) {
+		s.self.Labels = labels
 	}
+}
 
-	for i := range readySteps {
-		readySteps[i].Status = domain.StepInProgress
-		readySteps[i].UpdatedAt = time.Now()
-
-		if err := s.stepsRepo.Update(ctx, &readySteps[i]); err != nil {
-			return err
-		}
+// WithWorkerRepo makes the scheduler register itself and heartbeat while
+// Run is active, so other processes can see which workers are alive.
+func WithWorkerRepo(repo storage.WorkerRepository) Option {
+	return func(s *Scheduler) {
+		s.workersRepo = repo
 	}
+}
 
-	sem := make(chan struct{}, s.maxParallel)
-	var wg sync.WaitGroup
-
-	for _, step := range readySteps {
-		wg.Add(1)
-
-		select {
-		case sem <- struct{}{}:
-		case <-ctx.Done():
-			wg.Done()
-			return ctx.Err()
-		}
-
-		go func(st domain.Step) {
-			defer wg.Done()
-			defer func() { <-sem }()
-
-			stepCtx := ctx
-			if st.TimeoutSeconds > 0 {
-				var cancel context.CancelFunc
-				stepCtx, cancel = context.WithTimeout(
-					ctx,
-					time.Duration(st.TimeoutSeconds)*time.Second,
-				)
-				defer cancel()
-			}
-
-			err := s.runner.Run(stepCtx, st)
-
-			if err != nil {
-				now := time.Now()
-				st.Attempt++
+// WithAgentPauses stops acquisition of steps for agents paused in repo.
+func WithAgentPauses(repo storage.AgentPauseRepository) Option {
+	return func(s *Scheduler) {
+		s.pausesRepo = repo
+	}
+}
 
-				if errors.Is(err, context.DeadlineExceeded) {
-					st.LastError = "timeout exceeded"
-				} else {
-					st.LastError = err.Error()
-				}
+// AgentHealth reports the agents this worker currently considers down.
+// *agent.HealthChecker implements it.
+type AgentHealth interface {
+	Unhealthy() []string
+}
 
-				if st.Attempt >= st.MaxAttempts {
-					st.Status = domain.StepError
-				} else {
-					delay := nextBackoff(st.Attempt)
-					next := now.Add(delay)
+// WithAgentHealth stops acquisition of steps for agents that h reports
+// unhealthy. Their steps stay WAITING until the agent recovers, or until
+// a worker that can reach it picks them up.
+func WithAgentHealth(h AgentHealth) Option {
+	return func(s *Scheduler) {
+		s.health = h
+	}
+}
 
-					st.Status = domain.StepWaiting
-					st.NextRunAt = &next
-				}
+// WithCancelPollInterval sets how often running steps are checked for
+// cancellation in storage.
+func WithCancelPollInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.cancelPollInterval = d
+	}
+}
 
-				st.UpdatedAt = time.Now()
-				_ = s.stepsRepo.Update(ctx, &st)
-				return
-			}
+func WithHeartbeatInterval(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.heartbeatInterval = d
+	}
+}
 
-			st.Status = domain.StepDone
-			st.UpdatedAt = time.Now()
-			_ = s.stepsRepo.Update(ctx, &st)
+func New(
+	stepsRepo storage.StepRepository,
+	taskRepo storage.TaskRepository,
+	runner *runner.Runner,
+	maxParallel int,
+	opts ...Option,
+) *Scheduler {
+	hostname, _ := os.Hostname()
+	execCtx, execCancel := context.WithCancel(context.Background())
+
+	s := &Scheduler{
+		self: domain.Worker{
+			ID:       uuid.NewString(),
+			Hostname: hostname,
+		},
+		stepsRepo:          stepsRepo,
+		taskRepo:           taskRepo,
+		runner:             runner,
+		maxParallel:        maxParallel,
+		heartbeatInterval:  10 * time.Second,
+		cancelPollInterval: time.Second,
+		queue:              make(chan queued, maxParallel),
+		running:            make(map[uuid.UUID]context.CancelCauseFunc),
+		execCtx:            execCtx,
+		execCancel:         execCancel,
+		stop:               make(chan struct{}),
+	}
 
-		}(step)
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.capabilities != nil {
+		s.self.Capabilities = s.capabilities()
+		slices.Sort(s.self.Capabilities)
 	}
 
-	wg.Wait()
-	return nil
+	return s
 }
 
-func dependenciesDone(
-	step domain.Step,
-	all map[uuid.UUID]domain.Step,
-) bool {
-	for _, depID := range step.DependsOn {
-		dep, ok := all[depID]
-		if !ok || dep.Status != domain.StepDone {
-			return false
-		}
-	}
-	return true
+func (s *Scheduler) WorkerID() string {
+	return s.self.ID
 }
 
-func nextBackoff(attempt int) time.Duration {
-	switch {
-	case attempt <= 1:
-		return 1 * time.Second
-	case attempt == 2:
-		return 3 * time.Second
-	default:
-		return time.Duration(attempt*attempt) * time.Second
-	}
-}
+func (s *Scheduler) worker(id int) {
+	defer s.wg.Done()
 
-func (s *Scheduler) worker (
-	ctx context.Context, 
-	id int, 
-) {
 	for {
+		// Prefer stopping over picking up another queued step.
 		select {
-		case <-ctx.Done():
-			return 
-		case step := <-s.queue:
-			s.executeStep(ctx, step)
+		case <-s.stop:
+			return
+		default:
+		}
+
+		select {
+		case <-s.stop:
+			return
+		case q := <-s.queue:
+			s.stats.busy.Add(1)
+			s.executeStep(q)
+			s.stats.busy.Add(-1)
 		}
 	}
 }
 
-func (s *Scheduler) executeStep(
-	ctx context.Context,
-	st domain.Step,
-) {
-	stepCtx := ctx
+func (s *Scheduler) executeStep(q queued) {
+	st := q.step
+	ctx := s.execCtx
+	stepCtx, cancelStep := context.WithCancelCause(agent.WithTaskLabels(ctx, q.labels))
+	s.track(st.ID, cancelStep)
+	defer s.untrack(st.ID)
+
+	if q.deadline != nil {
+		// Clamp the step to what is left of its task's budget.
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithDeadlineCause(stepCtx, *q.deadline, runner.ErrTaskDeadline)
+		defer cancel()
+	}
 
 	if st.TimeoutSeconds > 0 {
 		var cancel context.CancelFunc
 		stepCtx, cancel = context.WithTimeout(
-			ctx,
+			stepCtx,
 			time.Duration(st.TimeoutSeconds)*time.Second,
 		)
 		defer cancel()
@@ -211,90 +229,178 @@ func (s *Scheduler) executeStep(
 
 	err := s.runner.Run(stepCtx, st)
 
-	now := time.Now()
-
-	if err != nil {
-		st.Attempt++
-		st.LastError = err.Error()
-
-		if st.Attempt >= st.MaxAttempts {
-			st.Status = domain.StepError
-			st.FinishedAt = &now
-		} else {
-			delay := nextBackoff(st.Attempt)
-			next := now.Add(delay)
-
-			st.Status = domain.StepWaiting
-			st.NextRunAt = &next
-		}
-
-		st.UpdatedAt = now
-		_ = s.stepsRepo.Update(ctx, &st)
-		return
+	if err != nil && s.execCtx.Err() != nil {
+		// Aborted by shutdown: the step did not get a fair attempt.
+		s.release(context.WithoutCancel(ctx), st.ID)
 	}
 
-	st.Status = domain.StepDone
-	st.FinishedAt = &now
-	st.UpdatedAt = now
-	_ = s.stepsRepo.Update(ctx, &st)
+	// Every other outcome, including retries, is settled by the runner.
 }
 
-
+// Run acquires and executes steps until ctx is cancelled. Cancelling ctx
+// only stops acquisition; call Shutdown afterwards to drain in-flight steps
+// and deregister the worker.
 func (s *Scheduler) Run(ctx context.Context) {
+	s.acquiring.Add(1)
+	defer s.acquiring.Done()
+
+	if s.workersRepo != nil {
+		if err := s.workersRepo.Register(ctx, &s.self); err != nil {
+			log.Printf("scheduler: register worker %s: %v", s.self.ID, err)
+		}
+	}
+
 	for i := 0; i < s.maxParallel; i++ {
-		go s.worker(ctx, i)
+		s.wg.Add(1)
+		go s.worker(i)
 	}
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
 
+	heartbeat := time.NewTicker(s.heartbeatInterval)
+	defer heartbeat.Stop()
+
+	cancelPoll := time.NewTicker(s.cancelPollInterval)
+	defer cancelPoll.Stop()
+
 	for {
 		select {
 		case <-ctx.Done():
+			s.stopWorkers()
+			return
+		case <-s.stop:
 			return
 		case <-ticker.C:
 			s.tick(ctx)
+		case <-heartbeat.C:
+			s.heartbeat(ctx)
+		case <-cancelPoll.C:
+			s.abortCancelled(ctx)
 		}
 	}
 }
 
-func (s *Scheduler) RunOnce(
-	ctx context.Context,
-	taskID uuid.UUID,
-) error {
-
-	steps, err := s.stepsRepo.AcquireReadySteps(
-		ctx,
-		taskID,
-		s.maxParallel,
-		s.workerID,
-	)
-	if err != nil {
-		return err
+// Shutdown stops acquisition and waits for in-flight steps until ctx is
+// done. Steps still queued, and steps still running when ctx expires, are
+// released back to WAITING without consuming an attempt.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.stopWorkers()
+	s.acquiring.Wait()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		s.execCancel()
+		<-done
 	}
 
-	for _, step := range steps {
-		go func(st domain.Step) {
-			_ = s.runner.Run(ctx, st)
-		}(step)
+	s.releaseQueued()
+	s.execCancel()
+
+	if s.workersRepo != nil {
+		s.deregister()
 	}
+	return err
+}
 
-	return nil
+func (s *Scheduler) stopWorkers() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
 }
 
+func (s *Scheduler) releaseQueued() {
+	ctx := context.WithoutCancel(s.execCtx)
+	for {
+		select {
+		case q := <-s.queue:
+			s.release(ctx, q.step.ID)
+		default:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) release(ctx context.Context, ids ...uuid.UUID) {
+	if err := s.stepsRepo.Release(ctx, s.self.ID, ids...); err != nil {
+		log.Printf("scheduler: release steps %v: %v", ids, err)
+		return
+	}
+	s.stats.released.Add(int64(len(ids)))
+}
+
+func (s *Scheduler) heartbeat(ctx context.Context) {
+	if s.workersRepo == nil {
+		return
+	}
+	if err := s.workersRepo.Heartbeat(ctx, s.self.ID); err != nil {
+		log.Printf("scheduler: heartbeat worker %s: %v", s.self.ID, err)
+	}
+}
+
+func (s *Scheduler) deregister() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.workersRepo.Deregister(ctx, s.self.ID); err != nil {
+		log.Printf("scheduler: deregister worker %s: %v", s.self.ID, err)
+	}
+}
+
+// capacity is how many more steps the workers can take right now: steps are
+// only acquired when a worker is free or about to be.
+func (s *Scheduler) capacity() int {
+	return s.maxParallel - int(s.stats.busy.Load()) - len(s.queue)
+}
 
 func (s *Scheduler) tick(ctx context.Context) {
+	s.refreshCapabilities(ctx)
+
+	free := s.capacity()
+	if free <= 0 {
+		s.stats.observeTick(0, true)
+		return
+	}
 
 	tasks, err := s.taskRepo.ListActive(ctx)
 	if err != nil {
 		return
 	}
 
+	paused, err := s.pausedAgents(ctx)
+	if err != nil {
+		log.Printf("scheduler: list paused agents: %v", err)
+		return
+	}
+	skip := paused
+	if s.health != nil {
+		skip = append(skip, s.health.Unhealthy()...)
+	}
+
+	acquired := 0
 	for _, task := range tasks {
+		if free <= 0 {
+			break
+		}
+		if left, ok := task.Remaining(); ok && left <= 0 {
+			// The engine is about to time the task out.
+			continue
+		}
+
 		steps, err := s.stepsRepo.AcquireReadySteps(
 			ctx,
 			task.ID,
-			s.maxParallel,
-			s.workerID,
+			free,
+			s.self,
+			skip,
 		)
 		if err != nil {
 			continue
@@ -302,10 +408,113 @@ func (s *Scheduler) tick(ctx context.Context) {
 
 		for _, step := range steps {
 			select {
-			case s.queue <- step:
+			case s.queue <- queued{step: step, deadline: task.Deadline, labels: task.Labels}:
+				acquired++
+				free--
 			default:
+				// Never leave an acquired step locked behind a full
+				// queue; hand it straight back.
+				s.release(context.WithoutCancel(ctx), step.ID)
 			}
 		}
 	}
+
+	s.stats.observeTick(acquired, s.capacity() <= 0)
 }
 
+// refreshCapabilities re-registers the worker when its capability source
+// changed since the last tick.
+func (s *Scheduler) refreshCapabilities(ctx context.Context) {
+	if s.capabilities == nil {
+		return
+	}
+
+	agents := s.capabilities()
+	slices.Sort(agents)
+	if slices.Equal(agents, s.self.Capabilities) {
+		return
+	}
+	s.self.Capabilities = agents
+
+	if s.workersRepo == nil {
+		return
+	}
+	if err := s.workersRepo.Register(ctx, &s.self); err != nil {
+		log.Printf("scheduler: register worker %s: %v", s.self.ID, err)
+	}
+}
+
+func (s *Scheduler) pausedAgents(ctx context.Context) ([]string, error) {
+	if s.pausesRepo == nil {
+		return nil, nil
+	}
+
+	pauses, err := s.pausesRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	agents := make([]string, len(pauses))
+	for i, p := range pauses {
+		agents[i] = p.Agent
+	}
+	return agents, nil
+}
+
+func (s *Scheduler) track(id uuid.UUID, cancel context.CancelCauseFunc) {
+	s.runningMu.Lock()
+	defer s.runningMu.Unlock()
+	s.running[id] = cancel
+}
+
+func (s *Scheduler) untrack(id uuid.UUID) {
+	s.runningMu.Lock()
+	cancel := s.running[id]
+	delete(s.running, id)
+	s.runningMu.Unlock()
+
+	if cancel != nil {
+		cancel(nil)
+	}
+}
+
+// Cancel aborts the step if this scheduler is running it, and reports
+// whether it was. A step is only aborted once.
+func (s *Scheduler) Cancel(id uuid.UUID) bool {
+	s.runningMu.Lock()
+	cancel, ok := s.running[id]
+	delete(s.running, id)
+	s.runningMu.Unlock()
+
+	if ok {
+		cancel(runner.ErrCancelled)
+	}
+	return ok
+}
+
+// abortCancelled aborts running steps that were cancelled in storage, by
+// this process or any other.
+func (s *Scheduler) abortCancelled(ctx context.Context) {
+	s.runningMu.Lock()
+	ids := make([]uuid.UUID, 0, len(s.running))
+	for id := range s.running {
+		ids = append(ids, id)
+	}
+	s.runningMu.Unlock()
+
+	if len(ids) == 0 {
+		return
+	}
+
+	statuses, err := s.stepsRepo.Statuses(ctx, ids...)
+	if err != nil {
+		log.Printf("scheduler: poll step cancellation: %v", err)
+		return
+	}
+
+	for id, status := range statuses {
+		if status == domain.StepCancelled && s.Cancel(id) {
+			log.Printf("scheduler: aborted cancelled step %s", id)
+		}
+	}
+}
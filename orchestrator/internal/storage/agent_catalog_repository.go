@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// AgentCatalogRepository stores the configs of agents registered at
+// runtime, keyed by name.
+type AgentCatalogRepository interface {
+	// Create stores a new agent. It reports false, without writing, when
+	// the name is taken.
+	Create(
+		ctx context.Context,
+		config *agent.Config,
+	) (bool, error)
+
+	// Update replaces the config of an agent. It reports false when no
+	// agent has the name.
+	Update(
+		ctx context.Context,
+		config *agent.Config,
+	) (bool, error)
+
+	Get(
+		ctx context.Context,
+		name string,
+	) (*agent.Config, error)
+
+	List(
+		ctx context.Context,
+	) ([]agent.Config, error)
+
+	// Delete reports false when no agent has the name.
+	Delete(
+		ctx context.Context,
+		name string,
+	) (bool, error)
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type AgentPauseRepository interface {
+	// Pause stops dispatch for the agent. Pausing an agent that is
+	// already paused updates the reason.
+	Pause(
+		ctx context.Context,
+		pause *domain.AgentPause,
+	) error
+
+	Resume(
+		ctx context.Context,
+		agent string,
+	) error
+
+	List(
+		ctx context.Context,
+	) ([]domain.AgentPause, error)
+}
@@ -0,0 +1,39 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type DeadLetterFilter struct {
+	Agent  string
+	TaskID *uuid.UUID
+	// Requeued selects requeued (true) or pending (false) entries; nil
+	// selects both.
+	Requeued *bool
+	Limit    int
+}
+
+type DeadLetterRepository interface {
+	Create(
+		ctx context.Context,
+		dl *domain.DeadLetter,
+	) error
+
+	GetByID(
+		ctx context.Context,
+		id uuid.UUID,
+	) (*domain.DeadLetter, error)
+
+	List(
+		ctx context.Context,
+		filter DeadLetterFilter,
+	) ([]domain.DeadLetter, error)
+
+	MarkRequeued(
+		ctx context.Context,
+		id uuid.UUID,
+	) error
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+type AgentCatalogRepo struct {
+	mu      sync.RWMutex
+	configs map[string]agent.Config
+}
+
+func NewAgentCatalogRepo() *AgentCatalogRepo {
+	return &AgentCatalogRepo{
+		configs: make(map[string]agent.Config),
+	}
+}
+
+func (r *AgentCatalogRepo) Create(ctx context.Context, config *agent.Config) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.configs[config.Name]; ok {
+		return false, nil
+	}
+	config.UpdatedAt = time.Now()
+	r.configs[config.Name] = cloneConfig(*config)
+	return true, nil
+}
+
+func (r *AgentCatalogRepo) Update(ctx context.Context, config *agent.Config) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.configs[config.Name]; !ok {
+		return false, nil
+	}
+	config.UpdatedAt = time.Now()
+	r.configs[config.Name] = cloneConfig(*config)
+	return true, nil
+}
+
+func (r *AgentCatalogRepo) Get(ctx context.Context, name string) (*agent.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.configs[name]
+	if !ok {
+		return nil, fmt.Errorf("agent %s: %w", name, agent.ErrAgentNotFound)
+	}
+	c = cloneConfig(c)
+	return &c, nil
+}
+
+func (r *AgentCatalogRepo) List(ctx context.Context) ([]agent.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]agent.Config, 0, len(r.configs))
+	for _, c := range r.configs {
+		out = append(out, cloneConfig(c))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
+
+func (r *AgentCatalogRepo) Delete(ctx context.Context, name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.configs[name]; !ok {
+		return false, nil
+	}
+	delete(r.configs, name)
+	return true, nil
+}
+
+// cloneConfig copies the parts of a config that callers could modify in place.
+func cloneConfig(c agent.Config) agent.Config {
+	c.Replicas = slices.Clone(c.Replicas)
+	if c.Breaker != nil {
+		b := *c.Breaker
+		c.Breaker = &b
+	}
+	if c.Exec != nil {
+		e := *c.Exec
+		e.Command = slices.Clone(e.Command)
+		e.Env = slices.Clone(e.Env)
+		c.Exec = &e
+	}
+	if c.WASM != nil {
+		w := *c.WASM
+		c.WASM = &w
+	}
+	return c
+}
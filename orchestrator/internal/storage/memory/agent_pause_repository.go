@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type AgentPauseRepo struct {
+	mu     sync.RWMutex
+	pauses map[string]domain.AgentPause
+}
+
+func NewAgentPauseRepo() *AgentPauseRepo {
+	return &AgentPauseRepo{
+		pauses: make(map[string]domain.AgentPause),
+	}
+}
+
+func (r *AgentPauseRepo) Pause(ctx context.Context, pause *domain.AgentPause) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.pauses[pause.Agent]; ok {
+		pause.PausedAt = existing.PausedAt
+	} else {
+		pause.PausedAt = time.Now()
+	}
+	r.pauses[pause.Agent] = *pause
+	return nil
+}
+
+func (r *AgentPauseRepo) Resume(ctx context.Context, agent string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pauses, agent)
+	return nil
+}
+
+func (r *AgentPauseRepo) List(ctx context.Context) ([]domain.AgentPause, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.AgentPause, 0, len(r.pauses))
+	for _, p := range r.pauses {
+		out = append(out, p)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Agent < out[j].Agent
+	})
+	return out, nil
+}
@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+	"github.com/yeOmaNnn/orchestrator/internal/storage"
+)
+
+type DeadLetterRepo struct {
+	mu      sync.RWMutex
+	letters map[uuid.UUID]domain.DeadLetter
+}
+
+func NewDeadLetterRepo() *DeadLetterRepo {
+	return &DeadLetterRepo{
+		letters: make(map[uuid.UUID]domain.DeadLetter),
+	}
+}
+
+func (r *DeadLetterRepo) Create(ctx context.Context, dl *domain.DeadLetter) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.letters[dl.ID] = *dl
+	return nil
+}
+
+func (r *DeadLetterRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.DeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	dl, ok := r.letters[id]
+	if !ok {
+		return nil, fmt.Errorf("dead letter not found")
+	}
+	return &dl, nil
+}
+
+func (r *DeadLetterRepo) List(
+	ctx context.Context,
+	filter storage.DeadLetterFilter,
+) ([]domain.DeadLetter, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := []domain.DeadLetter{}
+	for _, dl := range r.letters {
+		if filter.Agent != "" && dl.Agent != filter.Agent {
+			continue
+		}
+		if filter.TaskID != nil && dl.TaskID != *filter.TaskID {
+			continue
+		}
+		if filter.Requeued != nil && (dl.RequeuedAt != nil) != *filter.Requeued {
+			continue
+		}
+		out = append(out, dl)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	if filter.Limit > 0 && len(out) > filter.Limit {
+		out = out[:filter.Limit]
+	}
+	return out, nil
+}
+
+func (r *DeadLetterRepo) MarkRequeued(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	dl, ok := r.letters[id]
+	if !ok {
+		return fmt.Errorf("dead letter not found")
+	}
+	now := time.Now()
+	dl.RequeuedAt = &now
+	r.letters[id] = dl
+	return nil
+}
@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/leader"
+)
+
+// LeaderLocks holds in-process leader locks. Electors in the same process
+// that share it compete for the same leadership.
+type LeaderLocks struct {
+	mu    sync.Mutex
+	locks map[string]*leaderLock
+}
+
+type leaderLock struct {
+	info leader.Info
+	held bool
+}
+
+func NewLeaderLocks() *LeaderLocks {
+	return &LeaderLocks{
+		locks: make(map[string]*leaderLock),
+	}
+}
+
+// Backend returns the lock called name as seen by holder.
+func (l *LeaderLocks) Backend(name, holder string) leader.Backend {
+	return &leaderBackend{locks: l, name: name, holder: holder}
+}
+
+type leaderBackend struct {
+	locks  *LeaderLocks
+	name   string
+	holder string
+}
+
+func (b *leaderBackend) Name() string {
+	return b.name
+}
+
+func (b *leaderBackend) TryAcquire(ctx context.Context) (int64, bool, error) {
+	b.locks.mu.Lock()
+	defer b.locks.mu.Unlock()
+
+	lock, ok := b.locks.locks[b.name]
+	if !ok {
+		lock = &leaderLock{info: leader.Info{Name: b.name}}
+		b.locks.locks[b.name] = lock
+	}
+	if lock.held {
+		return 0, false, nil
+	}
+
+	now := time.Now()
+	lock.held = true
+	lock.info.Holder = b.holder
+	lock.info.Token++
+	lock.info.AcquiredAt = now
+	lock.info.RenewedAt = now
+	return lock.info.Token, true, nil
+}
+
+func (b *leaderBackend) Renew(ctx context.Context, token int64) error {
+	b.locks.mu.Lock()
+	defer b.locks.mu.Unlock()
+
+	lock, ok := b.locks.locks[b.name]
+	if !ok || !lock.held || lock.info.Token != token {
+		return leader.ErrLost
+	}
+	lock.info.RenewedAt = time.Now()
+	return nil
+}
+
+func (b *leaderBackend) Release(ctx context.Context) error {
+	b.locks.mu.Lock()
+	defer b.locks.mu.Unlock()
+
+	lock, ok := b.locks.locks[b.name]
+	if ok && lock.held && lock.info.Holder == b.holder {
+		lock.held = false
+	}
+	return nil
+}
+
+func (b *leaderBackend) Current(ctx context.Context) (leader.Info, bool, error) {
+	b.locks.mu.Lock()
+	defer b.locks.mu.Unlock()
+
+	lock, ok := b.locks.locks[b.name]
+	if !ok || !lock.held {
+		return leader.Info{}, false, nil
+	}
+	return lock.info, true, nil
+}
@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type ScheduleRepo struct {
+	mu        sync.RWMutex
+	schedules map[uuid.UUID]domain.Schedule
+}
+
+func NewScheduleRepo() *ScheduleRepo {
+	return &ScheduleRepo{
+		schedules: make(map[uuid.UUID]domain.Schedule),
+	}
+}
+
+func (r *ScheduleRepo) Create(ctx context.Context, schedule *domain.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	schedule.Version = 1
+	r.schedules[schedule.ID] = *schedule
+	return nil
+}
+
+func (r *ScheduleRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.schedules[id]
+	if !ok {
+		return nil, fmt.Errorf("schedule not found")
+	}
+	return &s, nil
+}
+
+func (r *ScheduleRepo) List(ctx context.Context) ([]domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.Schedule, 0, len(r.schedules))
+	for _, s := range r.schedules {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *ScheduleRepo) Update(ctx context.Context, schedule *domain.Schedule) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur, ok := r.schedules[schedule.ID]
+	if !ok {
+		return false, fmt.Errorf("schedule not found")
+	}
+	if cur.Version != schedule.Version {
+		return false, nil
+	}
+
+	schedule.Version++
+	schedule.CreatedAt = cur.CreatedAt
+	schedule.UpdatedAt = time.Now()
+	r.schedules[schedule.ID] = *schedule
+	return true, nil
+}
+
+func (r *ScheduleRepo) Delete(ctx context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.schedules[id]; !ok {
+		return fmt.Errorf("schedule not found")
+	}
+	delete(r.schedules, id)
+	return nil
+}
+
+func (r *ScheduleRepo) ListDue(ctx context.Context, now time.Time) ([]domain.Schedule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []domain.Schedule
+	for _, s := range r.schedules {
+		if s.Paused {
+			continue
+		}
+		if !s.NextRunAt.After(now) || s.QueuedRuns > 0 {
+			out = append(out, s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].NextRunAt.Before(out[j].NextRunAt)
+	})
+	return out, nil
+}
@@ -0,0 +1,355 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type StepRepo struct {
+	mu    sync.RWMutex
+	steps map[uuid.UUID]domain.Step
+}
+
+func NewStepRepo() *StepRepo {
+	return &StepRepo{
+		steps: make(map[uuid.UUID]domain.Step),
+	}
+}
+
+func (r *StepRepo) CreateMany(
+	ctx context.Context,
+	steps []domain.Step,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, s := range steps {
+		if s.CreatedAt.IsZero() {
+			s.CreatedAt = now
+		}
+		s.UpdatedAt = now
+		r.steps[s.ID] = s
+	}
+	return nil
+}
+
+func (r *StepRepo) CancelByTask(
+	ctx context.Context,
+	taskID uuid.UUID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, step := range r.steps {
+		if step.TaskID != taskID {
+			continue
+		}
+
+		if step.Status == domain.StepDone ||
+			step.Status == domain.StepError ||
+			step.Status == domain.StepCancelled {
+			continue
+		}
+
+		step.Status = domain.StepCancelled
+		step.UpdatedAt = time.Now()
+		r.steps[id] = step
+	}
+	return nil
+}
+
+func (r *StepRepo) GetByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (*domain.Step, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.steps[id]
+	if !ok {
+		return nil, fmt.Errorf("step not found")
+	}
+	return &s, nil
+}
+
+func (r *StepRepo) GetByTask(
+	ctx context.Context,
+	taskID uuid.UUID,
+) ([]domain.Step, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []domain.Step
+	for _, s := range r.steps {
+		if s.TaskID == taskID {
+			out = append(out, s)
+		}
+	}
+	sortByCreated(out)
+	return out, nil
+}
+
+func (r *StepRepo) Update(
+	ctx context.Context,
+	step *domain.Step,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if step.Output == nil {
+		step.Output = json.RawMessage(`{}`)
+	}
+	step.UpdatedAt = time.Now()
+	r.steps[step.ID] = *step
+	return nil
+}
+
+func (r *StepRepo) UpdateIfOwned(
+	ctx context.Context,
+	step *domain.Step,
+	owner string,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur, ok := r.steps[step.ID]
+	if !ok || cur.Status != domain.StepInProgress {
+		return false, nil
+	}
+	if owner != "" && (cur.LockedBy == nil || *cur.LockedBy != owner) {
+		return false, nil
+	}
+
+	if step.Output == nil {
+		step.Output = json.RawMessage(`{}`)
+	}
+	step.UpdatedAt = time.Now()
+	r.steps[step.ID] = *step
+	return true, nil
+}
+
+func (r *StepRepo) ListAwaiting(
+	ctx context.Context,
+) ([]domain.Step, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var steps []domain.Step
+	for _, s := range r.steps {
+		if s.Status == domain.StepAwaitingCallback {
+			steps = append(steps, s)
+		}
+	}
+	sort.Slice(steps, func(i, j int) bool {
+		return steps[i].UpdatedAt.Before(steps[j].UpdatedAt)
+	})
+	return steps, nil
+}
+
+func (r *StepRepo) UpdateIfAwaiting(
+	ctx context.Context,
+	step *domain.Step,
+	attempt int,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur, ok := r.steps[step.ID]
+	if !ok || cur.Status != domain.StepAwaitingCallback || cur.Attempt != attempt {
+		return false, nil
+	}
+
+	if step.Output == nil {
+		step.Output = json.RawMessage(`{}`)
+	}
+	step.UpdatedAt = time.Now()
+	r.steps[step.ID] = *step
+	return true, nil
+}
+
+func (r *StepRepo) UpdateProgress(
+	ctx context.Context,
+	id uuid.UUID,
+	owner string,
+	progress *domain.StepProgress,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cur, ok := r.steps[id]
+	if !ok || cur.Status != domain.StepInProgress {
+		return false, nil
+	}
+	if owner != "" && (cur.LockedBy == nil || *cur.LockedBy != owner) {
+		return false, nil
+	}
+
+	p := *progress
+	cur.Progress = &p
+	cur.UpdatedAt = time.Now()
+	r.steps[id] = cur
+	return true, nil
+}
+
+func (r *StepRepo) Statuses(
+	ctx context.Context,
+	ids ...uuid.UUID,
+) (map[uuid.UUID]domain.StepStatus, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make(map[uuid.UUID]domain.StepStatus, len(ids))
+	for _, id := range ids {
+		if s, ok := r.steps[id]; ok {
+			out[id] = s.Status
+		}
+	}
+	return out, nil
+}
+
+func (r *StepRepo) AcquireReadySteps(
+	ctx context.Context,
+	taskID uuid.UUID,
+	limit int,
+	worker domain.Worker,
+	paused []string,
+) ([]domain.Step, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var candidates []domain.Step
+	for _, s := range r.steps {
+		if s.TaskID == taskID && s.Status == domain.StepWaiting {
+			candidates = append(candidates, s)
+		}
+	}
+	sortByCreated(candidates)
+
+	now := time.Now()
+	var readySteps []domain.Step
+
+	for _, s := range candidates {
+		if len(readySteps) >= limit {
+			break
+		}
+
+		if s.NextRunAt != nil && s.NextRunAt.After(now) {
+			continue
+		}
+
+		if !worker.Eligible(s) || slices.Contains(paused, s.Agent) {
+			continue
+		}
+
+		if !r.dependenciesDone(s) {
+			continue
+		}
+
+		s.MarkInProgress(worker.ID)
+		r.steps[s.ID] = s
+		readySteps = append(readySteps, s)
+	}
+
+	return readySteps, nil
+}
+
+func (r *StepRepo) TransitionStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	from domain.StepStatus,
+	to domain.StepStatus,
+	reason string,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.steps[id]
+	if !ok || s.Status != from {
+		return false, nil
+	}
+
+	s.Status = to
+	s.LastError = reason
+	s.UpdatedAt = time.Now()
+	r.steps[id] = s
+	return true, nil
+}
+
+func (r *StepRepo) Release(
+	ctx context.Context,
+	workerID string,
+	ids ...uuid.UUID,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for _, id := range ids {
+		s, ok := r.steps[id]
+		if !ok || s.Status != domain.StepInProgress {
+			continue
+		}
+		if s.LockedBy == nil || *s.LockedBy != workerID {
+			continue
+		}
+
+		s.Status = domain.StepWaiting
+		s.LockedAt = nil
+		s.LockedBy = nil
+		s.StartedAt = nil
+		s.UpdatedAt = now
+		r.steps[id] = s
+	}
+	return nil
+}
+
+func (r *StepRepo) ReleaseStaleLocks(
+	ctx context.Context,
+	ttl time.Duration,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, s := range r.steps {
+		if s.Status != domain.StepInProgress || s.LockedAt == nil {
+			continue
+		}
+		if now.Sub(*s.LockedAt) < ttl {
+			continue
+		}
+
+		s.Status = domain.StepWaiting
+		s.LockedAt = nil
+		s.LockedBy = nil
+		s.UpdatedAt = now
+		r.steps[id] = s
+	}
+	return nil
+}
+
+func (r *StepRepo) dependenciesDone(step domain.Step) bool {
+	for _, depID := range step.DependsOn {
+		dep, ok := r.steps[depID]
+		if ok && dep.Status != domain.StepDone {
+			return false
+		}
+	}
+	return true
+}
+
+func sortByCreated(steps []domain.Step) {
+	sort.SliceStable(steps, func(i, j int) bool {
+		return steps[i].CreatedAt.Before(steps[j].CreatedAt)
+	})
+}
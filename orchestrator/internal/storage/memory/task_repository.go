@@ -0,0 +1,141 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type TaskRepo struct {
+	mu    sync.RWMutex
+	tasks map[uuid.UUID]*domain.Task
+}
+
+func NewTaskRepo() *TaskRepo {
+	return &TaskRepo{
+		tasks: make(map[uuid.UUID]*domain.Task),
+	}
+}
+
+func (r *TaskRepo) Create(ctx context.Context, task *domain.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := *task
+	r.tasks[task.ID] = &t
+	return nil
+}
+
+func (r *TaskRepo) GetByID(ctx context.Context, id uuid.UUID) (*domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.tasks[id]
+	if !ok {
+		return nil, fmt.Errorf("task not found")
+	}
+	out := *t
+	return &out, nil
+}
+
+func (r *TaskRepo) UpdateStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	status domain.TaskStatus,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[id]
+	if !ok {
+		return fmt.Errorf("task not found")
+	}
+	t.Status = status
+	return nil
+}
+
+func (r *TaskRepo) TransitionStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	from domain.TaskStatus,
+	to domain.TaskStatus,
+) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t, ok := r.tasks[id]
+	if !ok {
+		return false, fmt.Errorf("task not found")
+	}
+	if t.Status != from {
+		return false, nil
+	}
+	t.Status = to
+	return true, nil
+}
+
+func (r *TaskRepo) ListActive(
+	ctx context.Context,
+) ([]domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []domain.Task
+	for _, t := range r.tasks {
+		if t.Status == domain.TaskPending ||
+			t.Status == domain.TaskRunning {
+			out = append(out, *t)
+		}
+	}
+
+	return out, nil
+}
+
+func (r *TaskRepo) ListBySchedule(
+	ctx context.Context,
+	scheduleID uuid.UUID,
+	limit int,
+) ([]domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := []domain.Task{}
+	for _, t := range r.tasks {
+		if t.ScheduleID != nil && *t.ScheduleID == scheduleID {
+			out = append(out, *t)
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.After(out[j].CreatedAt)
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *TaskRepo) ListActiveBySchedule(
+	ctx context.Context,
+	scheduleID uuid.UUID,
+) ([]domain.Task, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := []domain.Task{}
+	for _, t := range r.tasks {
+		if t.ScheduleID == nil || *t.ScheduleID != scheduleID {
+			continue
+		}
+		switch t.Status {
+		case domain.TaskPending, domain.TaskRunning, domain.TaskPaused:
+			out = append(out, *t)
+		}
+	}
+	return out, nil
+}
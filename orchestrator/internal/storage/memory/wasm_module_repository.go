@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+type WASMModuleRepo struct {
+	mu      sync.RWMutex
+	modules map[string]wasmModule
+}
+
+type wasmModule struct {
+	agent.WASMModule
+	data []byte
+}
+
+func NewWASMModuleRepo() *WASMModuleRepo {
+	return &WASMModuleRepo{
+		modules: make(map[string]wasmModule),
+	}
+}
+
+func (r *WASMModuleRepo) Put(ctx context.Context, module *agent.WASMModule, data []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.modules[module.Digest]; ok {
+		*module = m.WASMModule
+		return nil
+	}
+	module.CreatedAt = time.Now()
+	r.modules[module.Digest] = wasmModule{
+		WASMModule: *module,
+		data:       data,
+	}
+	return nil
+}
+
+func (r *WASMModuleRepo) Get(ctx context.Context, digest string) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	m, ok := r.modules[digest]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", digest, agent.ErrModuleNotFound)
+	}
+	// Modules are never modified in place, so the bytes can be shared.
+	return m.data, nil
+}
+
+func (r *WASMModuleRepo) List(ctx context.Context) ([]agent.WASMModule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]agent.WASMModule, 0, len(r.modules))
+	for _, m := range r.modules {
+		out = append(out, m.WASMModule)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+func (r *WASMModuleRepo) Delete(ctx context.Context, digest string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.modules[digest]; !ok {
+		return false, nil
+	}
+	delete(r.modules, digest)
+	return true, nil
+}
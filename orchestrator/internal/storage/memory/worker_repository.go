@@ -0,0 +1,64 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type WorkerRepo struct {
+	mu      sync.RWMutex
+	workers map[string]domain.Worker
+}
+
+func NewWorkerRepo() *WorkerRepo {
+	return &WorkerRepo{
+		workers: make(map[string]domain.Worker),
+	}
+}
+
+func (r *WorkerRepo) Register(ctx context.Context, worker *domain.Worker) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	worker.StartedAt = now
+	worker.HeartbeatAt = now
+	r.workers[worker.ID] = *worker
+	return nil
+}
+
+func (r *WorkerRepo) Heartbeat(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	w, ok := r.workers[id]
+	if !ok {
+		return fmt.Errorf("worker %s not registered", id)
+	}
+	w.HeartbeatAt = time.Now()
+	r.workers[id] = w
+	return nil
+}
+
+func (r *WorkerRepo) Deregister(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.workers, id)
+	return nil
+}
+
+func (r *WorkerRepo) List(ctx context.Context) ([]domain.Worker, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]domain.Worker, 0, len(r.workers))
+	for _, w := range r.workers {
+		out = append(out, w)
+	}
+	return out, nil
+}
@@ -0,0 +1,148 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// AgentCatalogRepo keeps each agent config as a JSON document, so that new
+// config fields need no migration.
+type AgentCatalogRepo struct {
+	db *sql.DB
+}
+
+func NewAgentCatalogRepo(db *sql.DB) *AgentCatalogRepo {
+	return &AgentCatalogRepo{db: db}
+}
+
+func scanAgentConfig(row rowScanner) (agent.Config, error) {
+	var (
+		c   agent.Config
+		doc []byte
+	)
+	if err := row.Scan(&doc, &c.UpdatedAt); err != nil {
+		return agent.Config{}, err
+	}
+	updatedAt := c.UpdatedAt
+	if err := json.Unmarshal(doc, &c); err != nil {
+		return agent.Config{}, fmt.Errorf("decode agent config: %w", err)
+	}
+	c.UpdatedAt = updatedAt
+	return c, nil
+}
+
+func (r *AgentCatalogRepo) Create(
+	ctx context.Context,
+	config *agent.Config,
+) (bool, error) {
+	doc, err := json.Marshal(config)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO agents (name, config)
+		 VALUES ($1, $2)
+		 ON CONFLICT (name) DO NOTHING
+		 RETURNING updated_at`,
+		config.Name,
+		doc,
+	).Scan(&config.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *AgentCatalogRepo) Update(
+	ctx context.Context,
+	config *agent.Config,
+) (bool, error) {
+	doc, err := json.Marshal(config)
+	if err != nil {
+		return false, err
+	}
+
+	err = r.db.QueryRowContext(
+		ctx,
+		`UPDATE agents
+		 SET config = $2,
+		     updated_at = NOW()
+		 WHERE name = $1
+		 RETURNING updated_at`,
+		config.Name,
+		doc,
+	).Scan(&config.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *AgentCatalogRepo) Get(
+	ctx context.Context,
+	name string,
+) (*agent.Config, error) {
+	c, err := scanAgentConfig(r.db.QueryRowContext(
+		ctx,
+		`SELECT config, updated_at
+		 FROM agents
+		 WHERE name = $1`,
+		name,
+	))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("agent %s: %w", name, agent.ErrAgentNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *AgentCatalogRepo) List(
+	ctx context.Context,
+) ([]agent.Config, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT config, updated_at
+		 FROM agents
+		 ORDER BY name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []agent.Config{}
+	for rows.Next() {
+		c, err := scanAgentConfig(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (r *AgentCatalogRepo) Delete(
+	ctx context.Context,
+	name string,
+) (bool, error) {
+	res, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM agents WHERE name = $1`,
+		name,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
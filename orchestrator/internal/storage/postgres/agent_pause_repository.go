@@ -0,0 +1,70 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type AgentPauseRepo struct {
+	db *sql.DB
+}
+
+func NewAgentPauseRepo(db *sql.DB) *AgentPauseRepo {
+	return &AgentPauseRepo{db: db}
+}
+
+func (r *AgentPauseRepo) Pause(
+	ctx context.Context,
+	pause *domain.AgentPause,
+) error {
+	return r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO agent_pauses (agent, reason)
+		 VALUES ($1, $2)
+		 ON CONFLICT (agent) DO UPDATE
+		 SET reason = EXCLUDED.reason
+		 RETURNING paused_at`,
+		pause.Agent,
+		pause.Reason,
+	).Scan(&pause.PausedAt)
+}
+
+func (r *AgentPauseRepo) Resume(
+	ctx context.Context,
+	agent string,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM agent_pauses WHERE agent = $1`,
+		agent,
+	)
+	return err
+}
+
+func (r *AgentPauseRepo) List(
+	ctx context.Context,
+) ([]domain.AgentPause, error) {
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT agent, reason, paused_at
+		 FROM agent_pauses
+		 ORDER BY agent`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.AgentPause{}
+	for rows.Next() {
+		var p domain.AgentPause
+		if err := rows.Scan(&p.Agent, &p.Reason, &p.PausedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,100 @@
+package postgres
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// uuidArray scans a Postgres UUID[] column. The pgx database/sql driver
+// hands arrays over in their text form, e.g. {a,b}.
+type uuidArray []uuid.UUID
+
+func (a *uuidArray) Scan(src any) error {
+	elems, err := parseArray(src)
+	if err != nil {
+		return err
+	}
+
+	out := make([]uuid.UUID, 0, len(elems))
+	for _, e := range elems {
+		id, err := uuid.Parse(e)
+		if err != nil {
+			return err
+		}
+		out = append(out, id)
+	}
+	*a = out
+	return nil
+}
+
+// textArray scans a Postgres TEXT[] column.
+type textArray []string
+
+func (a *textArray) Scan(src any) error {
+	elems, err := parseArray(src)
+	if err != nil {
+		return err
+	}
+	*a = elems
+	return nil
+}
+
+func (a textArray) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	quoted := make([]string, len(a))
+	for i, s := range a {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+		s = strings.ReplaceAll(s, `"`, `\"`)
+		quoted[i] = `"` + s + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}", nil
+}
+
+func parseArray(src any) ([]string, error) {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		return []string{}, nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return nil, fmt.Errorf("unsupported array source %T", src)
+	}
+
+	s = strings.TrimPrefix(strings.TrimSuffix(s, "}"), "{")
+	if s == "" {
+		return []string{}, nil
+	}
+
+	var (
+		out     []string
+		cur     strings.Builder
+		quoted  bool
+		escaped bool
+	)
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			out = append(out, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	out = append(out, cur.String())
+	return out, nil
+}
@@ -0,0 +1,165 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+	"github.com/yeOmaNnn/orchestrator/internal/storage"
+)
+
+const deadLetterColumns = `id, step_id, task_id, agent, input, attempts,
+	last_error, created_at, requeued_at`
+
+type DeadLetterRepo struct {
+	db *sql.DB
+}
+
+func NewDeadLetterRepo(db *sql.DB) *DeadLetterRepo {
+	return &DeadLetterRepo{db: db}
+}
+
+func scanDeadLetter(row rowScanner) (domain.DeadLetter, error) {
+	var (
+		dl       domain.DeadLetter
+		input    []byte
+		attempts []byte
+	)
+	if err := row.Scan(
+		&dl.ID,
+		&dl.StepID,
+		&dl.TaskID,
+		&dl.Agent,
+		&input,
+		&attempts,
+		&dl.LastError,
+		&dl.CreatedAt,
+		&dl.RequeuedAt,
+	); err != nil {
+		return domain.DeadLetter{}, err
+	}
+	if err := json.Unmarshal(attempts, &dl.Attempts); err != nil {
+		return domain.DeadLetter{}, fmt.Errorf("decode attempts: %w", err)
+	}
+	dl.Input = input
+	return dl, nil
+}
+
+func (r *DeadLetterRepo) Create(
+	ctx context.Context,
+	dl *domain.DeadLetter,
+) error {
+	attempts, err := json.Marshal(dl.Attempts)
+	if err != nil {
+		return err
+	}
+	if dl.Attempts == nil {
+		attempts = []byte(`[]`)
+	}
+
+	_, err = r.db.ExecContext(
+		ctx,
+		`INSERT INTO dead_letters
+		 (id, step_id, task_id, agent, input, attempts, last_error, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		dl.ID,
+		dl.StepID,
+		dl.TaskID,
+		dl.Agent,
+		dl.Input,
+		attempts,
+		dl.LastError,
+		dl.CreatedAt,
+	)
+	return err
+}
+
+func (r *DeadLetterRepo) GetByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (*domain.DeadLetter, error) {
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT `+deadLetterColumns+`
+		 FROM dead_letters
+		 WHERE id = $1`,
+		id,
+	)
+
+	dl, err := scanDeadLetter(row)
+	if err != nil {
+		return nil, err
+	}
+	return &dl, nil
+}
+
+func (r *DeadLetterRepo) List(
+	ctx context.Context,
+	filter storage.DeadLetterFilter,
+) ([]domain.DeadLetter, error) {
+
+	var (
+		where []string
+		args  []any
+	)
+	if filter.Agent != "" {
+		args = append(args, filter.Agent)
+		where = append(where, fmt.Sprintf("agent = $%d", len(args)))
+	}
+	if filter.TaskID != nil {
+		args = append(args, *filter.TaskID)
+		where = append(where, fmt.Sprintf("task_id = $%d", len(args)))
+	}
+	if filter.Requeued != nil {
+		if *filter.Requeued {
+			where = append(where, "requeued_at IS NOT NULL")
+		} else {
+			where = append(where, "requeued_at IS NULL")
+		}
+	}
+
+	query := `SELECT ` + deadLetterColumns + ` FROM dead_letters`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY created_at DESC`
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []domain.DeadLetter{}
+	for rows.Next() {
+		dl, err := scanDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, dl)
+	}
+	return out, rows.Err()
+}
+
+func (r *DeadLetterRepo) MarkRequeued(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE dead_letters
+		 SET requeued_at = NOW()
+		 WHERE id = $1`,
+		id,
+	)
+	return err
+}
@@ -0,0 +1,111 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/yeOmaNnn/orchestrator/internal/events"
+)
+
+// eventChannel is the NOTIFY channel task events are published on.
+const eventChannel = "task_events"
+
+// maxNotifyPayload stays under the 8000 byte limit of a NOTIFY payload.
+const maxNotifyPayload = 7900
+
+// EventBus is an events.Bus across processes: events are published with
+// NOTIFY, and Listen delivers the events of every process to the local
+// subscribers.
+type EventBus struct {
+	db    *sql.DB
+	local *events.MemoryBus
+}
+
+func NewEventBus(db *sql.DB) *EventBus {
+	return &EventBus{
+		db:    db,
+		local: events.NewMemoryBus(),
+	}
+}
+
+func (b *EventBus) Publish(ctx context.Context, e events.Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload && e.Progress != nil {
+		// Subscribers read large partial outputs from the step instead.
+		progress := *e.Progress
+		progress.Partial = nil
+		e.Progress = &progress
+		if payload, err = json.Marshal(e); err != nil {
+			return err
+		}
+	}
+	if len(payload) > maxNotifyPayload {
+		return fmt.Errorf("event of %d bytes is too large to publish", len(payload))
+	}
+
+	_, err = b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, eventChannel, string(payload))
+	return err
+}
+
+func (b *EventBus) Subscribe(taskID uuid.UUID) (<-chan events.Event, func()) {
+	return b.local.Subscribe(taskID)
+}
+
+// Listen delivers published events to the subscribers of this process
+// until ctx is done, reconnecting after connection failures. Events
+// published while it reconnects are lost.
+func (b *EventBus) Listen(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("event bus: %v; reconnecting", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (b *EventBus) listen(ctx context.Context) error {
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pgxConn := driverConn.(*stdlib.Conn).Conn()
+
+		if _, err := pgxConn.Exec(ctx, `LISTEN `+eventChannel); err != nil {
+			return err
+		}
+
+		for {
+			n, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+
+			var e events.Event
+			if err := json.Unmarshal([]byte(n.Payload), &e); err != nil {
+				log.Printf("event bus: decode event: %v", err)
+				continue
+			}
+			b.local.Publish(ctx, e)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+
+	"github.com/yeOmaNnn/orchestrator/internal/leader"
+)
+
+// LeaderLock is a leader.Backend on a Postgres session advisory lock. The
+// lock lives on a dedicated connection: if the process dies or the
+// connection drops, Postgres frees the lock at once and another replica
+// takes over on its next attempt. Fencing tokens and the holder's identity
+// are kept in leader_leases.
+type LeaderLock struct {
+	db     *sql.DB
+	name   string
+	holder string
+	key    int64
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewLeaderLock(db *sql.DB, name, holder string) *LeaderLock {
+	h := fnv.New64a()
+	h.Write([]byte("orchestrator/leader/" + name))
+
+	return &LeaderLock{
+		db:     db,
+		name:   name,
+		holder: holder,
+		key:    int64(h.Sum64()),
+	}
+}
+
+func (l *LeaderLock) Name() string {
+	return l.name
+}
+
+func (l *LeaderLock) TryAcquire(ctx context.Context) (int64, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		conn, err := l.db.Conn(ctx)
+		if err != nil {
+			return 0, false, err
+		}
+		l.conn = conn
+	}
+
+	var ok bool
+	if err := l.conn.QueryRowContext(
+		ctx,
+		`SELECT pg_try_advisory_lock($1)`,
+		l.key,
+	).Scan(&ok); err != nil {
+		l.closeConn()
+		return 0, false, err
+	}
+	if !ok {
+		// Do not hold a pooled connection while following.
+		l.closeConn()
+		return 0, false, nil
+	}
+
+	var token int64
+	if err := l.conn.QueryRowContext(
+		ctx,
+		`INSERT INTO leader_leases (name, holder, token, acquired_at, renewed_at)
+		 VALUES ($1, $2, 1, NOW(), NOW())
+		 ON CONFLICT (name) DO UPDATE
+		 SET holder = EXCLUDED.holder,
+		     token = leader_leases.token + 1,
+		     acquired_at = NOW(),
+		     renewed_at = NOW()
+		 RETURNING token`,
+		l.name,
+		l.holder,
+	).Scan(&token); err != nil {
+		l.closeConn()
+		return 0, false, fmt.Errorf("issue fencing token: %w", err)
+	}
+
+	return token, true, nil
+}
+
+func (l *LeaderLock) Renew(ctx context.Context, token int64) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return leader.ErrLost
+	}
+
+	res, err := l.conn.ExecContext(
+		ctx,
+		`UPDATE leader_leases
+		 SET renewed_at = NOW()
+		 WHERE name = $1
+		   AND token = $2`,
+		l.name,
+		token,
+	)
+	if err != nil {
+		// The session, and with it the lock, may be gone.
+		l.closeConn()
+		return errors.Join(leader.ErrLost, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return leader.ErrLost
+	}
+	return nil
+}
+
+func (l *LeaderLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+
+	_, err := l.conn.ExecContext(
+		ctx,
+		`SELECT pg_advisory_unlock($1)`,
+		l.key,
+	)
+	l.closeConn()
+	return err
+}
+
+func (l *LeaderLock) Current(ctx context.Context) (leader.Info, bool, error) {
+	info := leader.Info{Name: l.name}
+
+	err := l.db.QueryRowContext(
+		ctx,
+		`SELECT holder, token, acquired_at, renewed_at
+		 FROM leader_leases
+		 WHERE name = $1`,
+		l.name,
+	).Scan(&info.Holder, &info.Token, &info.AcquiredAt, &info.RenewedAt)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return leader.Info{}, false, nil
+	}
+	if err != nil {
+		return leader.Info{}, false, err
+	}
+	return info, true, nil
+}
+
+func (l *LeaderLock) closeConn() {
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+}
+
+// fenced appends to the WHERE clause of a write the condition that the
+// leadership in ctx, if any, still holds its lock, and the condition's
+// arguments to args.
+func fenced(ctx context.Context, args []any) (string, []any) {
+	fence, ok := leader.FenceFrom(ctx)
+	if !ok {
+		return "", args
+	}
+
+	n := len(args)
+	clause := fmt.Sprintf(`
+		   AND (SELECT token FROM leader_leases WHERE name = $%d) = $%d`, n+1, n+2)
+	return clause, append(args, fence.Lease, fence.Token)
+}
@@ -0,0 +1,208 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type ScheduleRepo struct {
+	db *sql.DB
+}
+
+func NewScheduleRepo(db *sql.DB) *ScheduleRepo {
+	return &ScheduleRepo{db: db}
+}
+
+const scheduleColumns = `id, name, cron, interval_seconds, timezone, goal,
+	timeout_seconds, overlap, catch_up, paused, next_run_at, last_run_at,
+	queued_runs, version, created_at, updated_at`
+
+func scanSchedule(row rowScanner) (domain.Schedule, error) {
+	var s domain.Schedule
+	err := row.Scan(
+		&s.ID,
+		&s.Name,
+		&s.Cron,
+		&s.IntervalSeconds,
+		&s.Timezone,
+		&s.Goal,
+		&s.TimeoutSeconds,
+		&s.Overlap,
+		&s.CatchUp,
+		&s.Paused,
+		&s.NextRunAt,
+		&s.LastRunAt,
+		&s.QueuedRuns,
+		&s.Version,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	return s, err
+}
+
+func scanSchedules(rows *sql.Rows) ([]domain.Schedule, error) {
+	out := []domain.Schedule{}
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func (r *ScheduleRepo) Create(
+	ctx context.Context,
+	s *domain.Schedule,
+) error {
+	return r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO schedules
+		 (id, name, cron, interval_seconds, timezone, goal, timeout_seconds,
+		  overlap, catch_up, paused, next_run_at, queued_runs, version)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, 0, 1)
+		 RETURNING version, created_at, updated_at`,
+		s.ID,
+		s.Name,
+		s.Cron,
+		s.IntervalSeconds,
+		s.Timezone,
+		s.Goal,
+		s.TimeoutSeconds,
+		s.Overlap,
+		s.CatchUp,
+		s.Paused,
+		s.NextRunAt,
+	).Scan(&s.Version, &s.CreatedAt, &s.UpdatedAt)
+}
+
+func (r *ScheduleRepo) GetByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (*domain.Schedule, error) {
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT `+scheduleColumns+`
+		 FROM schedules
+		 WHERE id = $1`,
+		id,
+	)
+
+	s, err := scanSchedule(row)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *ScheduleRepo) List(
+	ctx context.Context,
+) ([]domain.Schedule, error) {
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+scheduleColumns+`
+		 FROM schedules
+		 ORDER BY created_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSchedules(rows)
+}
+
+func (r *ScheduleRepo) Update(
+	ctx context.Context,
+	s *domain.Schedule,
+) (bool, error) {
+
+	fence, args := fenced(ctx, []any{
+		s.ID,
+		s.Version,
+		s.Name,
+		s.Cron,
+		s.IntervalSeconds,
+		s.Timezone,
+		s.Goal,
+		s.TimeoutSeconds,
+		s.Overlap,
+		s.CatchUp,
+		s.Paused,
+		s.NextRunAt,
+		s.LastRunAt,
+		s.QueuedRuns,
+	})
+	err := r.db.QueryRowContext(
+		ctx,
+		`UPDATE schedules
+		 SET name = $3,
+		     cron = $4,
+		     interval_seconds = $5,
+		     timezone = $6,
+		     goal = $7,
+		     timeout_seconds = $8,
+		     overlap = $9,
+		     catch_up = $10,
+		     paused = $11,
+		     next_run_at = $12,
+		     last_run_at = $13,
+		     queued_runs = $14,
+		     version = version + 1,
+		     updated_at = NOW()
+		 WHERE id = $1
+		   AND version = $2`+fence+`
+		 RETURNING version, updated_at`,
+		args...,
+	).Scan(&s.Version, &s.UpdatedAt)
+
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *ScheduleRepo) Delete(
+	ctx context.Context,
+	id uuid.UUID,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM schedules WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+func (r *ScheduleRepo) ListDue(
+	ctx context.Context,
+	now time.Time,
+) ([]domain.Schedule, error) {
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+scheduleColumns+`
+		 FROM schedules
+		 WHERE NOT paused
+		   AND (next_run_at <= $1 OR queued_runs > 0)
+		 ORDER BY next_run_at`,
+		now,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSchedules(rows)
+}
@@ -3,13 +3,112 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/yeOmaNnn/orchestrator/internal/domain"
+	"github.com/yeOmaNnn/orchestrator/internal/retry"
 )
 
+const stepColumns = `id, task_id, agent, input, output, status,
+	retry_count, attempt, retry_policy, first_attempt_at, history,
+	depends_on, required_labels,
+	next_run_at, last_error, timeout_seconds,
+	locked_at, locked_by, started_at, finished_at,
+	created_at, updated_at, progress, job`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanStep(row rowScanner) (domain.Step, error) {
+	var (
+		s         domain.Step
+		input     []byte
+		output    []byte
+		policy    []byte
+		history   []byte
+		dependsOn uuidArray
+		labels    textArray
+		progress  []byte
+		job       []byte
+	)
+	if err := row.Scan(
+		&s.ID,
+		&s.TaskID,
+		&s.Agent,
+		&input,
+		&output,
+		&s.Status,
+		&s.RetryCount,
+		&s.Attempt,
+		&policy,
+		&s.FirstAttemptAt,
+		&history,
+		&dependsOn,
+		&labels,
+		&s.NextRunAt,
+		&s.LastError,
+		&s.TimeoutSeconds,
+		&s.LockedAt,
+		&s.LockedBy,
+		&s.StartedAt,
+		&s.FinishedAt,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+		&progress,
+		&job,
+	); err != nil {
+		return domain.Step{}, err
+	}
+	if len(policy) > 0 {
+		if err := json.Unmarshal(policy, &s.RetryPolicy); err != nil {
+			return domain.Step{}, fmt.Errorf("decode retry policy: %w", err)
+		}
+	}
+	if len(history) > 0 {
+		if err := json.Unmarshal(history, &s.History); err != nil {
+			return domain.Step{}, fmt.Errorf("decode history: %w", err)
+		}
+	}
+	if len(progress) > 0 {
+		if err := json.Unmarshal(progress, &s.Progress); err != nil {
+			return domain.Step{}, fmt.Errorf("decode progress: %w", err)
+		}
+	}
+	if len(job) > 0 {
+		if err := json.Unmarshal(job, &s.Job); err != nil {
+			return domain.Step{}, fmt.Errorf("decode job: %w", err)
+		}
+	}
+	s.Input = input
+	s.Output = output
+	s.DependsOn = dependsOn
+	s.RequiredLabels = labels
+	return s, nil
+}
+
+func scanSteps(rows *sql.Rows) ([]domain.Step, error) {
+	var steps []domain.Step
+	for rows.Next() {
+		s, err := scanStep(rows)
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, s)
+	}
+	return steps, rows.Err()
+}
+
+func marshalPolicy(p *retry.Policy) ([]byte, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
+}
+
 type StepRepo struct {
 	db *sql.DB
 }
@@ -30,17 +129,26 @@ func (r *StepRepo) CreateMany(
 	defer tx.Rollback()
 
 	for _, s := range steps {
-		_, err := tx.ExecContext(
+		policy, err := marshalPolicy(s.RetryPolicy)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(
 			ctx,
 			`INSERT INTO steps
-			 (id, task_id, agent, input, status, depends_on, created_at, updated_at)
-			 VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())`,
+			 (id, task_id, agent, input, status, depends_on, required_labels,
+			  retry_policy, timeout_seconds, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())`,
 			s.ID,
 			s.TaskID,
 			s.Agent,
 			s.Input,
 			s.Status,
 			s.DependsOn,
+			textArray(s.RequiredLabels),
+			policy,
+			s.TimeoutSeconds,
 		)
 		if err != nil {
 			return err
@@ -50,6 +158,26 @@ func (r *StepRepo) CreateMany(
 	return tx.Commit()
 }
 
+func (r *StepRepo) GetByID(
+	ctx context.Context,
+	id uuid.UUID,
+) (*domain.Step, error) {
+
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT `+stepColumns+`
+		 FROM steps
+		 WHERE id = $1`,
+		id,
+	)
+
+	s, err := scanStep(row)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
 func (r *StepRepo) GetByTask(
 	ctx context.Context,
 	taskID uuid.UUID,
@@ -57,11 +185,10 @@ func (r *StepRepo) GetByTask(
 
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT id, task_id, agent, input, output,
-		        status, retry_count, depends_on, 
-				locked_at, locked_by, created_at, update_at
+		`SELECT `+stepColumns+`
 		 FROM steps
-		 WHERE task_id = $1`,
+		 WHERE task_id = $1
+		 ORDER BY created_at`,
 		taskID,
 	)
 	if err != nil {
@@ -69,60 +196,236 @@ func (r *StepRepo) GetByTask(
 	}
 	defer rows.Close()
 
-	var steps []domain.Step
-	for rows.Next() {
-		var s domain.Step
-		if err := rows.Scan(
-			&s.ID,
-			&s.TaskID,
-			&s.Agent,
-			&s.Input,
-			&s.Output,
-			&s.Status,
-			&s.RetryCount,
-			&s.DependsOn,
-			&s.LockedAt, 
-			&s.LockedBy, 
-			&s.CreatedAt, 
-			&s.UpdatedAt,
-		); err != nil {
+	return scanSteps(rows)
+}
+
+// stepUpdate sets every mutable column of a step; callers append the WHERE
+// clause, with the step ID as $1.
+const stepUpdate = `UPDATE steps
+	 SET status = $2,
+	     output = $3,
+	     retry_count = $4,
+	     attempt = $5,
+	     first_attempt_at = $6,
+	     next_run_at = $7,
+	     last_error = $8,
+	     locked_at = $9,
+	     locked_by = $10,
+	     started_at = $11,
+	     finished_at = $12,
+	     agent = $13,
+	     input = $14,
+	     history = $15,
+	     required_labels = $16,
+	     progress = $17,
+	     job = $18,
+	     updated_at = NOW()`
+
+func stepUpdateArgs(step *domain.Step) ([]any, error) {
+	history, err := json.Marshal(step.History)
+	if err != nil {
+		return nil, err
+	}
+	if step.History == nil {
+		history = []byte(`[]`)
+	}
+	progress, err := marshalProgress(step.Progress)
+	if err != nil {
+		return nil, err
+	}
+	var job []byte
+	if step.Job != nil {
+		if job, err = json.Marshal(step.Job); err != nil {
 			return nil, err
 		}
-		steps = append(steps, s)
 	}
 
-	return steps, nil
+	return []any{
+		step.ID,
+		step.Status,
+		step.Output,
+		step.RetryCount,
+		step.Attempt,
+		step.FirstAttemptAt,
+		step.NextRunAt,
+		step.LastError,
+		step.LockedAt,
+		step.LockedBy,
+		step.StartedAt,
+		step.FinishedAt,
+		step.Agent,
+		step.Input,
+		history,
+		textArray(step.RequiredLabels),
+		progress,
+		job,
+	}, nil
+}
+
+func marshalProgress(p *domain.StepProgress) ([]byte, error) {
+	if p == nil {
+		return nil, nil
+	}
+	return json.Marshal(p)
 }
 
 func (r *StepRepo) Update(
 	ctx context.Context,
 	step *domain.Step,
 ) error {
-	_, err := r.db.ExecContext(
+	args, err := stepUpdateArgs(step)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, stepUpdate+` WHERE id = $1`, args...)
+	return err
+}
+
+func (r *StepRepo) UpdateIfOwned(
+	ctx context.Context,
+	step *domain.Step,
+	owner string,
+) (bool, error) {
+	args, err := stepUpdateArgs(step)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(
+		ctx,
+		stepUpdate+`
+		 WHERE id = $1
+		   AND status = 'IN_PROGRESS'
+		   AND ($19::text = '' OR locked_by = $19::text)`,
+		append(args, owner)...,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *StepRepo) ListAwaiting(
+	ctx context.Context,
+) ([]domain.Step, error) {
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+stepColumns+`
+		 FROM steps
+		 WHERE status = $1
+		 ORDER BY updated_at`,
+		domain.StepAwaitingCallback,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSteps(rows)
+}
+
+func (r *StepRepo) UpdateIfAwaiting(
+	ctx context.Context,
+	step *domain.Step,
+	attempt int,
+) (bool, error) {
+	args, err := stepUpdateArgs(step)
+	if err != nil {
+		return false, err
+	}
+
+	fence, args := fenced(ctx, append(args, attempt))
+	res, err := r.db.ExecContext(
+		ctx,
+		stepUpdate+`
+		 WHERE id = $1
+		   AND status = 'AWAITING_CALLBACK'
+		   AND attempt = $19`+fence,
+		args...,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *StepRepo) UpdateProgress(
+	ctx context.Context,
+	id uuid.UUID,
+	owner string,
+	progress *domain.StepProgress,
+) (bool, error) {
+	data, err := marshalProgress(progress)
+	if err != nil {
+		return false, err
+	}
+
+	res, err := r.db.ExecContext(
 		ctx,
 		`UPDATE steps
-		 SET status = $2,
-		     output = $3,
-		     retry_count = $4, 
-			 locked_at = $5, 
-			 locked_by = $6, 
-			 updated_at = NOW()
-		 WHERE id = $1`,
-		step.ID,
-		step.Status,
-		step.Output,
-		step.RetryCount,
-		step.LockedAt, 
-		step.LockedBy,
+		 SET progress = $2,
+		     updated_at = NOW()
+		 WHERE id = $1
+		   AND status = 'IN_PROGRESS'
+		   AND ($3::text = '' OR locked_by = $3::text)`,
+		id,
+		data,
+		owner,
 	)
-	return err
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *StepRepo) Statuses(
+	ctx context.Context,
+	ids ...uuid.UUID,
+) (map[uuid.UUID]domain.StepStatus, error) {
+	out := make(map[uuid.UUID]domain.StepStatus, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, status
+		 FROM steps
+		 WHERE id = ANY($1)`,
+		ids,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			id     uuid.UUID
+			status domain.StepStatus
+		)
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, err
+		}
+		out[id] = status
+	}
+	return out, rows.Err()
 }
 
 func (r *StepRepo) AcquireReadySteps(
 	ctx context.Context,
 	taskID uuid.UUID,
 	limit int,
-	workerID string,
+	worker domain.Worker,
+	paused []string,
 ) ([]domain.Step, error) {
 
 	rows, err := r.db.QueryContext(ctx, `
@@ -131,6 +434,7 @@ func (r *StepRepo) AcquireReadySteps(
 			status = 'IN_PROGRESS',
 			locked_at = NOW(),
 			locked_by = $3,
+			started_at = NOW(),
 			updated_at = NOW()
 		WHERE id IN (
 			SELECT s.id
@@ -138,43 +442,30 @@ func (r *StepRepo) AcquireReadySteps(
 			WHERE s.task_id = $1
 			  AND s.status = 'WAITING'
 			  AND (s.next_run_at IS NULL OR s.next_run_at <= NOW())
+			  AND (cardinality($4::text[]) = 0 OR s.agent = ANY($4::text[]))
+			  AND s.required_labels <@ $5::text[]
+			  AND NOT (s.agent = ANY($6::text[]))
 			  AND NOT EXISTS (
 				SELECT 1
-				FROM step_dependencies d
-				JOIN steps dep ON dep.id = d.depends_on
-				WHERE d.step_id = s.id
+				FROM steps dep
+				WHERE dep.id = ANY(s.depends_on)
 				  AND dep.status != 'DONE'
 			  )
 			ORDER BY s.created_at
 			LIMIT $2
 			FOR UPDATE SKIP LOCKED
 		)
-		RETURNING id, task_id, agent, input, output, status, retry_count
-	`, taskID, limit, workerID)
+		RETURNING `+stepColumns,
+		taskID, limit, worker.ID,
+		textArray(worker.Capabilities), textArray(worker.Labels),
+		textArray(paused))
 
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var steps []domain.Step
-	for rows.Next() {
-		var s domain.Step
-		if err := rows.Scan(
-			&s.ID,
-			&s.TaskID,
-			&s.Agent,
-			&s.Input,
-			&s.Output,
-			&s.Status,
-			&s.RetryCount,
-		); err != nil {
-			return nil, err
-		}
-		steps = append(steps, s)
-	}
-
-	return steps, nil
+	return scanSteps(rows)
 }
 
 func (r *StepRepo) CancelByTask(
@@ -198,11 +489,69 @@ func (r *StepRepo) CancelByTask(
 	return err
 }
 
+func (r *StepRepo) TransitionStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	from domain.StepStatus,
+	to domain.StepStatus,
+	reason string,
+) (bool, error) {
+
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE steps
+		 SET status = $3,
+		     last_error = $4,
+		     updated_at = NOW()
+		 WHERE id = $1
+		   AND status = $2`,
+		id,
+		from,
+		to,
+		reason,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *StepRepo) Release(
+	ctx context.Context,
+	workerID string,
+	ids ...uuid.UUID,
+) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE steps
+		 SET
+			status = 'WAITING',
+			locked_at = NULL,
+			locked_by = NULL,
+			started_at = NULL,
+			updated_at = NOW()
+		 WHERE id = ANY($1)
+		   AND status = 'IN_PROGRESS'
+		   AND locked_by = $2`,
+		ids,
+		workerID,
+	)
+
+	return err
+}
+
 func (r *StepRepo) ReleaseStaleLocks(
 	ctx context.Context,
 	ttl time.Duration,
 ) error {
 
+	fence, args := fenced(ctx, []any{fmt.Sprintf("%f seconds", ttl.Seconds())})
 	_, err := r.db.ExecContext(
 		ctx,
 		`UPDATE steps
@@ -212,9 +561,9 @@ func (r *StepRepo) ReleaseStaleLocks(
 			locked_by = NULL,
 			updated_at = NOW()
 		 WHERE status = 'IN_PROGRESS'
-		   AND locked_at < NOW() - $1::interval`,
-		fmt.Sprintf("%f seconds", ttl.Seconds()),
+		   AND locked_at < NOW() - $1::interval`+fence,
+		args...,
 	)
 
 	return err
-}
\ No newline at end of file
+}
@@ -22,11 +22,15 @@ func (r *TaskRepo) Create(
 ) error {
 	_, err := r.db.ExecContext(
 		ctx,
-		`INSERT INTO tasks (id, goal, status)
-		 VALUES ($1, $2, $3)`,
+		`INSERT INTO tasks (id, goal, status, deadline, schedule_id, labels, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
 		task.ID,
 		task.Goal,
 		task.Status,
+		task.Deadline,
+		task.ScheduleID,
+		textArray(task.Labels),
+		task.CreatedAt,
 	)
 	return err
 }
@@ -38,18 +42,14 @@ func (r *TaskRepo) GetByID(
 
 	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, goal, status
+		`SELECT `+taskColumns+`
 		 FROM tasks
 		 WHERE id = $1`,
 		id,
 	)
 
-	var task domain.Task
-	if err := row.Scan(
-		&task.ID,
-		&task.Goal,
-		&task.Status,
-	); err != nil {
+	task, err := scanTask(row)
+	if err != nil {
 		return nil, err
 	}
 
@@ -72,13 +72,37 @@ func (r *TaskRepo) UpdateStatus(
 	return err
 }
 
+func (r *TaskRepo) TransitionStatus(
+	ctx context.Context,
+	id uuid.UUID,
+	from domain.TaskStatus,
+	to domain.TaskStatus,
+) (bool, error) {
+	res, err := r.db.ExecContext(
+		ctx,
+		`UPDATE tasks
+		 SET status = $3
+		 WHERE id = $1
+		   AND status = $2`,
+		id,
+		from,
+		to,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
 func (r *TaskRepo) ListActive(
 	ctx context.Context,
 ) ([]domain.Task, error) {
 
 	rows, err := r.db.QueryContext(
 		ctx,
-		`SELECT id, goal, status
+		`SELECT `+taskColumns+`
 		 FROM tasks
 		 WHERE status IN ($1, $2)`,
 		domain.TaskPending,
@@ -89,19 +113,86 @@ func (r *TaskRepo) ListActive(
 	}
 	defer rows.Close()
 
-	var tasks []domain.Task
+	return scanTasks(rows)
+}
+
+func (r *TaskRepo) ListBySchedule(
+	ctx context.Context,
+	scheduleID uuid.UUID,
+	limit int,
+) ([]domain.Task, error) {
+
+	query := `SELECT ` + taskColumns + `
+		 FROM tasks
+		 WHERE schedule_id = $1
+		 ORDER BY created_at DESC`
+	args := []any{scheduleID}
+	if limit > 0 {
+		query += ` LIMIT $2`
+		args = append(args, limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+func (r *TaskRepo) ListActiveBySchedule(
+	ctx context.Context,
+	scheduleID uuid.UUID,
+) ([]domain.Task, error) {
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+taskColumns+`
+		 FROM tasks
+		 WHERE schedule_id = $1
+		   AND status IN ($2, $3, $4)`,
+		scheduleID,
+		domain.TaskPending,
+		domain.TaskRunning,
+		domain.TaskPaused,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanTasks(rows)
+}
+
+const taskColumns = `id, goal, status, created_at, deadline, schedule_id, labels`
+
+func scanTask(row rowScanner) (domain.Task, error) {
+	var (
+		t      domain.Task
+		labels textArray
+	)
+	err := row.Scan(
+		&t.ID,
+		&t.Goal,
+		&t.Status,
+		&t.CreatedAt,
+		&t.Deadline,
+		&t.ScheduleID,
+		&labels,
+	)
+	t.Labels = labels
+	return t, err
+}
 
+func scanTasks(rows *sql.Rows) ([]domain.Task, error) {
+	tasks := []domain.Task{}
 	for rows.Next() {
-		var t domain.Task
-		if err := rows.Scan(
-			&t.ID,
-			&t.Goal,
-			&t.Status,
-		); err != nil {
+		t, err := scanTask(rows)
+		if err != nil {
 			return nil, err
 		}
 		tasks = append(tasks, t)
 	}
-
-	return tasks, nil
+	return tasks, rows.Err()
 }
@@ -0,0 +1,95 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+type WASMModuleRepo struct {
+	db *sql.DB
+}
+
+func NewWASMModuleRepo(db *sql.DB) *WASMModuleRepo {
+	return &WASMModuleRepo{db: db}
+}
+
+func (r *WASMModuleRepo) Put(
+	ctx context.Context,
+	module *agent.WASMModule,
+	data []byte,
+) error {
+	// The no-op update makes RETURNING report the stored row when the
+	// module was uploaded before.
+	return r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO wasm_modules (digest, data, size)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (digest) DO UPDATE SET digest = EXCLUDED.digest
+		 RETURNING size, created_at`,
+		module.Digest,
+		data,
+		module.Size,
+	).Scan(&module.Size, &module.CreatedAt)
+}
+
+func (r *WASMModuleRepo) Get(
+	ctx context.Context,
+	digest string,
+) ([]byte, error) {
+	var data []byte
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT data FROM wasm_modules WHERE digest = $1`,
+		digest,
+	).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", digest, agent.ErrModuleNotFound)
+	}
+	return data, err
+}
+
+func (r *WASMModuleRepo) List(
+	ctx context.Context,
+) ([]agent.WASMModule, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT digest, size, created_at
+		 FROM wasm_modules
+		 ORDER BY created_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []agent.WASMModule{}
+	for rows.Next() {
+		var m agent.WASMModule
+		if err := rows.Scan(&m.Digest, &m.Size, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *WASMModuleRepo) Delete(
+	ctx context.Context,
+	digest string,
+) (bool, error) {
+	res, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM wasm_modules WHERE digest = $1`,
+		digest,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
@@ -0,0 +1,104 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type WorkerRepo struct {
+	db *sql.DB
+}
+
+func NewWorkerRepo(db *sql.DB) *WorkerRepo {
+	return &WorkerRepo{db: db}
+}
+
+func (r *WorkerRepo) Register(
+	ctx context.Context,
+	worker *domain.Worker,
+) error {
+	return r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO workers (id, hostname, capabilities, labels, started_at, heartbeat_at)
+		 VALUES ($1, $2, $3, $4, NOW(), NOW())
+		 ON CONFLICT (id) DO UPDATE
+		 SET hostname = EXCLUDED.hostname,
+		     capabilities = EXCLUDED.capabilities,
+		     labels = EXCLUDED.labels,
+		     started_at = EXCLUDED.started_at,
+		     heartbeat_at = EXCLUDED.heartbeat_at
+		 RETURNING started_at, heartbeat_at`,
+		worker.ID,
+		worker.Hostname,
+		textArray(worker.Capabilities),
+		textArray(worker.Labels),
+	).Scan(&worker.StartedAt, &worker.HeartbeatAt)
+}
+
+func (r *WorkerRepo) Heartbeat(
+	ctx context.Context,
+	id string,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE workers
+		 SET heartbeat_at = NOW()
+		 WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+func (r *WorkerRepo) Deregister(
+	ctx context.Context,
+	id string,
+) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`DELETE FROM workers WHERE id = $1`,
+		id,
+	)
+	return err
+}
+
+func (r *WorkerRepo) List(
+	ctx context.Context,
+) ([]domain.Worker, error) {
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, hostname, capabilities, labels, started_at, heartbeat_at
+		 FROM workers
+		 ORDER BY started_at`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var workers []domain.Worker
+	for rows.Next() {
+		var (
+			w      domain.Worker
+			caps   textArray
+			labels textArray
+		)
+		if err := rows.Scan(
+			&w.ID,
+			&w.Hostname,
+			&caps,
+			&labels,
+			&w.StartedAt,
+			&w.HeartbeatAt,
+		); err != nil {
+			return nil, err
+		}
+		w.Capabilities = caps
+		w.Labels = labels
+		workers = append(workers, w)
+	}
+
+	return workers, rows.Err()
+}
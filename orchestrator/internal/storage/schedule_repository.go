@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type ScheduleRepository interface {
+	Create(
+		ctx context.Context,
+		schedule *domain.Schedule,
+	) error
+
+	GetByID(
+		ctx context.Context,
+		id uuid.UUID,
+	) (*domain.Schedule, error)
+
+	List(
+		ctx context.Context,
+	) ([]domain.Schedule, error)
+
+	// Update stores every field of the schedule if its version still
+	// matches, and bumps the version. It reports false, without writing,
+	// when the schedule changed since it was read, or when ctx carries a
+	// leader.Fence that is no longer current.
+	Update(
+		ctx context.Context,
+		schedule *domain.Schedule,
+	) (bool, error)
+
+	Delete(
+		ctx context.Context,
+		id uuid.UUID,
+	) error
+
+	// ListDue returns unpaused schedules that are due at now or have
+	// queued runs.
+	ListDue(
+		ctx context.Context,
+		now time.Time,
+	) ([]domain.Schedule, error)
+}
@@ -14,6 +14,11 @@ type StepRepository interface {
 		steps []domain.Step,
 	) error
 
+	GetByID(
+		ctx context.Context,
+		id uuid.UUID,
+	) (*domain.Step, error)
+
 	GetByTask(
 		ctx context.Context,
 		taskID uuid.UUID,
@@ -22,23 +27,88 @@ type StepRepository interface {
 	Update(
 		ctx context.Context,
 		step *domain.Step,
-	) error 
+	) error
+
+	// UpdateIfOwned persists the step like Update, but only while the
+	// stored step is still IN_PROGRESS and, when owner is set, locked by
+	// owner. It reports false without writing if the step was cancelled,
+	// released or handed to another worker in the meantime.
+	UpdateIfOwned(
+		ctx context.Context,
+		step *domain.Step,
+		owner string,
+	) (bool, error)
+
+	// UpdateProgress records the progress of a running step, on the same
+	// terms as UpdateIfOwned.
+	UpdateProgress(
+		ctx context.Context,
+		id uuid.UUID,
+		owner string,
+		progress *domain.StepProgress,
+	) (bool, error)
 
+	// ListAwaiting returns the steps awaiting an asynchronous job.
+	ListAwaiting(
+		ctx context.Context,
+	) ([]domain.Step, error)
+
+	// UpdateIfAwaiting persists the step like Update, but only while the
+	// stored step is still AWAITING_CALLBACK on the given attempt. It
+	// reports false without writing if the job was settled or the step
+	// cancelled in the meantime, or if ctx carries a leader.Fence that is
+	// no longer current.
+	UpdateIfAwaiting(
+		ctx context.Context,
+		step *domain.Step,
+		attempt int,
+	) (bool, error)
+
+	// Statuses returns the current status of each of the given steps.
+	Statuses(
+		ctx context.Context,
+		ids ...uuid.UUID,
+	) (map[uuid.UUID]domain.StepStatus, error)
+
+	// AcquireReadySteps locks up to limit ready steps of the task that the
+	// worker is eligible for, skipping steps for any agent in paused.
 	AcquireReadySteps(
-		ctx context.Context, 
-		taskID uuid.UUID, 
+		ctx context.Context,
+		taskID uuid.UUID,
 		limit int,
-		workerID string, 
-	) ([]domain.Step, error) 
+		worker domain.Worker,
+		paused []string,
+	) ([]domain.Step, error)
 
 	CancelByTask(
 		ctx context.Context,
-		 taskID uuid.UUID,
-	) error 
+		taskID uuid.UUID,
+	) error
+
+	// TransitionStatus moves a step from one status to another only if it
+	// is still in the expected status, and records reason as LastError.
+	// It reports whether the transition happened.
+	TransitionStatus(
+		ctx context.Context,
+		id uuid.UUID,
+		from domain.StepStatus,
+		to domain.StepStatus,
+		reason string,
+	) (bool, error)
+
+	// Release returns steps locked by workerID to WAITING and clears the
+	// lock without counting an attempt.
+	Release(
+		ctx context.Context,
+		workerID string,
+		ids ...uuid.UUID,
+	) error
 
+	// ReleaseStaleLocks returns steps locked longer than ttl to WAITING.
+	// It writes nothing when ctx carries a leader.Fence that is no longer
+	// current.
 	ReleaseStaleLocks(
-		ctx context.Context, 
+		ctx context.Context,
 		ttl time.Duration,
-	) error 
+	) error
 }
-
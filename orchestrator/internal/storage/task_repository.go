@@ -23,6 +23,30 @@ type TaskRepository interface {
 		status domain.TaskStatus,
 		) error
 
+	// TransitionStatus sets the task to status to only if it is currently
+	// in status from, and reports whether it did.
+	TransitionStatus(
+		ctx context.Context,
+		id uuid.UUID,
+		from domain.TaskStatus,
+		to domain.TaskStatus,
+		) (bool, error)
+
 	ListActive(
 		ctx context.Context) ([]domain.Task, error)
+
+	// ListBySchedule returns the newest tasks spawned by a schedule,
+	// up to limit when limit is positive.
+	ListBySchedule(
+		ctx context.Context,
+		scheduleID uuid.UUID,
+		limit int,
+	) ([]domain.Task, error)
+
+	// ListActiveBySchedule returns the tasks spawned by a schedule that
+	// have not finished: pending, running or paused.
+	ListActiveBySchedule(
+		ctx context.Context,
+		scheduleID uuid.UUID,
+	) ([]domain.Task, error)
 }
@@ -0,0 +1,35 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/yeOmaNnn/orchestrator/internal/agent"
+)
+
+// WASMModuleRepository stores uploaded WebAssembly modules, keyed by
+// digest.
+type WASMModuleRepository interface {
+	// Put stores a module under module.Digest. Storing a module that is
+	// already stored changes nothing.
+	Put(
+		ctx context.Context,
+		module *agent.WASMModule,
+		data []byte,
+	) error
+
+	// Get returns the bytes of a module, or agent.ErrModuleNotFound.
+	Get(
+		ctx context.Context,
+		digest string,
+	) ([]byte, error)
+
+	List(
+		ctx context.Context,
+	) ([]agent.WASMModule, error)
+
+	// Delete reports false when no module has the digest.
+	Delete(
+		ctx context.Context,
+		digest string,
+	) (bool, error)
+}
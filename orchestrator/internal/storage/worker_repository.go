@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/yeOmaNnn/orchestrator/internal/domain"
+)
+
+type WorkerRepository interface {
+	Register(
+		ctx context.Context,
+		worker *domain.Worker,
+	) error
+
+	Heartbeat(
+		ctx context.Context,
+		id string,
+	) error
+
+	Deregister(
+		ctx context.Context,
+		id string,
+	) error
+
+	List(
+		ctx context.Context,
+	) ([]domain.Worker, error)
+}
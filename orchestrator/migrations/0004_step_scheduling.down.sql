@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_steps_locked_at;
+
+ALTER TABLE steps
+    DROP COLUMN IF EXISTS finished_at,
+    DROP COLUMN IF EXISTS started_at,
+    DROP COLUMN IF EXISTS locked_by,
+    DROP COLUMN IF EXISTS locked_at,
+    DROP COLUMN IF EXISTS timeout_seconds,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS next_run_at,
+    DROP COLUMN IF EXISTS max_attempts,
+    DROP COLUMN IF EXISTS attempt;
+
+ALTER TABLE tasks RENAME COLUMN goal TO name;
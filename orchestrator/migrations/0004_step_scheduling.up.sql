@@ -0,0 +1,16 @@
+ALTER TABLE tasks RENAME COLUMN name TO goal;
+
+ALTER TABLE steps
+    ADD COLUMN attempt INT NOT NULL DEFAULT 0,
+    ADD COLUMN max_attempts INT NOT NULL DEFAULT 3,
+    ADD COLUMN next_run_at TIMESTAMP,
+    ADD COLUMN last_error TEXT NOT NULL DEFAULT '',
+    ADD COLUMN timeout_seconds INT NOT NULL DEFAULT 30,
+    ADD COLUMN locked_at TIMESTAMP,
+    ADD COLUMN locked_by TEXT,
+    ADD COLUMN started_at TIMESTAMP,
+    ADD COLUMN finished_at TIMESTAMP;
+
+CREATE INDEX idx_steps_locked_at
+    ON steps(locked_at)
+    WHERE status = 'IN_PROGRESS';
@@ -0,0 +1,12 @@
+CREATE TABLE workers (
+    id TEXT PRIMARY KEY,
+
+    hostname TEXT NOT NULL DEFAULT '',
+    capabilities TEXT[] NOT NULL DEFAULT '{}',
+
+    started_at TIMESTAMP NOT NULL DEFAULT now(),
+    heartbeat_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_workers_heartbeat_at
+    ON workers(heartbeat_at);
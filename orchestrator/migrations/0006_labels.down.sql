@@ -0,0 +1,2 @@
+ALTER TABLE workers DROP COLUMN IF EXISTS labels;
+ALTER TABLE steps DROP COLUMN IF EXISTS required_labels;
@@ -0,0 +1,5 @@
+ALTER TABLE steps
+    ADD COLUMN required_labels TEXT[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE workers
+    ADD COLUMN labels TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,4 @@
+ALTER TABLE steps
+    DROP COLUMN IF EXISTS first_attempt_at,
+    DROP COLUMN IF EXISTS retry_policy,
+    ADD COLUMN max_attempts INT NOT NULL DEFAULT 3;
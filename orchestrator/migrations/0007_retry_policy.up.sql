@@ -0,0 +1,4 @@
+ALTER TABLE steps
+    DROP COLUMN max_attempts,
+    ADD COLUMN retry_policy JSONB,
+    ADD COLUMN first_attempt_at TIMESTAMP;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS dead_letters;
+ALTER TABLE steps DROP COLUMN IF EXISTS history;
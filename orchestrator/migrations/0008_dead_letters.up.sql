@@ -0,0 +1,28 @@
+ALTER TABLE steps
+    ADD COLUMN history JSONB NOT NULL DEFAULT '[]';
+
+CREATE TABLE dead_letters (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    step_id UUID NOT NULL,
+    task_id UUID NOT NULL,
+
+    agent TEXT NOT NULL,
+    input JSONB NOT NULL,
+    attempts JSONB NOT NULL DEFAULT '[]',
+    last_error TEXT NOT NULL DEFAULT '',
+
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    requeued_at TIMESTAMP,
+
+    CONSTRAINT fk_dead_letters_step
+        FOREIGN KEY (step_id)
+        REFERENCES steps(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX idx_dead_letters_agent
+    ON dead_letters(agent);
+
+CREATE INDEX idx_dead_letters_task_id
+    ON dead_letters(task_id);
@@ -0,0 +1,2 @@
+UPDATE tasks SET status = 'RUNNING' WHERE status = 'PAUSED';
+DROP TABLE IF EXISTS agent_pauses;
@@ -0,0 +1,6 @@
+CREATE TABLE agent_pauses (
+    agent TEXT PRIMARY KEY,
+
+    reason TEXT NOT NULL DEFAULT '',
+    paused_at TIMESTAMP NOT NULL DEFAULT now()
+);
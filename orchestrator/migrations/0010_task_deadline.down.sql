@@ -0,0 +1,2 @@
+UPDATE tasks SET status = 'FAILED' WHERE status = 'TIMED_OUT';
+ALTER TABLE tasks DROP COLUMN IF EXISTS deadline;
@@ -0,0 +1,33 @@
+CREATE TABLE schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    name TEXT NOT NULL DEFAULT '',
+    cron TEXT NOT NULL DEFAULT '',
+    interval_seconds INT NOT NULL DEFAULT 0,
+    timezone TEXT NOT NULL DEFAULT 'UTC',
+    goal TEXT NOT NULL,
+    timeout_seconds INT NOT NULL DEFAULT 0,
+
+    overlap TEXT NOT NULL DEFAULT 'skip',
+    catch_up TEXT NOT NULL DEFAULT 'none',
+    paused BOOLEAN NOT NULL DEFAULT false,
+
+    next_run_at TIMESTAMP NOT NULL,
+    last_run_at TIMESTAMP,
+    queued_runs INT NOT NULL DEFAULT 0,
+    version INT NOT NULL DEFAULT 1,
+
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_schedules_next_run_at
+    ON schedules(next_run_at)
+    WHERE NOT paused;
+
+ALTER TABLE tasks
+    ADD COLUMN schedule_id UUID REFERENCES schedules(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_tasks_schedule_id
+    ON tasks(schedule_id, created_at DESC)
+    WHERE schedule_id IS NOT NULL;
@@ -0,0 +1,9 @@
+CREATE TABLE leader_leases (
+    name TEXT PRIMARY KEY,
+
+    holder TEXT NOT NULL,
+    token BIGINT NOT NULL,
+
+    acquired_at TIMESTAMP NOT NULL DEFAULT now(),
+    renewed_at TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,7 @@
+CREATE TABLE agents (
+    name TEXT PRIMARY KEY,
+    config JSONB NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+ALTER TABLE tasks
+    ADD COLUMN labels TEXT[] NOT NULL DEFAULT '{}';
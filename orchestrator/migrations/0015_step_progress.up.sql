@@ -0,0 +1,2 @@
+ALTER TABLE steps
+    ADD COLUMN progress JSONB;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_steps_awaiting_callback;
+ALTER TABLE steps DROP COLUMN IF EXISTS job;
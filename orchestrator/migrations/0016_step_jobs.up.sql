@@ -0,0 +1,6 @@
+ALTER TABLE steps
+    ADD COLUMN job JSONB;
+
+CREATE INDEX idx_steps_awaiting_callback
+    ON steps (updated_at)
+    WHERE status = 'AWAITING_CALLBACK';
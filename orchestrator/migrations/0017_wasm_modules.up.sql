@@ -0,0 +1,7 @@
+CREATE TABLE wasm_modules (
+    digest TEXT PRIMARY KEY,
+    data BYTEA NOT NULL,
+    size BIGINT NOT NULL,
+
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);